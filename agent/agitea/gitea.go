@@ -0,0 +1,68 @@
+package agitea
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/clover0/issue-agent/platform/rest"
+)
+
+// Client is the client of Gitea API v1, which Forgejo serves as well.
+type Client struct {
+	gitURL *url.URL
+	token  string
+	api    *rest.Client
+}
+
+// NewGitea creates the client of the instance with GITEA_TOKEN.
+// gitURL is the base URL of the git remote, and it is the same as baseURL when empty.
+func NewGitea(baseURL string, gitURL string) (*Client, error) {
+	token, ok := os.LookupEnv("GITEA_TOKEN")
+	if !ok {
+		return nil, fmt.Errorf("GITEA_TOKEN is not set")
+	}
+
+	return NewClient(baseURL, gitURL, token, http.DefaultClient)
+}
+
+func NewClient(baseURL string, gitURL string, token string, httpClient *http.Client) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Gitea base url: %w", err)
+	}
+
+	g := u
+	if gitURL != "" {
+		g, err = url.Parse(strings.TrimSuffix(gitURL, "/"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse Gitea git url: %w", err)
+		}
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", "token "+token)
+
+	return &Client{
+		gitURL: g,
+		token:  token,
+		api:    rest.NewClient("Gitea", u.String()+"/api/v1", header, httpClient),
+	}, nil
+}
+
+// do sends the request to the path under /api/v1, discarding the response except the body. See rest.Client.Do.
+func (c *Client) do(ctx context.Context, method string, path string, body any, out any) error {
+	_, err := c.api.Do(ctx, method, path, body, out)
+	return err
+}
+
+// cloneURL returns the URL of the repository with the token.
+func (c *Client) cloneURL(owner string, repository string) string {
+	u := *c.gitURL
+	u.User = url.UserPassword("oauth2", c.token)
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + owner + "/" + repository + ".git"
+	return u.String()
+}
@@ -0,0 +1,378 @@
+package agitea
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+
+	"github.com/clover0/issue-agent/core/functions"
+	"github.com/clover0/issue-agent/logger"
+	"github.com/clover0/issue-agent/platform"
+	"github.com/clover0/issue-agent/platform/rest"
+	"github.com/clover0/issue-agent/telemetry"
+)
+
+var _ platform.Service = GiteaService{}
+
+// GiteaService operates the repository on Gitea or Forgejo.
+type GiteaService struct {
+	owner      string
+	repository string
+	client     *Client
+	logger     logger.Logger
+}
+
+func NewGiteaService(
+	owner string,
+	repository string,
+	client *Client,
+	logger logger.Logger,
+) GiteaService {
+	return GiteaService{
+		owner:      owner,
+		repository: repository,
+		client:     client,
+		logger:     logger,
+	}
+}
+
+type issue struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+}
+
+type pullRequest struct {
+	Number int       `json:"number"`
+	Title  string    `json:"title"`
+	Body   string    `json:"body"`
+	Head   branchRef `json:"head"`
+	Base   branchRef `json:"base"`
+}
+
+type branchRef struct {
+	Ref string `json:"ref"`
+}
+
+type comment struct {
+	ID             int64  `json:"id"`
+	Body           string `json:"body"`
+	IssueURL       string `json:"issue_url"`
+	PullRequestURL string `json:"pull_request_url"`
+}
+
+type reviewComment struct {
+	ID               int64  `json:"id"`
+	Body             string `json:"body"`
+	Path             string `json:"path"`
+	Position         int    `json:"position"`
+	OriginalPosition int    `json:"original_position"`
+}
+
+type label struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type content struct {
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	Type     string `json:"type"`
+	Encoding string `json:"encoding"`
+	Content  string `json:"content"`
+}
+
+// repoPath returns the escaped path of the repository API.
+func (s GiteaService) repoPath(repository string) string {
+	return "/repos/" + url.PathEscape(s.owner) + "/" + url.PathEscape(repository)
+}
+
+func (s GiteaService) GetIssue(ctx context.Context, repository string, issueNumber string) (_ functions.GetIssueOutput, err error) {
+	ctx, span := s.startSpan(ctx, "GetIssue", telemetry.AttrGiteaIndex.String(issueNumber))
+	defer func() { telemetry.End(span, err) }()
+
+	var is issue
+	if err := s.client.do(ctx, http.MethodGet, s.repoPath(repository)+"/issues/"+url.PathEscape(issueNumber), nil, &is); err != nil {
+		return functions.GetIssueOutput{}, fmt.Errorf("failed to get issue: %w", err)
+	}
+
+	return functions.GetIssueOutput{
+		Path:    strconv.Itoa(is.Number),
+		Title:   is.Title,
+		Content: is.Body,
+	}, nil
+}
+
+func (s GiteaService) GetPullRequest(ctx context.Context, prNumber string) (_ functions.GetPullRequestOutput, err error) {
+	ctx, span := s.startSpan(ctx, "GetPullRequest", telemetry.AttrGiteaIndex.String(prNumber))
+	defer func() { telemetry.End(span, err) }()
+
+	pullPath := s.repoPath(s.repository) + "/pulls/" + url.PathEscape(prNumber)
+
+	var pr pullRequest
+	if err := s.client.do(ctx, http.MethodGet, pullPath, nil, &pr); err != nil {
+		return functions.GetPullRequestOutput{}, fmt.Errorf("failed to get pull request: %w", err)
+	}
+
+	var diff []byte
+	if err := s.client.do(ctx, http.MethodGet, pullPath+".diff", nil, &diff); err != nil {
+		return functions.GetPullRequestOutput{}, fmt.Errorf("failed to get pull request diff: %w", err)
+	}
+
+	return functions.GetPullRequestOutput{
+		PRNumber: prNumber,
+		Head:     pr.Head.Ref,
+		Base:     pr.Base.Ref,
+		RawDiff:  string(diff),
+		Title:    pr.Title,
+		Content:  pr.Body,
+	}, nil
+}
+
+func (s GiteaService) GetBranch(ctx context.Context, branchName string) (_ string, err error) {
+	ctx, span := s.startSpan(ctx, "GetBranch", telemetry.AttrGiteaBranch.String(branchName))
+	defer func() { telemetry.End(span, err) }()
+
+	var branch struct {
+		Name string `json:"name"`
+	}
+	if err := s.client.do(ctx, http.MethodGet, s.repoPath(s.repository)+"/branches/"+url.PathEscape(branchName), nil, &branch); err != nil {
+		if rest.IsNotFound(err) {
+			return "", fmt.Errorf("branch %s not found : %w", branchName, err)
+		}
+		return "", fmt.Errorf("failed to get branch: %w", err)
+	}
+
+	return branch.Name, nil
+}
+
+// GetComment gets the issue comment. The issue number is not required on Gitea.
+func (s GiteaService) GetComment(ctx context.Context, _ string, commentID string) (_ functions.GetCommentOutput, err error) {
+	ctx, span := s.startSpan(ctx, "GetComment", telemetry.AttrGiteaCommentID.String(commentID))
+	defer func() { telemetry.End(span, err) }()
+
+	var c comment
+	if err := s.client.do(ctx, http.MethodGet, s.repoPath(s.repository)+"/issues/comments/"+url.PathEscape(commentID), nil, &c); err != nil {
+		return functions.GetCommentOutput{}, fmt.Errorf("failed to get comment: %w", err)
+	}
+
+	// the comment on the pull request has only the URL of the pull request
+	issueURL := c.IssueURL
+	if issueURL == "" {
+		issueURL = c.PullRequestURL
+	}
+
+	return functions.GetCommentOutput{
+		IssueNumber: issueURL[strings.LastIndex(issueURL, "/")+1:],
+		Content:     c.Body,
+	}, nil
+}
+
+// GetReviewComment gets the first comment of the review on the lines of the pull request.
+// Gitea identifies the review comments by the pull request and the review.
+func (s GiteaService) GetReviewComment(ctx context.Context, prNumber string, reviewID string) (_ functions.GetReviewOutput, err error) {
+	ctx, span := s.startSpan(ctx, "GetReviewComment", telemetry.AttrGiteaIndex.String(prNumber), telemetry.AttrGiteaCommentID.String(reviewID))
+	defer func() { telemetry.End(span, err) }()
+
+	var comments []reviewComment
+	if err := s.client.do(ctx, http.MethodGet,
+		s.repoPath(s.repository)+"/pulls/"+url.PathEscape(prNumber)+"/reviews/"+url.PathEscape(reviewID)+"/comments", nil, &comments); err != nil {
+		return functions.GetReviewOutput{}, fmt.Errorf("failed to get review: %w", err)
+	}
+	if len(comments) == 0 {
+		return functions.GetReviewOutput{}, fmt.Errorf("review %s has no comments on the lines", reviewID)
+	}
+
+	c := comments[0]
+	line := c.Position
+	if line == 0 {
+		// the comment on the removed line
+		line = c.OriginalPosition
+	}
+	return functions.GetReviewOutput{
+		IssuesNumber: prNumber,
+		Path:         c.Path,
+		StartLine:    line,
+		EndLine:      line,
+		Content:      c.Body,
+	}, nil
+}
+
+// CreatePullRequest creates the pull request with the labels existing in the repository.
+func (s GiteaService) CreatePullRequest(ctx context.Context, input platform.NewPullRequest) (_ platform.PullRequest, err error) {
+	ctx, span := s.startSpan(ctx, "CreatePullRequest", telemetry.AttrGiteaBranch.String(input.Head))
+	defer func() { telemetry.End(span, err) }()
+
+	labelIDs, err := s.labelIDs(ctx, input.Labels)
+	if err != nil {
+		return platform.PullRequest{}, err
+	}
+
+	var pr pullRequest
+	if err := s.client.do(ctx, http.MethodPost, s.repoPath(s.repository)+"/pulls", map[string]any{
+		"title":  input.Title,
+		"head":   input.Head,
+		"base":   input.Base,
+		"body":   input.Body,
+		"labels": labelIDs,
+	}, &pr); err != nil {
+		return platform.PullRequest{}, fmt.Errorf("failed to create pull request: %w", err)
+	}
+
+	return platform.PullRequest{Number: pr.Number}, nil
+}
+
+// labelIDs returns the IDs of the labels, because Gitea adds the labels by the IDs.
+// The labels not existing in the repository are skipped.
+func (s GiteaService) labelIDs(ctx context.Context, names []string) ([]int64, error) {
+	ids := []int64{}
+	if len(names) == 0 {
+		return ids, nil
+	}
+
+	var found []string
+	for page := 1; ; page++ {
+		var labels []label
+		if err := s.client.do(ctx, http.MethodGet, fmt.Sprintf("%s/labels?limit=50&page=%d", s.repoPath(s.repository), page), nil, &labels); err != nil {
+			return nil, fmt.Errorf("failed to list labels: %w", err)
+		}
+		if len(labels) == 0 {
+			break
+		}
+		for _, l := range labels {
+			if slices.Contains(names, l.Name) && !slices.Contains(found, l.Name) {
+				ids = append(ids, l.ID)
+				found = append(found, l.Name)
+			}
+		}
+	}
+
+	for _, name := range names {
+		if !slices.Contains(found, name) {
+			s.logger.Error("label %s is not found in the repository, so it is not added to the pull request\n", name)
+		}
+	}
+
+	return ids, nil
+}
+
+func (s GiteaService) CloneURL() (string, error) {
+	return s.client.cloneURL(s.owner, s.repository), nil
+}
+
+// PushAuth returns nil, because the token in the remote URL does not expire.
+func (s GiteaService) PushAuth() (transport.AuthMethod, error) {
+	return nil, nil
+}
+
+const contentSeparator = "---"
+
+func (s GiteaService) GetRepositoryContent(ctx context.Context, input functions.GetRepositoryContentInput) (_ functions.GetRepositoryContentOutput, err error) {
+	ctx, span := s.startSpan(ctx, "GetRepositoryContent", telemetry.AttrGiteaRepository.String(input.RepositoryName), telemetry.AttrGiteaContentPath.String(input.Path))
+	defer func() { telemetry.End(span, err) }()
+
+	segments := strings.Split(strings.Trim(input.Path, "/"), "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+
+	var raw []byte
+	if err := s.client.do(ctx, http.MethodGet, s.repoPath(input.RepositoryName)+"/contents/"+strings.Join(segments, "/"), nil, &raw); err != nil {
+		return functions.GetRepositoryContentOutput{}, fmt.Errorf("failed to get repository content: %w", err)
+	}
+
+	// the file is an object and the directory is an array of the entries
+	var contents []content
+	if err := json.Unmarshal(raw, &contents); err != nil {
+		var c content
+		if err := json.Unmarshal(raw, &c); err != nil {
+			return functions.GetRepositoryContentOutput{}, fmt.Errorf("failed to decode repository content: %w", err)
+		}
+		contents = []content{c}
+	}
+
+	var contentStr string
+	for _, cont := range contents {
+		decoded := cont.Content
+		if cont.Encoding == "base64" {
+			b, err := base64.StdEncoding.DecodeString(cont.Content)
+			if err != nil {
+				return functions.GetRepositoryContentOutput{}, fmt.Errorf("failed to decode content: %w", err)
+			}
+			decoded = string(b)
+		}
+		contentStr += fmt.Sprintf("%s\nfile name: %s\nfile path: %s\n\n%s",
+			contentSeparator, cont.Name, cont.Path, decoded)
+	}
+
+	return functions.GetRepositoryContentOutput{
+		Content: contentStr,
+	}, nil
+}
+
+func (s GiteaService) CreateIssueComment(ctx context.Context, issueNumber string, comment string) (_ functions.CreateIssueCommentOutput, err error) {
+	ctx, span := s.startSpan(ctx, "CreateIssueComment", telemetry.AttrGiteaIndex.String(issueNumber))
+	defer func() { telemetry.End(span, err) }()
+
+	if err := s.client.do(ctx, http.MethodPost, s.repoPath(s.repository)+"/issues/"+url.PathEscape(issueNumber)+"/comments", map[string]any{
+		"body": comment,
+	}, nil); err != nil {
+		return functions.CreateIssueCommentOutput{}, fmt.Errorf("failed to create issue comment: %w", err)
+	}
+
+	return functions.CreateIssueCommentOutput{}, nil
+}
+
+// CreateReviewCommentOne creates the review with the comment on the end line,
+// because Gitea comments on a single line.
+func (s GiteaService) CreateReviewCommentOne(ctx context.Context, review functions.CreatePullRequestReviewCommentInput) (_ functions.CreatePullRequestReviewCommentOutput, err error) {
+	ctx, span := s.startSpan(ctx, "CreateReviewCommentOne", telemetry.AttrGiteaIndex.String(review.PRNumber))
+	defer func() { telemetry.End(span, err) }()
+
+	body := review.ReviewComment
+	if review.ReviewStartLine != review.ReviewEndLine {
+		body = fmt.Sprintf("Lines %d-%d:\n\n%s", review.ReviewStartLine, review.ReviewEndLine, body)
+	}
+
+	if err := s.client.do(ctx, http.MethodPost, s.repoPath(s.repository)+"/pulls/"+url.PathEscape(review.PRNumber)+"/reviews", map[string]any{
+		"event": "COMMENT",
+		"comments": []map[string]any{{
+			"path":         review.ReviewFilePath,
+			"body":         body,
+			"new_position": review.ReviewEndLine,
+		}},
+	}, nil); err != nil {
+		return functions.CreatePullRequestReviewCommentOutput{}, fmt.Errorf("failed to create review comment: %w", err)
+	}
+
+	return functions.CreatePullRequestReviewCommentOutput{}, nil
+}
+
+func (s GiteaService) RequestReviewers(ctx context.Context, prNumber int, reviewers []string, teamReviewers []string) (_ functions.RequestReviewersOutput, err error) {
+	ctx, span := s.startSpan(ctx, "RequestReviewers", telemetry.AttrGiteaIndex.String(strconv.Itoa(prNumber)))
+	defer func() { telemetry.End(span, err) }()
+
+	if err := s.client.do(ctx, http.MethodPost, fmt.Sprintf("%s/pulls/%d/requested_reviewers", s.repoPath(s.repository), prNumber), map[string]any{
+		"reviewers":      reviewers,
+		"team_reviewers": teamReviewers,
+	}, nil); err != nil {
+		var errResp *rest.ErrorResponse
+		if errors.As(err, &errResp) && errResp.StatusCode >= 400 && errResp.StatusCode < 500 {
+			return functions.RequestReviewersOutput{},
+				fmt.Errorf("client error requesting reviewers=%s, team_reviewers=%s to PR: %s", reviewers, teamReviewers, errResp.Body)
+		}
+		return functions.RequestReviewersOutput{},
+			fmt.Errorf("failed to request reviewers=%s, team_reviewers=%s to PR: %w", reviewers, teamReviewers, err)
+	}
+
+	return functions.RequestReviewersOutput{}, nil
+}
@@ -0,0 +1,16 @@
+package agitea
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/clover0/issue-agent/platform"
+	"github.com/clover0/issue-agent/telemetry"
+)
+
+// startSpan starts the span of the Gitea operation named like gitea.GetIssue.
+func (s GiteaService) startSpan(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return platform.StartAPISpan(ctx, "gitea."+operation, telemetry.AttrGiteaRepository.String(s.owner+"/"+s.repository), attrs...)
+}
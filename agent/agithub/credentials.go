@@ -0,0 +1,185 @@
+package agithub
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/google/go-github/v73/github"
+	"golang.org/x/oauth2"
+
+	"github.com/clover0/issue-agent/config"
+)
+
+// installationTokenEarlyExpiry is the duration before the expiry to refresh the installation token,
+// so that the token does not expire during a push.
+const installationTokenEarlyExpiry = 5 * time.Minute
+
+// Credentials authenticate GitHub API and git with GITHUB_TOKEN, or with the installation tokens of GitHub App.
+type Credentials struct {
+	tokenSource oauth2.TokenSource
+
+	// gitUser is the user name of git with the token as the password
+	gitUser string
+
+	// refreshed reports whether the token expires and is refreshed
+	refreshed bool
+}
+
+// NewCredentials creates the credentials of GitHub App when it is configured, otherwise of GITHUB_TOKEN.
+func NewCredentials(conf config.GitHub) (Credentials, error) {
+	if !conf.App.Enabled() {
+		token, ok := os.LookupEnv("GITHUB_TOKEN")
+		if !ok {
+			return Credentials{}, fmt.Errorf("GITHUB_TOKEN is not set")
+		}
+		return Credentials{
+			tokenSource: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}),
+			gitUser:     "oauth2",
+		}, nil
+	}
+
+	key, err := readPrivateKey(conf.App)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	return Credentials{
+		tokenSource: oauth2.ReuseTokenSourceWithExpiry(nil, installationTokenSource{
+			conf: conf,
+			key:  key,
+		}, installationTokenEarlyExpiry),
+		gitUser:   "x-access-token",
+		refreshed: true,
+	}, nil
+}
+
+// Token returns the token, which is refreshed before the expiry.
+func (c Credentials) Token() (string, error) {
+	token, err := c.tokenSource.Token()
+	if err != nil {
+		return "", fmt.Errorf("failed to get GitHub token: %w", err)
+	}
+	return token.AccessToken, nil
+}
+
+// HTTPClient returns the client sending the token with the requests.
+func (c Credentials) HTTPClient() *http.Client {
+	return oauth2.NewClient(context.Background(), c.tokenSource)
+}
+
+// GitAuth returns the auth of git with the current token.
+// It is nil when the token does not expire, so that the credentials of the remote URL are used.
+func (c Credentials) GitAuth() (transport.AuthMethod, error) {
+	if !c.refreshed {
+		return nil, nil
+	}
+
+	token, err := c.Token()
+	if err != nil {
+		return nil, err
+	}
+	return &githttp.BasicAuth{Username: c.gitUser, Password: token}, nil
+}
+
+func readPrivateKey(conf config.GitHubApp) (*rsa.PrivateKey, error) {
+	var data []byte
+	if conf.PrivateKeyPath != "" {
+		b, err := os.ReadFile(conf.PrivateKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read GitHub App private key: %w", err)
+		}
+		data = b
+	} else {
+		v, ok := os.LookupEnv(conf.PrivateKeyEnv)
+		if !ok {
+			return nil, fmt.Errorf("GitHub App private key is not set to private_key_path or %s", conf.PrivateKeyEnv)
+		}
+		data = []byte(v)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("GitHub App private key is not PEM")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse GitHub App private key: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("GitHub App private key is not RSA key")
+	}
+	return rsaKey, nil
+}
+
+// installationTokenSource mints the installation token of GitHub App.
+type installationTokenSource struct {
+	conf config.GitHub
+	key  *rsa.PrivateKey
+}
+
+func (s installationTokenSource) Token() (*oauth2.Token, error) {
+	jwt, err := appJWT(s.conf.App.AppID, s.key, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := withEnterpriseURLs(github.NewClient(nil).WithAuthToken(jwt), s.conf)
+	if err != nil {
+		return nil, err
+	}
+
+	token, _, err := client.Apps.CreateInstallationToken(context.Background(), s.conf.App.InstallationID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create installation token of GitHub App: %w", err)
+	}
+
+	return &oauth2.Token{
+		AccessToken: token.GetToken(),
+		Expiry:      token.GetExpiresAt().Time,
+	}, nil
+}
+
+// appJWT returns the JSON Web Token authenticating as GitHub App.
+// The issued time is a minute ago to allow the clock drift, and the token expires in 10 minutes at most.
+func appJWT(appID int64, key *rsa.PrivateKey, now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.FormatInt(appID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign GitHub App JWT: %w", err)
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
@@ -0,0 +1,101 @@
+package agithub_test
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+
+	"github.com/clover0/issue-agent/agithub"
+	"github.com/clover0/issue-agent/config"
+	"github.com/clover0/issue-agent/test/assert"
+)
+
+func TestNewCredentials_GitHubApp(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	t.Setenv("TEST_GITHUB_APP_PRIVATE_KEY", string(pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})))
+
+	var minted atomic.Int32
+	var gotIssuer string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v3/app/installations/42/access_tokens" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		// verify the JWT signed with the private key of the app
+		parts := strings.Split(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), ".")
+		hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hash[:], sig); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		claims, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		var c struct {
+			Iss string `json:"iss"`
+		}
+		_ = json.Unmarshal(claims, &c)
+		gotIssuer = c.Iss
+
+		n := minted.Add(1)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = fmt.Fprintf(w, `{"token":"ghs_%d","expires_at":"%s"}`, n, time.Now().Add(time.Hour).Format(time.RFC3339))
+	}))
+	defer server.Close()
+
+	credentials, err := agithub.NewCredentials(config.GitHub{
+		BaseURL: server.URL,
+		App: config.GitHubApp{
+			AppID:          7,
+			InstallationID: 42,
+			PrivateKeyEnv:  "TEST_GITHUB_APP_PRIVATE_KEY",
+		},
+	})
+	assert.NoError(t, err)
+
+	token, err := credentials.Token()
+	assert.NoError(t, err)
+	assert.Equal(t, token, "ghs_1")
+	assert.Equal(t, gotIssuer, "7")
+
+	auth, err := credentials.GitAuth()
+	assert.NoError(t, err)
+	assert.Equal(t, *auth.(*githttp.BasicAuth), githttp.BasicAuth{Username: "x-access-token", Password: "ghs_1"})
+
+	// the token is reused until it is about to expire
+	assert.Equal(t, minted.Load(), int32(1))
+}
+
+func TestNewCredentials_Token(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "token")
+
+	credentials, err := agithub.NewCredentials(config.GitHub{})
+	assert.NoError(t, err)
+
+	token, err := credentials.Token()
+	assert.NoError(t, err)
+	assert.Equal(t, token, "token")
+
+	// the credentials of the remote URL are used to push
+	auth, err := credentials.GitAuth()
+	assert.NoError(t, err)
+	assert.Nil(t, auth)
+}
@@ -2,15 +2,34 @@ package agithub
 
 import (
 	"fmt"
-	"os"
+	"strings"
 
 	"github.com/google/go-github/v73/github"
+
+	"github.com/clover0/issue-agent/config"
 )
 
-func NewGitHub() (*github.Client, error) {
-	token, ok := os.LookupEnv("GITHUB_TOKEN")
-	if !ok {
-		return nil, fmt.Errorf("GITHUB_TOKEN is not set")
+// NewGitHub creates the client of github.com, or GitHub Enterprise Server when the base URL is set.
+func NewGitHub(conf config.GitHub, credentials Credentials) (*github.Client, error) {
+	return withEnterpriseURLs(github.NewClient(credentials.HTTPClient()), conf)
+}
+
+// withEnterpriseURLs sets the URLs of GitHub Enterprise Server to the client when the base URL is set.
+func withEnterpriseURLs(client *github.Client, conf config.GitHub) (*github.Client, error) {
+	if conf.BaseURL == "" {
+		return client, nil
+	}
+
+	uploadURL := conf.UploadURL
+	if uploadURL == "" {
+		// go-github appends api/uploads/ to the URL of the host
+		uploadURL = strings.TrimSuffix(strings.TrimSuffix(conf.BaseURL, "/"), "/api/v3")
+	}
+
+	client, err := client.WithEnterpriseURLs(conf.BaseURL, uploadURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set GitHub Enterprise Server URLs: %w", err)
 	}
-	return github.NewClient(nil).WithAuthToken(token), nil
+
+	return client, nil
 }
@@ -8,42 +8,56 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/google/go-github/v73/github"
 
 	"github.com/clover0/issue-agent/core/functions"
 	"github.com/clover0/issue-agent/logger"
+	"github.com/clover0/issue-agent/platform"
+	"github.com/clover0/issue-agent/telemetry"
 	"github.com/clover0/issue-agent/util/pointer"
 )
 
+var _ platform.Service = GitHubService{}
+
 type GitHubService struct {
 	owner      string
 	repository string
-	client     *github.Client
-	logger     logger.Logger
+	// gitURL is the base URL of the git remote, such as https://github.com and the URL of GitHub Enterprise Server
+	gitURL      string
+	client      *github.Client
+	credentials Credentials
+	logger      logger.Logger
 }
 
 func NewGitHubService(
 	owner string,
 	repository string,
+	gitURL string,
 	client *github.Client,
+	credentials Credentials,
 	logger logger.Logger,
 ) GitHubService {
 	return GitHubService{
-		owner:      owner,
-		repository: repository,
-		client:     client,
-		logger:     logger,
+		owner:       owner,
+		repository:  repository,
+		gitURL:      gitURL,
+		client:      client,
+		credentials: credentials,
+		logger:      logger,
 	}
 }
 
-func (s GitHubService) GetIssue(repository string, issueNumber string) (functions.GetIssueOutput, error) {
+func (s GitHubService) GetIssue(ctx context.Context, repository string, issueNumber string) (_ functions.GetIssueOutput, err error) {
+	ctx, span := s.startSpan(ctx, "GetIssue", telemetry.AttrGitHubRepository.String(repository), telemetry.AttrGitHubIssueNumber.String(issueNumber))
+	defer func() { telemetry.End(span, err) }()
+
 	number, err := strconv.Atoi(issueNumber)
 	if err != nil {
 		return functions.GetIssueOutput{}, fmt.Errorf("failed to convert issue number to int: %w", err)
 	}
 
-	c := context.Background()
-	issue, _, err := s.client.Issues.Get(c, s.owner, repository, number)
+	issue, _, err := s.client.Issues.Get(ctx, s.owner, repository, number)
 	if err != nil {
 		return functions.GetIssueOutput{}, fmt.Errorf("failed to get issue: %w", err)
 	}
@@ -55,18 +69,20 @@ func (s GitHubService) GetIssue(repository string, issueNumber string) (function
 	}, nil
 }
 
-func (s GitHubService) GetPullRequest(prNumber string) (functions.GetPullRequestOutput, error) {
+func (s GitHubService) GetPullRequest(ctx context.Context, prNumber string) (_ functions.GetPullRequestOutput, err error) {
+	ctx, span := s.startSpan(ctx, "GetPullRequest", telemetry.AttrGitHubPullRequest.String(prNumber))
+	defer func() { telemetry.End(span, err) }()
+
 	number, err := strconv.Atoi(prNumber)
 	if err != nil {
 		return functions.GetPullRequestOutput{}, fmt.Errorf("failed to convert pull request number to int: %w", err)
 	}
 
-	c := context.Background()
-	pr, _, err := s.client.PullRequests.Get(c, s.owner, s.repository, number)
+	pr, _, err := s.client.PullRequests.Get(ctx, s.owner, s.repository, number)
 	if err != nil {
 		return functions.GetPullRequestOutput{}, fmt.Errorf("failed to get pull request: %w", err)
 	}
-	diff, _, err := s.client.PullRequests.GetRaw(c, s.owner, s.repository, number, github.RawOptions{Type: github.Diff})
+	diff, _, err := s.client.PullRequests.GetRaw(ctx, s.owner, s.repository, number, github.RawOptions{Type: github.Diff})
 	if err != nil {
 		return functions.GetPullRequestOutput{}, fmt.Errorf("failed to get pull request diff: %w", err)
 	}
@@ -81,9 +97,11 @@ func (s GitHubService) GetPullRequest(prNumber string) (functions.GetPullRequest
 	}, nil
 }
 
-func (s GitHubService) GetBranch(branchName string) (string, error) {
-	c := context.Background()
-	branch, resp, err := s.client.Repositories.GetBranch(c, s.owner, s.repository, branchName, 0)
+func (s GitHubService) GetBranch(ctx context.Context, branchName string) (_ string, err error) {
+	ctx, span := s.startSpan(ctx, "GetBranch", telemetry.AttrGitHubBranch.String(branchName))
+	defer func() { telemetry.End(span, err) }()
+
+	branch, resp, err := s.client.Repositories.GetBranch(ctx, s.owner, s.repository, branchName, 0)
 	if err != nil {
 		if resp != nil && resp.StatusCode == 404 {
 			return "", fmt.Errorf("branch %s not found : %w", branchName, err)
@@ -94,14 +112,17 @@ func (s GitHubService) GetBranch(branchName string) (string, error) {
 	return branch.GetName(), nil
 }
 
-func (s GitHubService) GetComment(commentNumber string) (functions.GetCommentOutput, error) {
-	c := context.Background()
+// GetComment gets the issue comment. The issue number is not required on GitHub.
+func (s GitHubService) GetComment(ctx context.Context, _ string, commentNumber string) (_ functions.GetCommentOutput, err error) {
+	ctx, span := s.startSpan(ctx, "GetComment", telemetry.AttrGitHubCommentID.String(commentNumber))
+	defer func() { telemetry.End(span, err) }()
+
 	number, err := strconv.ParseInt(commentNumber, 10, 64)
 	if err != nil {
 		return functions.GetCommentOutput{}, fmt.Errorf("failed to convert comment number to int %s", commentNumber)
 	}
 
-	comment, _, err := s.client.Issues.GetComment(c, s.owner, s.repository, number)
+	comment, _, err := s.client.Issues.GetComment(ctx, s.owner, s.repository, number)
 	if err != nil {
 		return functions.GetCommentOutput{}, fmt.Errorf("failed to get comment: %w", err)
 	}
@@ -120,14 +141,17 @@ func (s GitHubService) GetComment(commentNumber string) (functions.GetCommentOut
 	}, nil
 }
 
-func (s GitHubService) GetReviewComment(reviewID string) (functions.GetReviewOutput, error) {
-	c := context.Background()
+// GetReviewComment gets the review comment. The pull request number is not required on GitHub.
+func (s GitHubService) GetReviewComment(ctx context.Context, _ string, reviewID string) (_ functions.GetReviewOutput, err error) {
+	ctx, span := s.startSpan(ctx, "GetReviewComment", telemetry.AttrGitHubCommentID.String(reviewID))
+	defer func() { telemetry.End(span, err) }()
+
 	id, err := strconv.ParseInt(reviewID, 10, 64)
 	if err != nil {
 		return functions.GetReviewOutput{}, fmt.Errorf("failed to convert review id to int %s", reviewID)
 	}
 
-	review, _, err := s.client.PullRequests.GetComment(c, s.owner, s.repository, id)
+	review, _, err := s.client.PullRequests.GetComment(ctx, s.owner, s.repository, id)
 	if err != nil {
 		return functions.GetReviewOutput{}, fmt.Errorf("failed to get review: %w", err)
 	}
@@ -153,12 +177,58 @@ func (s GitHubService) GetReviewComment(reviewID string) (functions.GetReviewOut
 	}, nil
 }
 
+func (s GitHubService) CreatePullRequest(ctx context.Context, input platform.NewPullRequest) (_ platform.PullRequest, err error) {
+	ctx, span := s.startSpan(ctx, "CreatePullRequest", telemetry.AttrGitHubBranch.String(input.Head))
+	defer func() { telemetry.End(span, err) }()
+
+	pr, _, err := s.client.PullRequests.Create(ctx, s.owner, s.repository, &github.NewPullRequest{
+		Title: &input.Title,
+		Head:  &input.Head,
+		Base:  &input.Base,
+		Body:  &input.Body,
+	})
+	if err != nil {
+		return platform.PullRequest{}, fmt.Errorf("failed to create pull request: %w", err)
+	}
+
+	if len(input.Labels) > 0 {
+		if _, _, err = s.client.Issues.AddLabelsToIssue(ctx, s.owner, s.repository, pr.GetNumber(), input.Labels); err != nil {
+			return platform.PullRequest{}, fmt.Errorf("failed to add labels(%s) to pull request: %w", input.Labels, err)
+		}
+	}
+
+	return platform.PullRequest{Number: pr.GetNumber()}, nil
+}
+
+func (s GitHubService) CloneURL() (string, error) {
+	token, err := s.credentials.Token()
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(strings.TrimSuffix(s.gitURL, "/"))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse git url: %w", err)
+	}
+	u.User = url.UserPassword(s.credentials.gitUser, token)
+	u.Path += "/" + s.owner + "/" + s.repository + ".git"
+
+	return u.String(), nil
+}
+
+// PushAuth returns the auth with the refreshed installation token of GitHub App,
+// because the token in the remote URL expires in an hour.
+func (s GitHubService) PushAuth() (transport.AuthMethod, error) {
+	return s.credentials.GitAuth()
+}
+
 const contentSeparator = "---"
 
-func (s GitHubService) GetRepositoryContent(input functions.GetRepositoryContentInput) (functions.GetRepositoryContentOutput, error) {
-	c := context.Background()
+func (s GitHubService) GetRepositoryContent(ctx context.Context, input functions.GetRepositoryContentInput) (_ functions.GetRepositoryContentOutput, err error) {
+	ctx, span := s.startSpan(ctx, "GetRepositoryContent", telemetry.AttrGitHubRepository.String(input.RepositoryName), telemetry.AttrGitHubContentPath.String(input.Path))
+	defer func() { telemetry.End(span, err) }()
 
-	content, dirContent, _, err := s.client.Repositories.GetContents(c, s.owner, input.RepositoryName, input.Path, nil)
+	content, dirContent, _, err := s.client.Repositories.GetContents(ctx, s.owner, input.RepositoryName, input.Path, nil)
 	if err != nil {
 		return functions.GetRepositoryContentOutput{}, fmt.Errorf("failed to get repository content: %w", err)
 	}
@@ -188,15 +258,17 @@ func (s GitHubService) GetRepositoryContent(input functions.GetRepositoryContent
 	}, nil
 }
 
-func (s GitHubService) CreateIssueComment(issueNumber string, comment string) (functions.CreateIssueCommentOutput, error) {
-	c := context.Background()
+func (s GitHubService) CreateIssueComment(ctx context.Context, issueNumber string, comment string) (_ functions.CreateIssueCommentOutput, err error) {
+	ctx, span := s.startSpan(ctx, "CreateIssueComment", telemetry.AttrGitHubIssueNumber.String(issueNumber))
+	defer func() { telemetry.End(span, err) }()
+
 	number, err := strconv.Atoi(issueNumber)
 	if err != nil {
 		return functions.CreateIssueCommentOutput{}, fmt.Errorf("failed to convert issue number to int: %w", err)
 	}
 
 	issueComment := &github.IssueComment{Body: &comment}
-	_, _, err = s.client.Issues.CreateComment(c, s.owner, s.repository, number, issueComment)
+	_, _, err = s.client.Issues.CreateComment(ctx, s.owner, s.repository, number, issueComment)
 	if err != nil {
 		return functions.CreateIssueCommentOutput{}, fmt.Errorf("failed to create issue comment: %w", err)
 	}
@@ -204,8 +276,9 @@ func (s GitHubService) CreateIssueComment(issueNumber string, comment string) (f
 	return functions.CreateIssueCommentOutput{}, nil
 }
 
-func (s GitHubService) CreateReviewCommentOne(review functions.CreatePullRequestReviewCommentInput) (functions.CreatePullRequestReviewCommentOutput, error) {
-	c := context.Background()
+func (s GitHubService) CreateReviewCommentOne(ctx context.Context, review functions.CreatePullRequestReviewCommentInput) (_ functions.CreatePullRequestReviewCommentOutput, err error) {
+	ctx, span := s.startSpan(ctx, "CreateReviewCommentOne", telemetry.AttrGitHubPullRequest.String(review.PRNumber))
+	defer func() { telemetry.End(span, err) }()
 
 	prNumber, err := strconv.Atoi(review.PRNumber)
 	if err != nil {
@@ -225,7 +298,7 @@ func (s GitHubService) CreateReviewCommentOne(review functions.CreatePullRequest
 		Line:      pointer.Ptr(review.ReviewEndLine),
 	}}
 
-	_, _, err = s.client.PullRequests.CreateReview(c, s.owner, s.repository, prNumber, &github.PullRequestReviewRequest{
+	_, _, err = s.client.PullRequests.CreateReview(ctx, s.owner, s.repository, prNumber, &github.PullRequestReviewRequest{
 		Event:    pointer.Ptr("COMMENT"),
 		Comments: reviewComment,
 	})
@@ -236,11 +309,12 @@ func (s GitHubService) CreateReviewCommentOne(review functions.CreatePullRequest
 	return functions.CreatePullRequestReviewCommentOutput{}, nil
 }
 
-func (s GitHubService) RequestReviewers(prNumber int, reviewers []string, teamReviewers []string) (functions.RequestReviewersOutput, error) {
-	c := context.Background()
+func (s GitHubService) RequestReviewers(ctx context.Context, prNumber int, reviewers []string, teamReviewers []string) (_ functions.RequestReviewersOutput, err error) {
+	ctx, span := s.startSpan(ctx, "RequestReviewers", telemetry.AttrGitHubPullRequest.String(strconv.Itoa(prNumber)))
+	defer func() { telemetry.End(span, err) }()
 
 	_, resp, err := s.client.PullRequests.RequestReviewers(
-		c,
+		ctx,
 		s.owner,
 		s.repository,
 		prNumber,
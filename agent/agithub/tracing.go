@@ -0,0 +1,16 @@
+package agithub
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/clover0/issue-agent/platform"
+	"github.com/clover0/issue-agent/telemetry"
+)
+
+// startSpan starts the span of the GitHub operation named like github.GetIssue.
+func (s GitHubService) startSpan(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return platform.StartAPISpan(ctx, "github."+operation, telemetry.AttrGitHubRepository.String(s.owner+"/"+s.repository), attrs...)
+}
@@ -0,0 +1,75 @@
+package agitlab
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/clover0/issue-agent/platform/rest"
+)
+
+// Client is the client of GitLab REST API v4.
+type Client struct {
+	baseURL *url.URL
+	token   string
+	api     *rest.Client
+}
+
+// NewGitLab creates the client of the GitLab instance with GITLAB_TOKEN.
+func NewGitLab(baseURL string) (*Client, error) {
+	token, ok := os.LookupEnv("GITLAB_TOKEN")
+	if !ok {
+		return nil, fmt.Errorf("GITLAB_TOKEN is not set")
+	}
+
+	return NewClient(baseURL, token, http.DefaultClient)
+}
+
+func NewClient(baseURL string, token string, httpClient *http.Client) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse GitLab base url: %w", err)
+	}
+
+	header := http.Header{}
+	header.Set("PRIVATE-TOKEN", token)
+
+	return &Client{
+		baseURL: u,
+		token:   token,
+		api:     rest.NewClient("GitLab", u.String()+"/api/v4", header, httpClient),
+	}, nil
+}
+
+// do sends the request to the path under /api/v4. See rest.Client.Do.
+func (c *Client) do(ctx context.Context, method string, path string, body any, out any) (*http.Response, error) {
+	return c.api.Do(ctx, method, path, body, out)
+}
+
+// listPages gets the items of all the pages of the list, following the X-Next-Page header.
+// The path must have the query.
+func listPages[T any](ctx context.Context, c *Client, path string) ([]T, error) {
+	var items []T
+	page := "1"
+	for page != "" {
+		var p []T
+		resp, err := c.do(ctx, http.MethodGet, path+"&page="+page, nil, &p)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, p...)
+		page = resp.Header.Get("X-Next-Page")
+	}
+	return items, nil
+}
+
+// cloneURL returns the URL of the repository with the token.
+func (c *Client) cloneURL(project string) string {
+	u := *c.baseURL
+	u.User = url.UserPassword("oauth2", c.token)
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + project + ".git"
+	return u.String()
+}
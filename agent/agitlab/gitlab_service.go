@@ -0,0 +1,449 @@
+package agitlab
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+
+	"github.com/clover0/issue-agent/core/functions"
+	"github.com/clover0/issue-agent/logger"
+	"github.com/clover0/issue-agent/platform"
+	"github.com/clover0/issue-agent/platform/rest"
+	"github.com/clover0/issue-agent/telemetry"
+)
+
+var _ platform.Service = GitLabService{}
+
+// GitLabService operates the project on GitLab.
+// The pull requests are the merge requests, and the numbers of them are the IIDs in the project.
+type GitLabService struct {
+	// owner is the namespace of the project, such as group/subgroup
+	owner      string
+	repository string
+	client     *Client
+	logger     logger.Logger
+}
+
+func NewGitLabService(
+	owner string,
+	repository string,
+	client *Client,
+	logger logger.Logger,
+) GitLabService {
+	return GitLabService{
+		owner:      owner,
+		repository: repository,
+		client:     client,
+		logger:     logger,
+	}
+}
+
+type issue struct {
+	IID         int    `json:"iid"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+type mergeRequest struct {
+	IID          int      `json:"iid"`
+	Title        string   `json:"title"`
+	Description  string   `json:"description"`
+	SourceBranch string   `json:"source_branch"`
+	TargetBranch string   `json:"target_branch"`
+	DiffRefs     diffRefs `json:"diff_refs"`
+	Reviewers    []user   `json:"reviewers"`
+}
+
+type diffRefs struct {
+	BaseSHA  string `json:"base_sha"`
+	HeadSHA  string `json:"head_sha"`
+	StartSHA string `json:"start_sha"`
+}
+
+type diff struct {
+	OldPath     string `json:"old_path"`
+	NewPath     string `json:"new_path"`
+	Diff        string `json:"diff"`
+	NewFile     bool   `json:"new_file"`
+	DeletedFile bool   `json:"deleted_file"`
+}
+
+type note struct {
+	ID       int       `json:"id"`
+	Body     string    `json:"body"`
+	Position *position `json:"position"`
+}
+
+type position struct {
+	PositionType string     `json:"position_type"`
+	BaseSHA      string     `json:"base_sha"`
+	HeadSHA      string     `json:"head_sha"`
+	StartSHA     string     `json:"start_sha"`
+	OldPath      string     `json:"old_path"`
+	NewPath      string     `json:"new_path"`
+	NewLine      int        `json:"new_line,omitempty"`
+	OldLine      int        `json:"old_line,omitempty"`
+	LineRange    *lineRange `json:"line_range,omitempty"`
+}
+
+type lineRange struct {
+	Start linePosition `json:"start"`
+	End   linePosition `json:"end"`
+}
+
+type linePosition struct {
+	NewLine int `json:"new_line"`
+	OldLine int `json:"old_line"`
+}
+
+type user struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+}
+
+type treeEntry struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Type string `json:"type"`
+}
+
+// projectPath returns the escaped path of the project API.
+func (s GitLabService) projectPath(repository string) string {
+	return "/projects/" + url.PathEscape(s.owner+"/"+repository)
+}
+
+func (s GitLabService) mergeRequestPath(iid string) string {
+	return s.projectPath(s.repository) + "/merge_requests/" + url.PathEscape(iid)
+}
+
+func (s GitLabService) GetIssue(ctx context.Context, repository string, issueNumber string) (_ functions.GetIssueOutput, err error) {
+	ctx, span := s.startSpan(ctx, "GetIssue", telemetry.AttrGitLabIID.String(issueNumber))
+	defer func() { telemetry.End(span, err) }()
+
+	var is issue
+	if _, err := s.client.do(ctx, http.MethodGet, s.projectPath(repository)+"/issues/"+url.PathEscape(issueNumber), nil, &is); err != nil {
+		return functions.GetIssueOutput{}, fmt.Errorf("failed to get issue: %w", err)
+	}
+
+	return functions.GetIssueOutput{
+		Path:    strconv.Itoa(is.IID),
+		Title:   is.Title,
+		Content: is.Description,
+	}, nil
+}
+
+// GetPullRequest gets the merge request and its diff in the unified format.
+func (s GitLabService) GetPullRequest(ctx context.Context, prNumber string) (_ functions.GetPullRequestOutput, err error) {
+	ctx, span := s.startSpan(ctx, "GetPullRequest", telemetry.AttrGitLabIID.String(prNumber))
+	defer func() { telemetry.End(span, err) }()
+
+	mr, err := s.getMergeRequest(ctx, prNumber)
+	if err != nil {
+		return functions.GetPullRequestOutput{}, err
+	}
+
+	diffs, err := s.listDiffs(ctx, prNumber)
+	if err != nil {
+		return functions.GetPullRequestOutput{}, err
+	}
+
+	return functions.GetPullRequestOutput{
+		PRNumber: prNumber,
+		Head:     mr.SourceBranch,
+		Base:     mr.TargetBranch,
+		RawDiff:  rawDiff(diffs),
+		Title:    mr.Title,
+		Content:  mr.Description,
+	}, nil
+}
+
+func (s GitLabService) getMergeRequest(ctx context.Context, iid string) (mergeRequest, error) {
+	var mr mergeRequest
+	if _, err := s.client.do(ctx, http.MethodGet, s.mergeRequestPath(iid), nil, &mr); err != nil {
+		return mergeRequest{}, fmt.Errorf("failed to get merge request: %w", err)
+	}
+	return mr, nil
+}
+
+// listDiffs lists the diffs of all pages.
+func (s GitLabService) listDiffs(ctx context.Context, iid string) ([]diff, error) {
+	diffs, err := listPages[diff](ctx, s.client, s.mergeRequestPath(iid)+"/diffs?per_page=100")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get merge request diffs: %w", err)
+	}
+	return diffs, nil
+}
+
+// rawDiff builds the unified diff from the diffs of files.
+func rawDiff(diffs []diff) string {
+	var b strings.Builder
+	for _, d := range diffs {
+		oldPath, newPath := "a/"+d.OldPath, "b/"+d.NewPath
+		if d.NewFile {
+			oldPath = "/dev/null"
+		}
+		if d.DeletedFile {
+			newPath = "/dev/null"
+		}
+		fmt.Fprintf(&b, "diff --git a/%s b/%s\n--- %s\n+++ %s\n%s", d.OldPath, d.NewPath, oldPath, newPath, d.Diff)
+		if !strings.HasSuffix(d.Diff, "\n") {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+func (s GitLabService) GetBranch(ctx context.Context, branchName string) (_ string, err error) {
+	ctx, span := s.startSpan(ctx, "GetBranch", telemetry.AttrGitLabBranch.String(branchName))
+	defer func() { telemetry.End(span, err) }()
+
+	var branch struct {
+		Name string `json:"name"`
+	}
+	if _, err := s.client.do(ctx, http.MethodGet, s.projectPath(s.repository)+"/repository/branches/"+url.PathEscape(branchName), nil, &branch); err != nil {
+		if rest.IsNotFound(err) {
+			return "", fmt.Errorf("branch %s not found : %w", branchName, err)
+		}
+		return "", fmt.Errorf("failed to get branch: %w", err)
+	}
+
+	return branch.Name, nil
+}
+
+// GetComment gets the note on the merge request.
+// The note on the lines of the diff is returned with the information of the lines.
+func (s GitLabService) GetComment(ctx context.Context, issueNumber string, commentID string) (_ functions.GetCommentOutput, err error) {
+	ctx, span := s.startSpan(ctx, "GetComment", telemetry.AttrGitLabIID.String(issueNumber), telemetry.AttrGitLabNoteID.String(commentID))
+	defer func() { telemetry.End(span, err) }()
+
+	n, err := s.getNote(ctx, issueNumber, commentID)
+	if err != nil {
+		return functions.GetCommentOutput{}, err
+	}
+
+	content := n.Body
+	if n.Position != nil {
+		content = reviewOutput(issueNumber, n).ToLLMString()
+	}
+
+	return functions.GetCommentOutput{
+		IssueNumber: issueNumber,
+		Content:     content,
+	}, nil
+}
+
+// GetReviewComment gets the note on the lines of the diff of the merge request.
+func (s GitLabService) GetReviewComment(ctx context.Context, prNumber string, reviewID string) (_ functions.GetReviewOutput, err error) {
+	ctx, span := s.startSpan(ctx, "GetReviewComment", telemetry.AttrGitLabIID.String(prNumber), telemetry.AttrGitLabNoteID.String(reviewID))
+	defer func() { telemetry.End(span, err) }()
+
+	n, err := s.getNote(ctx, prNumber, reviewID)
+	if err != nil {
+		return functions.GetReviewOutput{}, err
+	}
+	if n.Position == nil {
+		return functions.GetReviewOutput{}, fmt.Errorf("note %s is not on the lines of the diff", reviewID)
+	}
+
+	return reviewOutput(prNumber, n), nil
+}
+
+func (s GitLabService) getNote(ctx context.Context, iid string, noteID string) (note, error) {
+	var n note
+	if _, err := s.client.do(ctx, http.MethodGet, s.mergeRequestPath(iid)+"/notes/"+url.PathEscape(noteID), nil, &n); err != nil {
+		return note{}, fmt.Errorf("failed to get note: %w", err)
+	}
+	return n, nil
+}
+
+func reviewOutput(iid string, n note) functions.GetReviewOutput {
+	path := n.Position.NewPath
+	endLine := n.Position.NewLine
+	if endLine == 0 {
+		// the note on the removed line
+		path = n.Position.OldPath
+		endLine = n.Position.OldLine
+	}
+	startLine := endLine
+	if r := n.Position.LineRange; r != nil && r.Start.NewLine > 0 {
+		startLine = r.Start.NewLine
+	}
+
+	return functions.GetReviewOutput{
+		IssuesNumber: iid,
+		Path:         path,
+		StartLine:    startLine,
+		EndLine:      endLine,
+		Content:      n.Body,
+	}
+}
+
+func (s GitLabService) CreatePullRequest(ctx context.Context, input platform.NewPullRequest) (_ platform.PullRequest, err error) {
+	ctx, span := s.startSpan(ctx, "CreatePullRequest", telemetry.AttrGitLabBranch.String(input.Head))
+	defer func() { telemetry.End(span, err) }()
+
+	var mr mergeRequest
+	if _, err := s.client.do(ctx, http.MethodPost, s.projectPath(s.repository)+"/merge_requests", map[string]any{
+		"title":         input.Title,
+		"source_branch": input.Head,
+		"target_branch": input.Base,
+		"description":   input.Body,
+		"labels":        strings.Join(input.Labels, ","),
+	}, &mr); err != nil {
+		return platform.PullRequest{}, fmt.Errorf("failed to create merge request: %w", err)
+	}
+
+	return platform.PullRequest{Number: mr.IID}, nil
+}
+
+func (s GitLabService) CloneURL() (string, error) {
+	return s.client.cloneURL(s.owner + "/" + s.repository), nil
+}
+
+// PushAuth returns nil, because the token in the remote URL does not expire.
+func (s GitLabService) PushAuth() (transport.AuthMethod, error) {
+	return nil, nil
+}
+
+const contentSeparator = "---"
+
+// GetRepositoryContent gets the file, or the entries of the directory, on the default branch.
+func (s GitLabService) GetRepositoryContent(ctx context.Context, input functions.GetRepositoryContentInput) (_ functions.GetRepositoryContentOutput, err error) {
+	ctx, span := s.startSpan(ctx, "GetRepositoryContent", telemetry.AttrGitLabContentPath.String(input.Path))
+	defer func() { telemetry.End(span, err) }()
+
+	project := s.projectPath(input.RepositoryName)
+	path := strings.Trim(input.Path, "/")
+
+	// the content is of the default branch of the project without ref, as GitHub does
+	var raw []byte
+	_, err = s.client.do(ctx, http.MethodGet, project+"/repository/files/"+url.PathEscape(path)+"/raw", nil, &raw)
+	if err == nil {
+		return functions.GetRepositoryContentOutput{
+			Content: fmt.Sprintf("%s\nfile name: %s\nfile path: %s\n\n%s", contentSeparator, pathBase(path), path, raw),
+		}, nil
+	}
+	if !rest.IsNotFound(err) {
+		return functions.GetRepositoryContentOutput{}, fmt.Errorf("failed to get repository content: %w", err)
+	}
+
+	// the path is a directory
+	entries, err := listPages[treeEntry](ctx, s.client, project+"/repository/tree?per_page=100&path="+url.QueryEscape(path))
+	if err != nil {
+		return functions.GetRepositoryContentOutput{}, fmt.Errorf("failed to get repository content: %w", err)
+	}
+	if len(entries) == 0 {
+		return functions.GetRepositoryContentOutput{}, fmt.Errorf("repository content %s not found", input.Path)
+	}
+
+	var contentStr string
+	for _, e := range entries {
+		contentStr += fmt.Sprintf("%s\nfile name: %s\nfile path: %s\n\n", contentSeparator, e.Name, e.Path)
+	}
+
+	return functions.GetRepositoryContentOutput{
+		Content: contentStr,
+	}, nil
+}
+
+// CreateIssueComment creates the note on the merge request.
+func (s GitLabService) CreateIssueComment(ctx context.Context, issueNumber string, comment string) (_ functions.CreateIssueCommentOutput, err error) {
+	ctx, span := s.startSpan(ctx, "CreateIssueComment", telemetry.AttrGitLabIID.String(issueNumber))
+	defer func() { telemetry.End(span, err) }()
+
+	if _, err := s.client.do(ctx, http.MethodPost, s.mergeRequestPath(issueNumber)+"/notes", map[string]any{
+		"body": comment,
+	}, nil); err != nil {
+		return functions.CreateIssueCommentOutput{}, fmt.Errorf("failed to create merge request note: %w", err)
+	}
+
+	return functions.CreateIssueCommentOutput{}, nil
+}
+
+// CreateReviewCommentOne starts the discussion on the line of the diff of the merge request.
+// The discussion is on the end line, because GitLab identifies the range of lines by the line codes of the diff.
+func (s GitLabService) CreateReviewCommentOne(ctx context.Context, review functions.CreatePullRequestReviewCommentInput) (_ functions.CreatePullRequestReviewCommentOutput, err error) {
+	ctx, span := s.startSpan(ctx, "CreateReviewCommentOne", telemetry.AttrGitLabIID.String(review.PRNumber))
+	defer func() { telemetry.End(span, err) }()
+
+	mr, err := s.getMergeRequest(ctx, review.PRNumber)
+	if err != nil {
+		return functions.CreatePullRequestReviewCommentOutput{}, err
+	}
+
+	body := review.ReviewComment
+	if review.ReviewStartLine != review.ReviewEndLine {
+		body = fmt.Sprintf("Lines %d-%d:\n\n%s", review.ReviewStartLine, review.ReviewEndLine, body)
+	}
+
+	if _, err := s.client.do(ctx, http.MethodPost, s.mergeRequestPath(review.PRNumber)+"/discussions", map[string]any{
+		"body": body,
+		"position": position{
+			PositionType: "text",
+			BaseSHA:      mr.DiffRefs.BaseSHA,
+			HeadSHA:      mr.DiffRefs.HeadSHA,
+			StartSHA:     mr.DiffRefs.StartSHA,
+			OldPath:      review.ReviewFilePath,
+			NewPath:      review.ReviewFilePath,
+			NewLine:      review.ReviewEndLine,
+		},
+	}, nil); err != nil {
+		return functions.CreatePullRequestReviewCommentOutput{}, fmt.Errorf("failed to create review comment: %w", err)
+	}
+
+	return functions.CreatePullRequestReviewCommentOutput{}, nil
+}
+
+// RequestReviewers adds the users to the reviewers of the merge request.
+// GitLab has no team reviewers.
+func (s GitLabService) RequestReviewers(ctx context.Context, prNumber int, reviewers []string, teamReviewers []string) (_ functions.RequestReviewersOutput, err error) {
+	ctx, span := s.startSpan(ctx, "RequestReviewers", telemetry.AttrGitLabIID.String(strconv.Itoa(prNumber)))
+	defer func() { telemetry.End(span, err) }()
+
+	if len(teamReviewers) > 0 {
+		return functions.RequestReviewersOutput{}, fmt.Errorf("team_reviewers=%s are not supported on GitLab, request the users instead", teamReviewers)
+	}
+
+	iid := strconv.Itoa(prNumber)
+	mr, err := s.getMergeRequest(ctx, iid)
+	if err != nil {
+		return functions.RequestReviewersOutput{}, err
+	}
+
+	// the reviewers are replaced with reviewer_ids, so the current reviewers are kept
+	var ids []int
+	for _, r := range mr.Reviewers {
+		ids = append(ids, r.ID)
+	}
+	for _, name := range reviewers {
+		var users []user
+		if _, err := s.client.do(ctx, http.MethodGet, "/users?username="+url.QueryEscape(name), nil, &users); err != nil {
+			return functions.RequestReviewersOutput{}, fmt.Errorf("failed to find reviewer %s: %w", name, err)
+		}
+		if len(users) == 0 {
+			return functions.RequestReviewersOutput{}, fmt.Errorf("reviewer %s not found", name)
+		}
+		if !slices.Contains(ids, users[0].ID) {
+			ids = append(ids, users[0].ID)
+		}
+	}
+
+	if _, err := s.client.do(ctx, http.MethodPut, s.mergeRequestPath(iid), map[string]any{
+		"reviewer_ids": ids,
+	}, nil); err != nil {
+		return functions.RequestReviewersOutput{},
+			fmt.Errorf("failed to request reviewers=%s to merge request: %w", reviewers, err)
+	}
+
+	return functions.RequestReviewersOutput{}, nil
+}
+
+func pathBase(path string) string {
+	return path[strings.LastIndex(path, "/")+1:]
+}
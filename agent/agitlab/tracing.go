@@ -0,0 +1,16 @@
+package agitlab
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/clover0/issue-agent/platform"
+	"github.com/clover0/issue-agent/telemetry"
+)
+
+// startSpan starts the span of the GitLab operation named like gitlab.GetIssue.
+func (s GitLabService) startSpan(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return platform.StartAPISpan(ctx, "gitlab."+operation, telemetry.AttrGitLabProject.String(s.owner+"/"+s.repository), attrs...)
+}
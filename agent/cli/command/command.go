@@ -7,6 +7,8 @@ import (
 	"github.com/clover0/issue-agent/cli/command/createpr"
 	"github.com/clover0/issue-agent/cli/command/help"
 	"github.com/clover0/issue-agent/cli/command/react"
+	"github.com/clover0/issue-agent/cli/command/resume"
+	"github.com/clover0/issue-agent/cli/command/runlocal"
 	"github.com/clover0/issue-agent/cli/command/version"
 	"github.com/clover0/issue-agent/logger"
 )
@@ -34,6 +36,10 @@ func Execute() error {
 		return createpr.CreatePR(others)
 	case react.ReactCommand:
 		return react.React(others)
+	case runlocal.RunLocalCommand:
+		return runlocal.RunLocal(others)
+	case resume.ResumeCommand:
+		return resume.Resume(others)
 	case help.HelpCommand:
 		help.Help(lo)
 		return nil
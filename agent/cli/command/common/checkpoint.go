@@ -0,0 +1,40 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/clover0/issue-agent/core"
+)
+
+const checkpointDir = ".checkpoints"
+
+// CheckpointPath returns the absolute path of the checkpoint file.
+// When the path is not passed, the file is named by the key in the checkpoint directory of the workdir.
+func CheckpointPath(path string, workDir string, key string) (string, error) {
+	if path == "" {
+		path = filepath.Join(workDir, checkpointDir, key+".json")
+	}
+
+	return filepath.Abs(path)
+}
+
+// NewCheckpointStore creates the checkpoint store.
+// When resuming, the store is loaded from the checkpoint file saved by the previous run.
+func NewCheckpointStore(path string, resume bool, command string, commandArgs []string) (*core.FileCheckpointStore, error) {
+	if resume {
+		return core.LoadFileCheckpointStore(path)
+	}
+
+	return core.NewFileCheckpointStore(path, command, commandArgs), nil
+}
+
+// ExistsDir checks if the directory exists.
+func ExistsDir(dir string) bool {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return false
+	}
+
+	return info.IsDir()
+}
@@ -11,6 +11,8 @@ type CommonInput struct {
 	LogLevel   string
 	Language   string
 	Model      string
+	Checkpoint string
+	Report     string
 }
 
 func AddCommonFlags(fs *flag.FlagSet, cfg *CommonInput) {
@@ -29,4 +31,10 @@ Default: info.`)
 Default: English.`)
 
 	fs.StringVar(&cfg.Model, "model", "", "LLM name. For the model name, check the documentation of each LLM provider.")
+
+	fs.StringVar(&cfg.Checkpoint, "checkpoint", "", `Path to the checkpoint file saving the agents progress to resume the run.
+Default: .checkpoints directory in the workdir.`)
+
+	fs.StringVar(&cfg.Report, "report", "", `Path to the JSON file reporting the run, such as agents, steps, functions and token usage.
+Default: .reports directory in the workdir.`)
 }
@@ -0,0 +1,44 @@
+package common
+
+import (
+	"fmt"
+
+	"github.com/clover0/issue-agent/agitea"
+	"github.com/clover0/issue-agent/agithub"
+	"github.com/clover0/issue-agent/agitlab"
+	"github.com/clover0/issue-agent/config"
+	"github.com/clover0/issue-agent/logger"
+	"github.com/clover0/issue-agent/platform"
+)
+
+// NewPlatformService creates the service of the platform in the configuration for the repository.
+func NewPlatformService(conf config.Config, lo logger.Logger, repository string) (platform.Service, error) {
+	switch conf.Agent.Platform {
+	case config.PlatformGitLab:
+		client, err := agitlab.NewGitLab(conf.Agent.GitLab.BaseURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create GitLab client: %w", err)
+		}
+		return agitlab.NewGitLabService(conf.Agent.Owner(), repository, client, lo), nil
+
+	case config.PlatformGitea:
+		client, err := agitea.NewGitea(conf.Agent.Gitea.BaseURL, conf.Agent.Gitea.GitURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Gitea client: %w", err)
+		}
+		return agitea.NewGiteaService(conf.Agent.Owner(), repository, client, lo), nil
+
+	case config.PlatformGitHub, "":
+		credentials, err := agithub.NewCredentials(conf.Agent.GitHub)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create GitHub credentials: %w", err)
+		}
+		gh, err := agithub.NewGitHub(conf.Agent.GitHub, credentials)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create GitHub client: %w", err)
+		}
+		return agithub.NewGitHubService(conf.Agent.GitHub.Owner, repository, conf.Agent.GitHub.GitURL, gh, credentials, lo), nil
+	}
+
+	return nil, fmt.Errorf("unknown platform: %s", conf.Agent.Platform)
+}
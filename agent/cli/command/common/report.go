@@ -0,0 +1,31 @@
+package common
+
+import (
+	"path/filepath"
+
+	"github.com/clover0/issue-agent/core"
+	"github.com/clover0/issue-agent/logger"
+)
+
+const reportDir = ".reports"
+
+// ReportPath returns the absolute path of the run report file.
+// When the path is not passed, the file is named by the key in the report directory of the workdir.
+func ReportPath(path string, workDir string, key string) (string, error) {
+	if path == "" {
+		path = filepath.Join(workDir, reportDir, key+".json")
+	}
+
+	return filepath.Abs(path)
+}
+
+// SaveReport finishes the run report with the error of the run and writes it to the path.
+// Failing to save does not fail the run, because the report is only a record.
+func SaveReport(lo logger.Logger, report *core.RunReport, path string, runErr error) {
+	report.Finish(runErr)
+	if err := report.Save(path); err != nil {
+		lo.Error("failed to save run report: %s\n", err)
+		return
+	}
+	lo.Info("run report: %s\n", path)
+}
@@ -0,0 +1,29 @@
+package common
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/clover0/issue-agent/config"
+	"github.com/clover0/issue-agent/logger"
+	"github.com/clover0/issue-agent/telemetry"
+)
+
+// StartTracing sets up the tracing by the configuration and starts the root span of the command.
+// end ends the span with the error of the run and flushes the spans to the exporter.
+func StartTracing(ctx context.Context, lo logger.Logger, conf config.Tracing, command string) (_ context.Context, end func(runErr error), _ error) {
+	shutdown, err := telemetry.Setup(ctx, conf)
+	if err != nil {
+		return ctx, nil, fmt.Errorf("setup tracing: %w", err)
+	}
+
+	ctx, span := telemetry.Tracer().Start(ctx, command)
+
+	return ctx, func(runErr error) {
+		telemetry.End(span, runErr)
+		// the context of the run may be canceled, so the spans are flushed with a new context
+		if err := shutdown(context.Background()); err != nil {
+			lo.Error("failed to shutdown tracing: %s\n", err)
+		}
+	}, nil
+}
@@ -3,18 +3,28 @@ package createpr
 import (
 	"context"
 	"fmt"
+	"strings"
 
-	"github.com/clover0/issue-agent/agithub"
 	"github.com/clover0/issue-agent/cli/command/common"
 	"github.com/clover0/issue-agent/config"
 	"github.com/clover0/issue-agent/core"
 	"github.com/clover0/issue-agent/logger"
 	"github.com/clover0/issue-agent/models"
+	"github.com/clover0/issue-agent/platform"
 )
 
 const CreatePrCommand = "create-pr"
 
 func CreatePR(flags []string) error {
+	return createPR(flags, false)
+}
+
+// ResumeCreatePR resumes the create-pr command from the checkpoint saved by the previous run.
+func ResumeCreatePR(flags []string) error {
+	return createPR(flags, true)
+}
+
+func createPR(flags []string, resume bool) (err error) {
 	cliIn, err := ParseCreatePRInput(flags)
 	if err != nil {
 		return fmt.Errorf("failed to parse input: %w", err)
@@ -33,12 +43,43 @@ func CreatePR(flags []string) error {
 
 	lo := logger.NewPrinter(conf.LogLevel)
 
+	ctx, endTracing, err := common.StartTracing(context.Background(), lo, conf.Tracing, CreatePrCommand)
+	if err != nil {
+		return err
+	}
+	defer func() { endTracing(err) }()
+
+	// key names the checkpoint and report files of the issue
+	key := fmt.Sprintf("%s_%s_issues_%s", strings.ReplaceAll(cliIn.GitHubOwner, "/", "_"), cliIn.WorkRepository, cliIn.GithubIssueNumber)
+	checkpointPath, err := common.CheckpointPath(cliIn.Common.Checkpoint, conf.WorkDir, key)
+	if err != nil {
+		return fmt.Errorf("checkpoint path: %w", err)
+	}
+	checkpoint, err := common.NewCheckpointStore(checkpointPath, resume, CreatePrCommand, flags)
+	if err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	lo.Info("checkpoint file: %s\n", checkpoint.Path())
+
+	reportPath, err := common.ReportPath(cliIn.Common.Report, conf.WorkDir, key)
+	if err != nil {
+		return fmt.Errorf("report path: %w", err)
+	}
+	report := core.NewRunReport(CreatePrCommand)
+	defer func() { common.SaveReport(lo, report, reportPath, err) }()
+
+	service, err := common.NewPlatformService(conf, lo, cliIn.WorkRepository)
+	if err != nil {
+		return err
+	}
+
 	if err := common.EnsureDirAndEnter(conf.WorkDir); err != nil {
 		return err
 	}
 
-	if *conf.Agent.GitHub.CloneRepository {
-		if err := agithub.CloneRepository(lo, conf.Agent.GitHub.Owner, cliIn.WorkRepository, cliIn.BaseBranch); err != nil {
+	// the cloned repository remains when resuming in the same environment
+	if *conf.Agent.GitHub.CloneRepository && !(resume && common.ExistsDir(cliIn.WorkRepository)) {
+		if err := platform.CloneRepository(lo, service, cliIn.WorkRepository, cliIn.BaseBranch); err != nil {
 			return fmt.Errorf("clone repository: %w", err)
 		}
 	}
@@ -47,12 +88,11 @@ func CreatePR(flags []string) error {
 		return err
 	}
 
-	gh, err := agithub.NewGitHub()
-	if err != nil {
-		return fmt.Errorf("failed to create GitHub client: %w", err)
+	if resume {
+		if err := checkpoint.RestoreWorkdir(); err != nil {
+			return fmt.Errorf("restore working directory: %w", err)
+		}
 	}
 
-	ctx := context.Background()
-
-	return core.OrchestrateAgentsByIssue(ctx, lo, conf, cliIn.BaseBranch, cliIn.WorkRepository, gh, cliIn.GithubIssueNumber, models.SelectForwarder)
+	return core.OrchestrateAgentsByIssue(ctx, lo, conf, cliIn.BaseBranch, cliIn.WorkRepository, service, cliIn.GithubIssueNumber, models.SelectForwarderByConfig(conf.Agent), models.PriceOf, checkpoint, report)
 }
@@ -3,6 +3,7 @@ package createpr
 import (
 	"flag"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
@@ -42,7 +43,7 @@ func (c *CreatePRInput) MergeConfig(conf config.Config) config.Config {
 	}
 
 	if c.GitHubOwner != "" {
-		conf.Agent.GitHub.Owner = c.GitHubOwner
+		conf.Agent.SetOwner(c.GitHubOwner)
 	}
 
 	return conf
@@ -85,16 +86,17 @@ type ArgGitHubCreatePR struct {
 
 // ParseCreatePRGitHubArg binds the input to the GitHub input
 // expected format: OWNER/REPOSITORY/issues/NUMBER
+// OWNER can be the nested groups such as group/subgroup on GitLab.
 func ParseCreatePRGitHubArg(arg string) (ArgGitHubCreatePR, error) {
 	splits := strings.Split(arg, "/")
-	if len(splits) != 4 {
+	if len(splits) < 4 || splits[len(splits)-2] != "issues" || slices.Contains(splits, "") {
 		return ArgGitHubCreatePR{}, fmt.Errorf("invalid input format: `%s`. valid format is `OWNER/REPOSITORY/issues/NUMBER`", arg)
 	}
 
 	return ArgGitHubCreatePR{
-		Owner:       splits[0],
-		Repository:  splits[1],
-		IssueNumber: splits[3],
+		Owner:       strings.Join(splits[:len(splits)-3], "/"),
+		Repository:  splits[len(splits)-3],
+		IssueNumber: splits[len(splits)-1],
 	}, nil
 }
 
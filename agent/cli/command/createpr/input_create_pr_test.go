@@ -182,6 +182,33 @@ func TestMergeConfig(t *testing.T) {
 				},
 			},
 		},
+		"merge owner into the gitlab owner": {
+			input: &createpr.CreatePRInput{
+				Common:            &common.CommonInput{},
+				GitHubOwner:       "group/subgroup",
+				WorkRepository:    "repo",
+				GithubIssueNumber: "123",
+			},
+			config: config.Config{
+				Agent: config.Agent{
+					Platform: config.PlatformGitLab,
+					GitHub: config.GitHub{
+						Owner: "original-owner",
+					},
+				},
+			},
+			want: config.Config{
+				Agent: config.Agent{
+					Platform: config.PlatformGitLab,
+					GitHub: config.GitHub{
+						Owner: "original-owner",
+					},
+					GitLab: config.GitLab{
+						Owner: "group/subgroup",
+					},
+				},
+			},
+		},
 	}
 
 	for name, tt := range tests {
@@ -251,6 +278,15 @@ func TestParseGitHubArg(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		"valid input: nested groups of GitLab": {
+			input: "group/subgroup/repo/issues/123",
+			want: createpr.ArgGitHubCreatePR{
+				Owner:       "group/subgroup",
+				Repository:  "repo",
+				IssueNumber: "123",
+			},
+			wantErr: false,
+		},
 		"invalid input: missing `issues` segment": {
 			input:   "owner/repo/123",
 			want:    createpr.ArgGitHubCreatePR{},
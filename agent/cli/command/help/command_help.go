@@ -7,6 +7,7 @@ import (
 
 	"github.com/clover0/issue-agent/cli/command/createpr"
 	"github.com/clover0/issue-agent/cli/command/react"
+	"github.com/clover0/issue-agent/cli/command/runlocal"
 	"github.com/clover0/issue-agent/logger"
 )
 
@@ -21,6 +22,7 @@ Command and Flags
 `
 	createPRFlags, _ := createpr.CreatePRFlags()
 	reactFlags, _ := react.ReactFlags()
+	runLocalFlags, _ := runlocal.RunLocalFlags()
 
 	msg += fmt.Sprintf("  %s:\n", createpr.CreatePrCommand)
 	msg += "    Usage:\n"
@@ -39,6 +41,8 @@ Command and Flags
 	msg += "    RESOURCE_FORMAT:\n"
 	msg += "        issue_comment(pull request comment): OWNER/REPO/issues/comments/COMMENT_ID\n"
 	msg += "        pull_request_review_comment: OWNER/REPO/pulls/comments/COMMENT_ID\n"
+	msg += "        note on GitLab merge request: OWNER/REPO/merge_requests/IID/notes/NOTE_ID\n"
+	msg += "        review on Gitea pull request: OWNER/REPO/pulls/NUMBER/reviews/REVIEW_ID\n"
 	msg += "    Example:\n"
 	msg += "       react owner/example/issues/comments/123456 [flags]\n"
 	msg += "    Flags:\n"
@@ -48,6 +52,28 @@ Command and Flags
 		msg += "\n"
 	})
 
+	msg += fmt.Sprintf("  %s:\n", runlocal.RunLocalCommand)
+	msg += "    Usage:\n"
+	msg += fmt.Sprintf("      %s REPOSITORY_PATH [flags]\n", runlocal.RunLocalCommand)
+	msg += "    Run the agents for the issue in the local repository without GitHub.\n"
+	msg += "    The changes are committed to a local branch, and the pull request is written to a file.\n"
+	msg += "    Example:\n"
+	msg += "       run-local ./example -issue_file task.md [flags]\n"
+	msg += "    Flags:\n"
+	runLocalFlags.VisitAll(func(flg *flag.Flag) {
+		msg += fmt.Sprintf("    --%s\n", flg.Name)
+		msg += IndentMultiLine(flg.Usage, "      ")
+		msg += "\n"
+	})
+
+	msg += "  resume:\n"
+	msg += "    Usage:\n"
+	msg += "      resume CHECKPOINT_FILE\n"
+	msg += "    Resume the create-pr, react or run-local command from the checkpoint file saved by the interrupted run.\n"
+	msg += "    The checkpoint file is saved in the .checkpoints directory of the workdir by default.\n"
+	msg += "    Example:\n"
+	msg += "       resume /tmp/repositories/.checkpoints/owner_example_issues_1.json\n"
+
 	lo.Info(msg)
 }
 
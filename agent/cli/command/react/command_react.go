@@ -1,20 +1,31 @@
 package react
 
 import (
+	"context"
 	"fmt"
+	"strings"
 
-	"github.com/clover0/issue-agent/agithub"
 	"github.com/clover0/issue-agent/cli/command/common"
 	"github.com/clover0/issue-agent/config"
 	"github.com/clover0/issue-agent/core"
 	"github.com/clover0/issue-agent/core/functions"
 	"github.com/clover0/issue-agent/logger"
 	"github.com/clover0/issue-agent/models"
+	"github.com/clover0/issue-agent/platform"
 )
 
 const ReactCommand = "react"
 
 func React(flags []string) error {
+	return react(flags, false)
+}
+
+// ResumeReact resumes the react command from the checkpoint saved by the previous run.
+func ResumeReact(flags []string) error {
+	return react(flags, true)
+}
+
+func react(flags []string, resume bool) (err error) {
 	cliIn, err := ParseReactInput(flags)
 	if err != nil {
 		return fmt.Errorf("failed to parse input: %w", err)
@@ -33,18 +44,39 @@ func React(flags []string) error {
 
 	lo := logger.NewPrinter(conf.LogLevel)
 
-	gh, err := agithub.NewGitHub()
+	ctx, endTracing, err := common.StartTracing(context.Background(), lo, conf.Tracing, ReactCommand)
+	if err != nil {
+		return err
+	}
+	defer func() { endTracing(err) }()
+
+	checkpointPath, err := common.CheckpointPath(cliIn.Common.Checkpoint, conf.WorkDir, checkpointKey(cliIn))
+	if err != nil {
+		return fmt.Errorf("checkpoint path: %w", err)
+	}
+	checkpoint, err := common.NewCheckpointStore(checkpointPath, resume, ReactCommand, flags)
 	if err != nil {
-		return fmt.Errorf("failed to create GitHub client: %w", err)
+		return fmt.Errorf("checkpoint: %w", err)
 	}
+	lo.Info("checkpoint file: %s\n", checkpoint.Path())
 
-	ghService := agithub.NewGitHubService(conf.Agent.GitHub.Owner, cliIn.WorkRepository, gh, lo)
+	reportPath, err := common.ReportPath(cliIn.Common.Report, conf.WorkDir, checkpointKey(cliIn))
+	if err != nil {
+		return fmt.Errorf("report path: %w", err)
+	}
+	report := core.NewRunReport(ReactCommand)
+	defer func() { common.SaveReport(lo, report, reportPath, err) }()
 
-	comment, err := getComment(ghService, cliIn)
+	service, err := common.NewPlatformService(conf, lo, cliIn.WorkRepository)
+	if err != nil {
+		return err
+	}
+
+	comment, err := getComment(ctx, service, cliIn)
 	if err != nil {
 		return fmt.Errorf("failed to get comment: %w", err)
 	}
-	pr, err := ghService.GetPullRequest(comment.IssueNumber)
+	pr, err := service.GetPullRequest(ctx, comment.IssueNumber)
 	if err != nil {
 		return fmt.Errorf("failed to get pull request: %w", err)
 	}
@@ -57,8 +89,9 @@ func React(flags []string) error {
 		return err
 	}
 
-	if *conf.Agent.GitHub.CloneRepository {
-		if err := agithub.CloneRepository(lo, conf.Agent.GitHub.Owner, cliIn.WorkRepository, pr.Head); err != nil {
+	// the cloned repository remains when resuming in the same environment
+	if *conf.Agent.GitHub.CloneRepository && !(resume && common.ExistsDir(cliIn.WorkRepository)) {
+		if err := platform.CloneRepository(lo, service, cliIn.WorkRepository, pr.Head); err != nil {
 			return fmt.Errorf("clone repository: %w", err)
 		}
 	}
@@ -67,21 +100,36 @@ func React(flags []string) error {
 		return err
 	}
 
+	if resume {
+		if err := checkpoint.RestoreWorkdir(); err != nil {
+			return fmt.Errorf("restore working directory: %w", err)
+		}
+	}
+
 	return core.OrchestrateAgentsByComment(
-		lo, conf, cliIn.WorkRepository, gh, models.SelectForwarder, comment, pr)
+		ctx, lo, conf, cliIn.WorkRepository, service, models.SelectForwarderByConfig(conf.Agent), models.PriceOf, comment, pr, checkpoint, report)
+}
+
+func checkpointKey(in ReactInput) string {
+	owner := strings.ReplaceAll(in.GitHubOwner, "/", "_")
+	if in.ReactType == ReviewComment {
+		return fmt.Sprintf("%s_%s_pulls_comments_%s", owner, in.WorkRepository, in.ReviewID)
+	}
+
+	return fmt.Sprintf("%s_%s_issues_comments_%s", owner, in.WorkRepository, in.CommentID)
 }
 
-func getComment(ghService agithub.GitHubService, in ReactInput) (functions.GetCommentOutput, error) {
+func getComment(ctx context.Context, service platform.Service, in ReactInput) (functions.GetCommentOutput, error) {
 	switch in.ReactType {
 	case Comment:
-		comment, err := ghService.GetComment(in.CommentID)
+		comment, err := service.GetComment(ctx, in.GithubPRNumber, in.CommentID)
 		if err != nil {
 			return functions.GetCommentOutput{}, fmt.Errorf("failed to get issue: %w", err)
 		}
 		return comment, nil
 
 	case ReviewComment:
-		comment, err := ghService.GetReviewComment(in.ReviewID)
+		comment, err := service.GetReviewComment(ctx, in.GithubPRNumber, in.ReviewID)
 		if err != nil {
 			return functions.GetCommentOutput{}, fmt.Errorf("failed to get review: %w", err)
 		}
@@ -65,7 +65,7 @@ func (c *ReactInput) MergeConfig(conf config.Config) config.Config {
 	}
 
 	if c.GitHubOwner != "" {
-		conf.Agent.GitHub.Owner = c.GitHubOwner
+		conf.Agent.SetOwner(c.GitHubOwner)
 	}
 
 	return conf
@@ -87,10 +87,44 @@ func (c *ReactInput) Validate() error {
 //
 // issue_comment(pull request): OWNER/REPO/issues/comments/COMMENT_ID
 // pull_request_review_comment: OWNER/REPO/pulls/comments/COMMENT_ID
+// note on GitLab merge request: OWNER/REPO/merge_requests/IID/notes/NOTE_ID (OWNER can be group/subgroup)
+// review on Gitea pull request: OWNER/REPO/pulls/NUMBER/reviews/REVIEW_ID
 func BindReactGitHubArg(arg string) (ArgGitHubReact, error) {
 	commonPattern := `^(?P<owner>[^/]+)/(?P<repo>[^/]+)/`
 	issueCommentPattern := commonPattern + `issues/comments/(?P<commentID>[^/]+)$`
 	pullRequestReviewPattern := commonPattern + `pulls/comments/(?P<commentID>[^/]+)$`
+	pullRequestReviewsPattern := commonPattern + `pulls/(?P<number>[^/]+)/reviews/(?P<reviewID>[^/]+)$`
+	mergeRequestNotePattern := `^(?P<owner>[^/]+(?:/[^/]+)*)/(?P<repo>[^/]+)/merge_requests/(?P<iid>[^/]+)/notes/(?P<noteID>[^/]+)$`
+
+	{
+		// handle review on Gitea pull request, which is identified by the pull request and the review
+		re := regexp.MustCompile(pullRequestReviewsPattern)
+		matches := re.FindStringSubmatch(arg)
+		if len(matches) == 1+4 {
+			return ArgGitHubReact{
+				ReactType:  ReviewComment,
+				Owner:      matches[re.SubexpIndex("owner")],
+				Repository: matches[re.SubexpIndex("repo")],
+				PRNumber:   matches[re.SubexpIndex("number")],
+				ReviewID:   matches[re.SubexpIndex("reviewID")],
+			}, nil
+		}
+	}
+
+	{
+		// handle note on GitLab merge request, including the note on the lines of the diff
+		re := regexp.MustCompile(mergeRequestNotePattern)
+		matches := re.FindStringSubmatch(arg)
+		if len(matches) == 1+4 {
+			return ArgGitHubReact{
+				ReactType:  Comment,
+				Owner:      matches[re.SubexpIndex("owner")],
+				Repository: matches[re.SubexpIndex("repo")],
+				PRNumber:   matches[re.SubexpIndex("iid")],
+				CommentID:  matches[re.SubexpIndex("noteID")],
+			}, nil
+		}
+	}
 
 	{
 		// handle pull request review comment
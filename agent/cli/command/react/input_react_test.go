@@ -39,6 +39,30 @@ func TestBindReactGitHubArg(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		"valid: GitLab merge request note input": {
+			input: "group/subgroup/repo/merge_requests/12/notes/345678",
+			want: react.ArgGitHubReact{
+				ReactType:  react.Comment,
+				Owner:      "group/subgroup",
+				Repository: "repo",
+				PRNumber:   "12",
+				CommentID:  "345678",
+				ReviewID:   "",
+			},
+			wantErr: false,
+		},
+		"valid: Gitea pull request review input": {
+			input: "owner/repo/pulls/12/reviews/34",
+			want: react.ArgGitHubReact{
+				ReactType:  react.ReviewComment,
+				Owner:      "owner",
+				Repository: "repo",
+				PRNumber:   "12",
+				CommentID:  "",
+				ReviewID:   "34",
+			},
+			wantErr: false,
+		},
 		"invalid input: wrong format": {
 			input:   "owner/repo/something/comments/123456",
 			want:    react.ArgGitHubReact{},
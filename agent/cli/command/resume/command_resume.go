@@ -0,0 +1,45 @@
+package resume
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/clover0/issue-agent/cli/command/createpr"
+	"github.com/clover0/issue-agent/cli/command/react"
+	"github.com/clover0/issue-agent/cli/command/runlocal"
+	"github.com/clover0/issue-agent/core"
+)
+
+const ResumeCommand = "resume"
+
+// Resume resumes the command run saved in the checkpoint file.
+// expected args: CHECKPOINT_FILE
+func Resume(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("invalid input: `%s CHECKPOINT_FILE` is expected", ResumeCommand)
+	}
+
+	path, err := filepath.Abs(args[0])
+	if err != nil {
+		return fmt.Errorf("checkpoint path: %w", err)
+	}
+
+	cp, err := core.ReadCheckpoint(path)
+	if err != nil {
+		return err
+	}
+
+	// the checkpoint flag is added last so that it takes precedence over the saved one
+	flags := append(cp.CommandArgs, "-checkpoint", path)
+
+	switch cp.Command {
+	case createpr.CreatePrCommand:
+		return createpr.ResumeCreatePR(flags)
+	case react.ReactCommand:
+		return react.ResumeReact(flags)
+	case runlocal.RunLocalCommand:
+		return runlocal.ResumeRunLocal(flags)
+	default:
+		return fmt.Errorf("command %s in checkpoint can not be resumed", cp.Command)
+	}
+}
@@ -0,0 +1,152 @@
+package runlocal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-git/v5"
+
+	"github.com/clover0/issue-agent/cli/command/common"
+	"github.com/clover0/issue-agent/config"
+	"github.com/clover0/issue-agent/core"
+	"github.com/clover0/issue-agent/core/functions"
+	"github.com/clover0/issue-agent/local"
+	"github.com/clover0/issue-agent/logger"
+	"github.com/clover0/issue-agent/models"
+)
+
+const RunLocalCommand = "run-local"
+
+const pullRequestDir = ".pull_requests"
+
+// RunLocal runs the agents for the issue in the local repository without GitHub.
+// The changes are committed to a local branch, and the pull request is written to a file.
+func RunLocal(flags []string) error {
+	return runLocal(flags, false)
+}
+
+// ResumeRunLocal resumes the run-local command from the checkpoint saved by the previous run.
+func ResumeRunLocal(flags []string) error {
+	return runLocal(flags, true)
+}
+
+func runLocal(flags []string, resume bool) (err error) {
+	cliIn, err := ParseRunLocalInput(flags)
+	if err != nil {
+		return fmt.Errorf("failed to parse input: %w", err)
+	}
+
+	conf, err := config.LoadInCommand(cliIn.Common.Config)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	conf = cliIn.MergeConfig(conf)
+
+	if err := config.ValidateLocal(conf); err != nil {
+		return err
+	}
+
+	lo := logger.NewPrinter(conf.LogLevel)
+
+	ctx, endTracing, err := common.StartTracing(context.Background(), lo, conf.Tracing, RunLocalCommand)
+	if err != nil {
+		return err
+	}
+	defer func() { endTracing(err) }()
+
+	text, err := cliIn.IssueText()
+	if err != nil {
+		return err
+	}
+	issue, err := local.ParseIssue(text)
+	if err != nil {
+		return fmt.Errorf("parse issue: %w", err)
+	}
+
+	repoPath, err := filepath.Abs(cliIn.Repository)
+	if err != nil {
+		return fmt.Errorf("repository path: %w", err)
+	}
+
+	// the worktree has the changes of the agent when resuming
+	if !resume {
+		if err := local.CheckCleanWorktree(repoPath); err != nil {
+			return err
+		}
+	}
+
+	// the base branch is saved in the checkpoint, because the current branch is the working branch when resuming
+	if cliIn.BaseBranch == "" {
+		if cliIn.BaseBranch, err = currentBranch(repoPath); err != nil {
+			return fmt.Errorf("base branch: %w", err)
+		}
+		flags = append(flags, "-base_branch", cliIn.BaseBranch)
+	}
+
+	checkpointPath, err := common.CheckpointPath(cliIn.Common.Checkpoint, conf.WorkDir, cliIn.Key())
+	if err != nil {
+		return fmt.Errorf("checkpoint path: %w", err)
+	}
+	checkpoint, err := common.NewCheckpointStore(checkpointPath, resume, RunLocalCommand, flags)
+	if err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	lo.Info("checkpoint file: %s\n", checkpoint.Path())
+
+	reportPath, err := common.ReportPath(cliIn.Common.Report, conf.WorkDir, cliIn.Key())
+	if err != nil {
+		return fmt.Errorf("report path: %w", err)
+	}
+	report := core.NewRunReport(RunLocalCommand)
+	defer func() { common.SaveReport(lo, report, reportPath, err) }()
+
+	pullRequestPath := cliIn.PullRequestFile
+	if pullRequestPath == "" {
+		pullRequestPath = filepath.Join(conf.WorkDir, pullRequestDir, cliIn.Key()+".md")
+	}
+	if pullRequestPath, err = filepath.Abs(pullRequestPath); err != nil {
+		return fmt.Errorf("pull request path: %w", err)
+	}
+
+	if err := os.Chdir(repoPath); err != nil {
+		return fmt.Errorf("change directory: %w", err)
+	}
+
+	if resume {
+		if err := checkpoint.RestoreWorkdir(); err != nil {
+			return fmt.Errorf("restore working directory: %w", err)
+		}
+	}
+
+	submitService, err := local.NewSubmitFileLocalService(lo,
+		functions.SubmitFilesServiceInput{
+			Repository: filepath.Base(repoPath),
+			BaseBranch: cliIn.BaseBranch,
+			GitEmail:   conf.Agent.Git.UserEmail,
+			GitName:    conf.Agent.Git.UserName,
+		},
+		pullRequestPath,
+	)
+	if err != nil {
+		return fmt.Errorf("create submit file service: %w", err)
+	}
+
+	return core.OrchestrateAgentsByLocalIssue(ctx, lo, conf, cliIn.BaseBranch, issue, local.NewRepositoryService(issue), submitService,
+		models.SelectForwarderByConfig(conf.Agent), models.PriceOf, checkpoint, report)
+}
+
+func currentBranch(repoPath string) (string, error) {
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return "", fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	return head.Name().Short(), nil
+}
@@ -0,0 +1,118 @@
+package runlocal
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/clover0/issue-agent/cli/command/common"
+	"github.com/clover0/issue-agent/cli/util"
+	"github.com/clover0/issue-agent/config"
+)
+
+type RunLocalInput struct {
+	Common          *common.CommonInput
+	Repository      string `validate:"required"`
+	Issue           string
+	IssueFile       string
+	BaseBranch      string
+	PullRequestFile string
+}
+
+func (c *RunLocalInput) MergeConfig(conf config.Config) config.Config {
+	if c.Common.LogLevel != "" {
+		conf.LogLevel = c.Common.LogLevel
+	}
+
+	if c.Common.Language != "" {
+		conf.Language = c.Common.Language
+	}
+
+	if c.Common.Model != "" {
+		conf.Agent.Model = c.Common.Model
+	}
+
+	return conf
+}
+
+func (c *RunLocalInput) Validate() error {
+	validate := validator.New()
+	if err := validate.Struct(c); err != nil {
+		errs := err.(validator.ValidationErrors)
+		return fmt.Errorf("validation failed: %w", errs)
+	}
+
+	if (c.Issue == "") == (c.IssueFile == "") {
+		return fmt.Errorf("either issue or issue_file is required")
+	}
+
+	return nil
+}
+
+// IssueText returns the text of the issue passed by the flag or the file.
+func (c *RunLocalInput) IssueText() (string, error) {
+	if c.IssueFile == "" {
+		return c.Issue, nil
+	}
+
+	b, err := os.ReadFile(c.IssueFile)
+	if err != nil {
+		return "", fmt.Errorf("read issue file: %w", err)
+	}
+	return string(b), nil
+}
+
+// Key names the checkpoint, report and pull request files of the run.
+func (c *RunLocalInput) Key() string {
+	issue := "issue"
+	if c.IssueFile != "" {
+		issue = strings.TrimSuffix(filepath.Base(c.IssueFile), filepath.Ext(c.IssueFile))
+	}
+	return fmt.Sprintf("local_%s_%s", filepath.Base(c.Repository), issue)
+}
+
+func RunLocalFlags() (*flag.FlagSet, *RunLocalInput) {
+	flagMapper := &RunLocalInput{
+		Common: &common.CommonInput{},
+	}
+
+	cmd := flag.NewFlagSet("run-local", flag.ExitOnError)
+
+	common.AddCommonFlags(cmd, flagMapper.Common)
+
+	cmd.StringVar(&flagMapper.Issue, "issue", "", "Text of the issue to work on. Either issue or issue_file is required.")
+
+	cmd.StringVar(&flagMapper.IssueFile, "issue_file", "", `Path to the text or markdown file of the issue to work on.
+The first line is the title of the issue.`)
+
+	cmd.StringVar(&flagMapper.BaseBranch, "base_branch", "", `Base branch of the changes.
+Default: the current branch of the repository.`)
+
+	cmd.StringVar(&flagMapper.PullRequestFile, "pull_request_file", "", `Path to the markdown file to write the pull request.
+Default: .pull_requests directory in the workdir.`)
+
+	return cmd, flagMapper
+}
+
+// ParseRunLocalInput parses the input.
+// expected format: REPOSITORY_PATH [flags]
+func ParseRunLocalInput(argAndFlags []string) (RunLocalInput, error) {
+	arg, flags := util.ParseArgFlags(argAndFlags)
+
+	cmd, cliIn := RunLocalFlags()
+	if err := cmd.Parse(flags); err != nil {
+		return RunLocalInput{}, fmt.Errorf("failed to parse input: %w", err)
+	}
+
+	cliIn.Repository = arg
+
+	if err := cliIn.Validate(); err != nil {
+		return RunLocalInput{}, err
+	}
+
+	return *cliIn, nil
+}
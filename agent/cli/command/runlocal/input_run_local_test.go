@@ -0,0 +1,53 @@
+package runlocal_test
+
+import (
+	"testing"
+
+	"github.com/clover0/issue-agent/cli/command/runlocal"
+	"github.com/clover0/issue-agent/test/assert"
+)
+
+func TestParseRunLocalInput(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		input   []string
+		wantKey string
+		wantErr bool
+	}{
+		"issue text": {
+			input:   []string{"./repo", "-issue", "Fix the typo"},
+			wantKey: "local_repo_issue",
+		},
+		"issue file": {
+			input:   []string{"/work/repo", "-issue_file", "tasks/add-flag.md", "-base_branch", "main"},
+			wantKey: "local_repo_add-flag",
+		},
+		"no issue": {
+			input:   []string{"./repo"},
+			wantErr: true,
+		},
+		"both issue and issue file": {
+			input:   []string{"./repo", "-issue", "Fix the typo", "-issue_file", "task.md"},
+			wantErr: true,
+		},
+		"no repository": {
+			input:   []string{},
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := runlocal.ParseRunLocalInput(tt.input)
+			if tt.wantErr {
+				assert.HasError(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, got.Key(), tt.wantKey)
+		})
+	}
+}
@@ -2,15 +2,25 @@ package cli
 
 // Environment variable names to pass to container from host.
 const (
-	AnthropicApiKey = "ANTHROPIC_API_KEY"
-	GithubToken     = "GITHUB_TOKEN"
-	OpenaiApiKey    = "OPENAI_API_KEY"
+	AnthropicApiKey          = "ANTHROPIC_API_KEY"
+	AnthropicVertexProjectID = "ANTHROPIC_VERTEX_PROJECT_ID"
+	CloudMLRegion            = "CLOUD_ML_REGION"
+	GeminiApiKey             = "GEMINI_API_KEY"
+	GiteaToken               = "GITEA_TOKEN"
+	GithubToken              = "GITHUB_TOKEN"
+	GitlabToken              = "GITLAB_TOKEN"
+	OpenaiApiKey             = "OPENAI_API_KEY"
 )
 
 func EnvNames() []string {
 	return []string{
 		AnthropicApiKey,
+		AnthropicVertexProjectID,
+		CloudMLRegion,
+		GeminiApiKey,
+		GiteaToken,
 		GithubToken,
+		GitlabToken,
 		OpenaiApiKey,
 	}
 }
@@ -1,14 +1,17 @@
 package main
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/google/go-github/v73/github"
 
 	"github.com/clover0/issue-agent/agithub"
 	"github.com/clover0/issue-agent/config"
+	"github.com/clover0/issue-agent/core"
 	"github.com/clover0/issue-agent/core/functions"
 	"github.com/clover0/issue-agent/logger"
+	"github.com/clover0/issue-agent/platform"
 )
 
 func main() {
@@ -20,14 +23,15 @@ func main() {
 	lo := logger.NewPrinter(conf.LogLevel)
 
 	ghClient := github.NewClient(nil).WithAuthToken("")
-	submitService := agithub.NopSubmitFileService{}
-	revisionService := agithub.NopSubmitRevisionService{}
+	submitService := platform.NopSubmitFileService{}
+	revisionService := platform.NopSubmitRevisionService{}
 
 	functions.InitializeFunctions(
-		agithub.NewGitHubService(conf.Agent.GitHub.Owner, "repo", ghClient, lo),
+		agithub.NewGitHubService(conf.Agent.GitHub.Owner, "repo", conf.Agent.GitHub.GitURL, ghClient, agithub.Credentials{}, lo),
 		submitService,
 		revisionService,
 		conf.Agent.AllowFunctions,
+		core.FunctionSetting(conf),
 	)
 	functions.InitializeInvokeAgentFunction(conf.Agent.AllowFunctions, agentCallerMock{})
 
@@ -35,7 +39,7 @@ func main() {
 	for _, f := range functions.AllFunctions() {
 		out += fmt.Sprintf("%s: %s\n", f.Name, f.Description)
 		for propKey, values := range f.Parameters["properties"].(map[string]any) {
-			propValues, ok := values.(map[string]any)
+			propValues, ok := values.(functions.JSONSchema)
 			if !ok {
 				lo.Error("failed to get properties\n")
 				return
@@ -51,6 +55,6 @@ func main() {
 
 type agentCallerMock struct{}
 
-func (a agentCallerMock) Invoke(input functions.InvokeAgentInput) (functions.InvokeAgentOutput, error) {
+func (a agentCallerMock) Invoke(_ context.Context, input functions.InvokeAgentInput) (functions.InvokeAgentOutput, error) {
 	return functions.InvokeAgentOutput{}, nil
 }
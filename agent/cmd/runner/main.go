@@ -3,7 +3,9 @@ package main
 import (
 	"bytes"
 	"context"
+	"flag"
 	"fmt"
+	"net/url"
 	"os"
 	"os/exec"
 	"os/signal"
@@ -15,7 +17,9 @@ import (
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 
 	"github.com/clover0/issue-agent/cli"
+	"github.com/clover0/issue-agent/cli/command/common"
 	"github.com/clover0/issue-agent/cli/command/createpr"
+	"github.com/clover0/issue-agent/cli/command/runlocal"
 	"github.com/clover0/issue-agent/config"
 	"github.com/clover0/issue-agent/logger"
 	"github.com/clover0/issue-agent/util"
@@ -23,6 +27,12 @@ import (
 
 const defaultConfigPath = "./issue_agent.yml"
 
+// dockerHost is the host name of the host from the container
+const dockerHost = "host.docker.internal"
+
+// gcpCredentialsPath is the path of the Google Cloud credentials file mounted to the container
+const gcpCredentialsPath = "/agent/config/gcp_credentials.json"
+
 // This value is set at release build time
 // ldflags "-X github.com/clover0/issue-agent/main.containerImageTag=v0.0.1"
 var containerImageTag = "dev"
@@ -55,10 +65,19 @@ func run(lo logger.Logger) error {
 		return err
 	}
 
+	containerArgs := os.Args[1:]
+	var localDockerArgs []string
+	if len(os.Args) > 1 && os.Args[1] == runlocal.RunLocalCommand {
+		containerArgs, localDockerArgs, err = runLocalArgs(os.Args[1:])
+		if err != nil {
+			return err
+		}
+	}
+
 	var awsDockerEnvs []string
-	if util.IsAWSBedrockModel(flags.Common.Model) || util.IsAWSBedrockModel(conf.Agent.Model) {
+	if util.IsAWSBedrockModel(flags.Model) || usesProvider(conf, config.ProviderBedrock, util.IsAWSBedrockModel) {
 		lo.Info("detected using AWS Bedrock, so setup AWS session\n")
-		awsKeys, err := getAWSKeys(lo, flags.Common.AWSProfile, flags.Common.AWSRegion)
+		awsKeys, err := getAWSKeys(lo, flags.AWSProfile, flags.AWSRegion)
 		if err != nil {
 			return err
 		}
@@ -68,6 +87,34 @@ func run(lo logger.Logger) error {
 		awsDockerEnvs = append(awsDockerEnvs, "-e", "AWS_SESSION_TOKEN="+awsKeys.SessionToken)
 	}
 
+	var gcpDockerArgs []string
+	// Vertex AI is not detected from the model name
+	if usesProvider(conf, config.ProviderVertex, func(string) bool { return false }) {
+		// Mount the credentials file of Google Cloud, such as the one created by gcloud or google-github-actions/auth
+		if path, ok := os.LookupEnv("GOOGLE_APPLICATION_CREDENTIALS"); ok {
+			lo.Info("detected using Vertex AI, so mount the Google Cloud credentials\n")
+			gcpDockerArgs = append(gcpDockerArgs, "-v", path+":"+gcpCredentialsPath+":ro")
+			gcpDockerArgs = append(gcpDockerArgs, "-e", "GOOGLE_APPLICATION_CREDENTIALS="+gcpCredentialsPath)
+		}
+	}
+
+	var githubAppDockerArgs []string
+	if app := conf.Agent.GitHub.App; app.Enabled() {
+		if app.PrivateKeyPath != "" {
+			path, err := filepath.Abs(app.PrivateKeyPath)
+			if err != nil {
+				return fmt.Errorf("GitHub App private key path: %w", err)
+			}
+			lo.Info("detected using GitHub App, so mount the private key\n")
+			githubAppDockerArgs = append(githubAppDockerArgs, "-v", path+":"+config.GitHubAppPrivateKeyFilePath+":ro")
+		} else if _, ok := os.LookupEnv(app.PrivateKeyEnv); ok {
+			// docker takes the value from the environment, so the key is not in the arguments
+			githubAppDockerArgs = append(githubAppDockerArgs, "-e", app.PrivateKeyEnv)
+		}
+	}
+
+	openAICompatibleArgs := openAICompatibleDockerArgs(conf)
+
 	// TODO: changeable image name
 	imageName := "ghcr.io/clover0/issue-agent"
 	imageTag := containerImageTag
@@ -84,9 +131,13 @@ func run(lo logger.Logger) error {
 	}
 	args = append(args, dockerEnvs...)
 	args = append(args, awsDockerEnvs...)
+	args = append(args, gcpDockerArgs...)
+	args = append(args, githubAppDockerArgs...)
+	args = append(args, openAICompatibleArgs...)
+	args = append(args, localDockerArgs...)
 	args = append(args, imageName+":"+imageTag)
-	args = append(args, os.Args[1:]...)
-	for _, a := range os.Args[1:] {
+	args = append(args, containerArgs...)
+	for _, a := range containerArgs {
 		if strings.HasSuffix(a, "-config") {
 			break
 		}
@@ -126,11 +177,19 @@ func run(lo logger.Logger) error {
 	return nil
 }
 
-func parseArgs(lo logger.Logger) (*createpr.CreatePRInput, error) {
+func parseArgs(lo logger.Logger) (*common.CommonInput, error) {
 	// TODO:
 	// Since we only need to parse the model, aws-profile, and aws-region arguments,
 	// using CreatePRFlags is not suitable for the current implementation.
-	flags, mapper := createpr.CreatePRFlags()
+	var flags *flag.FlagSet
+	var mapper *common.CommonInput
+	if len(os.Args) > 1 && os.Args[1] == runlocal.RunLocalCommand {
+		f, m := runlocal.RunLocalFlags()
+		flags, mapper = f, m.Common
+	} else {
+		f, m := createpr.CreatePRFlags()
+		flags, mapper = f, m.Common
+	}
 
 	start := 1
 	for i, arg := range os.Args {
@@ -154,6 +213,50 @@ func parseArgs(lo logger.Logger) (*createpr.CreatePRInput, error) {
 	return mapper, nil
 }
 
+// runLocalArgs returns the arguments of run-local command in the container and the docker arguments
+// mounting the repository, the issue file and the directory of the pull request file at the same paths as the host.
+// The pull request file is written to the current directory by default, because the workdir of the container is removed.
+func runLocalArgs(args []string) (containerArgs []string, dockerArgs []string, _ error) {
+	cliIn, err := runlocal.ParseRunLocalInput(args[1:])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	repoPath, err := filepath.Abs(cliIn.Repository)
+	if err != nil {
+		return nil, nil, fmt.Errorf("repository path: %w", err)
+	}
+	dockerArgs = append(dockerArgs, "-v", repoPath+":"+repoPath)
+
+	// the later flags take precedence over the former ones
+	containerArgs = slices.Concat([]string{args[0], repoPath}, args[2:])
+
+	if cliIn.IssueFile != "" {
+		issuePath, err := filepath.Abs(cliIn.IssueFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("issue file path: %w", err)
+		}
+		dockerArgs = append(dockerArgs, "-v", issuePath+":"+issuePath+":ro")
+		containerArgs = append(containerArgs, "-issue_file", issuePath)
+	}
+
+	pullRequestPath := cliIn.PullRequestFile
+	if pullRequestPath == "" {
+		pullRequestPath = cliIn.Key() + ".md"
+	}
+	if pullRequestPath, err = filepath.Abs(pullRequestPath); err != nil {
+		return nil, nil, fmt.Errorf("pull request file path: %w", err)
+	}
+	pullRequestDir := filepath.Dir(pullRequestPath)
+	if err := os.MkdirAll(pullRequestDir, 0755); err != nil {
+		return nil, nil, fmt.Errorf("create directory of pull request file: %w", err)
+	}
+	dockerArgs = append(dockerArgs, "-v", pullRequestDir+":"+pullRequestDir)
+	containerArgs = append(containerArgs, "-pull_request_file", pullRequestPath)
+
+	return containerArgs, dockerArgs, nil
+}
+
 func dockerCmd() string {
 	com, ok := os.LookupEnv("_DOCKER_CMD")
 	if ok {
@@ -202,6 +305,50 @@ func getConfigPathOrDefault() (string, error) {
 	return path, nil
 }
 
+// usesProvider reports whether the agent, the agent roles or the fallbacks use the provider.
+// isProviderModel detects the provider from the model name when the provider is not specified.
+func usesProvider(conf config.Config, provider string, isProviderModel func(model string) bool) bool {
+	settings := []config.AgentSetting{
+		conf.Agents.Planning.WithDefaults(conf.Agent),
+		conf.Agents.Developer.WithDefaults(conf.Agent),
+		conf.Agents.SubAgent.WithDefaults(conf.Agent),
+		conf.Agents.CommentReactor.WithDefaults(conf.Agent),
+	}
+	for _, fb := range conf.Agent.Fallbacks {
+		settings = append(settings, config.AgentSetting{Model: fb.Model, Provider: fb.Provider})
+	}
+
+	for _, s := range settings {
+		if s.Provider == provider || (s.Provider == "" && isProviderModel(s.Model)) {
+			return true
+		}
+	}
+	return false
+}
+
+// openAICompatibleDockerArgs returns the docker arguments for the OpenAI-compatible endpoints,
+// passing the environment variables of the API keys set on the host,
+// and resolving host.docker.internal to the host on Linux as Docker Desktop does.
+func openAICompatibleDockerArgs(conf config.Config) []string {
+	var args []string
+	addHost := false
+	for _, e := range conf.Agent.OpenAICompatible {
+		if e.APIKeyEnv != "" && !slices.Contains(args, e.APIKeyEnv) {
+			if _, ok := os.LookupEnv(e.APIKeyEnv); ok {
+				// docker takes the value from the environment, so the key is not in the arguments
+				args = append(args, "-e", e.APIKeyEnv)
+			}
+		}
+		if u, err := url.Parse(e.BaseURL); err == nil && u.Hostname() == dockerHost {
+			addHost = true
+		}
+	}
+	if addHost {
+		args = append(args, "--add-host", dockerHost+":host-gateway")
+	}
+	return args
+}
+
 // Pass only the environment variables that are required by the agent.
 // This is to avoid passing sensitive information to the container.
 func passEnvs() []string {
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/clover0/issue-agent/config"
+	"github.com/clover0/issue-agent/test/assert"
+)
+
+func TestOpenAICompatibleDockerArgs(t *testing.T) {
+	t.Setenv("AZURE_OPENAI_API_KEY", "key")
+
+	tests := map[string]struct {
+		endpoints []config.OpenAICompatible
+		want      []string
+	}{
+		"api key set on the host": {
+			endpoints: []config.OpenAICompatible{
+				{Name: "azure", BaseURL: "https://example.openai.azure.com/openai/v1", APIKeyEnv: "AZURE_OPENAI_API_KEY"},
+				{Name: "azure-mini", BaseURL: "https://example.openai.azure.com/openai/v1", APIKeyEnv: "AZURE_OPENAI_API_KEY"},
+			},
+			want: []string{"-e", "AZURE_OPENAI_API_KEY"},
+		},
+		"api key not set on the host": {
+			endpoints: []config.OpenAICompatible{
+				{Name: "vllm", BaseURL: "https://vllm.example.com/v1", APIKeyEnv: "VLLM_API_KEY"},
+			},
+			want: nil,
+		},
+		"endpoint on the host": {
+			endpoints: []config.OpenAICompatible{
+				{Name: "ollama", BaseURL: "http://host.docker.internal:11434/v1"},
+			},
+			want: []string{"--add-host", "host.docker.internal:host-gateway"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			conf := config.Config{Agent: config.Agent{OpenAICompatible: tt.endpoints}}
+
+			assert.EqualStringSlices(t, openAICompatibleDockerArgs(conf), tt.want)
+		})
+	}
+}
@@ -4,10 +4,16 @@ import (
 	_ "embed"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"gopkg.in/yaml.v3"
+
+	"github.com/clover0/issue-agent/util/pointer"
 )
 
 //go:embed default_config.yml
@@ -16,7 +22,9 @@ var defaultConfig []byte
 const (
 	// ConfigFilePath always has config.yml mounted to
 	ConfigFilePath = "/agent/config/config.yml"
-	DefaultWorkDir = "/agent/repositories"
+	// GitHubAppPrivateKeyFilePath has the file of github.app.private_key_path mounted to
+	GitHubAppPrivateKeyFilePath = "/agent/config/github_app_private_key.pem"
+	DefaultWorkDir              = "/agent/repositories"
 
 	LogDebug = "debug"
 	LogInfo  = "info"
@@ -28,25 +36,298 @@ type Git struct {
 	UserEmail string `yaml:"user_email"`
 }
 
+// GitHub is the setting of the repository. It is used for the other platforms as well.
 type GitHub struct {
-	CloneRepository *bool    `yaml:"clone_repository"`
-	Owner           string   `yaml:"owner" validate:"required"`
-	PRLabels        []string `yaml:"pr_labels"`
+	CloneRepository *bool `yaml:"clone_repository"`
+
+	// Owner is the owner of the repository. It is the owner on the other platforms unless their owner is set.
+	Owner    string   `yaml:"owner"`
+	PRLabels []string `yaml:"pr_labels"`
+
+	// BaseURL is the API URL of GitHub Enterprise Server. Empty is github.com.
+	BaseURL string `yaml:"base_url" validate:"omitempty,url"`
+
+	// UploadURL is the upload URL of GitHub Enterprise Server. Default is /api/uploads/ on the host of BaseURL.
+	UploadURL string `yaml:"upload_url" validate:"omitempty,url"`
+
+	// GitURL is the base URL of the git remote to clone and push. Default is the host of BaseURL, or https://github.com.
+	GitURL string `yaml:"git_url" validate:"omitempty,url"`
+
+	// App authenticates as the installation of GitHub App instead of GITHUB_TOKEN
+	App GitHubApp `yaml:"app"`
+}
+
+// GitHubApp is the setting of GitHub App. It is enabled when AppID is set.
+type GitHubApp struct {
+	AppID          int64 `yaml:"app_id"`
+	InstallationID int64 `yaml:"installation_id" validate:"required_with=AppID"`
+
+	// PrivateKeyPath is the path to the PEM file of the private key
+	PrivateKeyPath string `yaml:"private_key_path"`
+
+	// PrivateKeyEnv is the name of the environment variable of the PEM of the private key, used when PrivateKeyPath is empty
+	PrivateKeyEnv string `yaml:"private_key_env"`
+}
+
+// Enabled reports whether GitHub App is used.
+func (a GitHubApp) Enabled() bool {
+	return a.AppID != 0
+}
+
+const (
+	PlatformGitHub = "github"
+	PlatformGitLab = "gitlab"
+	PlatformGitea  = "gitea"
+)
+
+type GitLab struct {
+	// BaseURL is the URL of the GitLab instance
+	BaseURL string `yaml:"base_url" validate:"omitempty,url"`
+
+	// Owner is the namespace of the project such as group/subgroup. Default is GitHub.Owner.
+	Owner string `yaml:"owner"`
+}
+
+// Gitea is the setting of the Gitea or Forgejo instance.
+type Gitea struct {
+	// BaseURL is the URL of the instance. It is required for gitea platform.
+	BaseURL string `yaml:"base_url" validate:"omitempty,url"`
+
+	// GitURL is the base URL of the git remote to clone and push. Default is BaseURL.
+	GitURL string `yaml:"git_url" validate:"omitempty,url"`
+
+	// Owner is the owner of the repository. Default is GitHub.Owner.
+	Owner string `yaml:"owner"`
+}
+
+// Defaults of the functions, which the functions use as well when they are not set.
+const (
+	DefaultOpenFileMaxBytes         = 15000
+	DefaultRunCommandTimeout        = 5 * time.Minute
+	DefaultRunCommandMaxOutputBytes = 10000
+)
+
+type AllowedCommand struct {
+	Command string        `yaml:"command" validate:"required"`
+	Timeout time.Duration `yaml:"timeout" validate:"gte=0"`
+}
+
+type RunCommand struct {
+	Commands       []AllowedCommand `yaml:"commands" validate:"dive"`
+	MaxOutputBytes int              `yaml:"max_output_bytes" validate:"gte=0"`
+}
+
+type OpenFile struct {
+	MaxBytes int `yaml:"max_bytes" validate:"gte=0"`
+}
+
+type Functions struct {
+	IgnorePatterns []string   `yaml:"ignore_patterns"`
+	OpenFile       OpenFile   `yaml:"open_file"`
+	RunCommand     RunCommand `yaml:"run_command"`
+}
+
+// Limit limits the usage of LLM. Zero means unlimited.
+type Limit struct {
+	MaxInputTokens  int64   `yaml:"max_input_tokens" validate:"gte=0"`
+	MaxOutputTokens int64   `yaml:"max_output_tokens" validate:"gte=0"`
+	MaxCostUSD      float64 `yaml:"max_cost_usd" validate:"gte=0"`
+}
+
+type Budget struct {
+	// Agent limits each agent
+	Agent Limit `yaml:"agent"`
+
+	// Run limits all agents in a run of the command
+	Run Limit `yaml:"run"`
+}
+
+// ModelCapabilities are the capabilities of the models served by an OpenAI-compatible endpoint.
+type ModelCapabilities struct {
+	// ParallelToolCalls is false when the models cannot return multiple tool calls in a response
+	ParallelToolCalls *bool `yaml:"parallel_tool_calls"`
+
+	// Temperature is false when the models do not accept the temperature parameter, such as reasoning models
+	Temperature *bool `yaml:"temperature"`
+}
+
+// OpenAICompatible is an endpoint serving OpenAI Chat Completions API, such as vLLM, Ollama, LiteLLM and Azure OpenAI.
+type OpenAICompatible struct {
+	Name    string `yaml:"name" validate:"required"`
+	BaseURL string `yaml:"base_url" validate:"required,url"`
+
+	// APIKeyEnv is the name of the environment variable of the API key. No API key is sent when it is empty.
+	APIKeyEnv string `yaml:"api_key_env"`
+
+	// APIKeyHeader is the header sending the API key as it is, such as api-key of Azure OpenAI.
+	// The API key is sent as the bearer token of Authorization header when it is empty.
+	APIKeyHeader string `yaml:"api_key_header"`
+
+	// QueryParams are added to the requests, such as api-version of Azure OpenAI
+	QueryParams map[string]string `yaml:"query_params"`
+
+	// Models served by the endpoint. Agents use the endpoint when the model is one of them.
+	Models       []string          `yaml:"models" validate:"required,min=1"`
+	Capabilities ModelCapabilities `yaml:"capabilities"`
+}
+
+const (
+	ProviderAnthropic = "anthropic"
+	ProviderOpenAI    = "openai"
+	ProviderBedrock   = "bedrock"
+	ProviderVertex    = "vertex"
+	ProviderGemini    = "gemini"
+)
+
+// Providers returns the supported providers of agent.provider.
+func Providers() []string {
+	return []string{
+		ProviderAnthropic,
+		ProviderOpenAI,
+		ProviderBedrock,
+		ProviderVertex,
+		ProviderGemini,
+	}
+}
+
+// ContextWindow compacts the old tool results in the history of agent, when the context exceeds the threshold.
+type ContextWindow struct {
+	// CompactThresholdTokens is the tokens of the context to start compacting. Zero means no compaction.
+	CompactThresholdTokens int64 `yaml:"compact_threshold_tokens" validate:"gte=0"`
+
+	// KeepRecentToolResults is the number of the recent tool results which are not compacted. Default is 10.
+	KeepRecentToolResults *int `yaml:"keep_recent_tool_results" validate:"omitempty,gte=0"`
+}
+
+// Streaming receives the responses of LLM as streams, showing the progress in the debug log.
+type Streaming struct {
+	Enabled bool `yaml:"enabled"`
+
+	// IdleTimeout aborts the request when no event arrives from the stream within the duration
+	IdleTimeout time.Duration `yaml:"idle_timeout" validate:"gte=0"`
+}
+
+// Fallback is the model used when the requests to the previous models fail, such as the outage of the provider.
+type Fallback struct {
+	Model    string `yaml:"model" validate:"required"`
+	Provider string `yaml:"provider" validate:"omitempty,provider"`
 }
 
 type Agent struct {
-	Model          string   `yaml:"model" validate:"required"`
-	MaxSteps       int      `yaml:"max_steps" validate:"gte=0"`
-	Git            Git      `yaml:"git"`
-	GitHub         GitHub   `yaml:"github"`
-	AllowFunctions []string `yaml:"allow_functions"`
+	Model string `yaml:"model" validate:"required"`
+
+	// Provider serves the model. The provider is detected from the model name when it is empty.
+	Provider string `yaml:"provider" validate:"omitempty,provider"`
+
+	MaxSteps int `yaml:"max_steps" validate:"gte=0"`
+	Git      Git `yaml:"git"`
+
+	// Platform hosts the repositories, issues and pull requests
+	Platform string `yaml:"platform" validate:"omitempty,oneof=github gitlab gitea"`
+
+	GitHub           GitHub             `yaml:"github"`
+	GitLab           GitLab             `yaml:"gitlab"`
+	Gitea            Gitea              `yaml:"gitea"`
+	AllowFunctions   []string           `yaml:"allow_functions"`
+	Functions        Functions          `yaml:"functions"`
+	Budget           Budget             `yaml:"budget"`
+	OpenAICompatible []OpenAICompatible `yaml:"openai_compatible" validate:"dive"`
+
+	// Fallbacks are tried in order when the requests to the model of the agent fail
+	Fallbacks []Fallback `yaml:"fallbacks" validate:"dive"`
+
+	ContextWindow ContextWindow `yaml:"context_window"`
+
+	Streaming Streaming `yaml:"streaming"`
+}
+
+// Owner returns the owner of the repository on the platform.
+func (a Agent) Owner() string {
+	switch a.Platform {
+	case PlatformGitLab:
+		if a.GitLab.Owner != "" {
+			return a.GitLab.Owner
+		}
+	case PlatformGitea:
+		if a.Gitea.Owner != "" {
+			return a.Gitea.Owner
+		}
+	}
+	return a.GitHub.Owner
+}
+
+// SetOwner sets the owner of the repository on the platform.
+func (a *Agent) SetOwner(owner string) {
+	switch a.Platform {
+	case PlatformGitLab:
+		a.GitLab.Owner = owner
+	case PlatformGitea:
+		a.Gitea.Owner = owner
+	default:
+		a.GitHub.Owner = owner
+	}
+}
+
+// AgentSetting is the setting of an agent role.
+// The empty fields are complemented by the fields of Agent.
+type AgentSetting struct {
+	Model    string `yaml:"model"`
+	Provider string `yaml:"provider" validate:"omitempty,provider"`
+	MaxSteps int    `yaml:"max_steps" validate:"gte=0"`
+}
+
+// WithDefaults returns the setting complemented by the model, the provider and the max steps of the agent.
+// The provider is inherited only with the model, so that the provider of an overridden model is detected from it.
+func (s AgentSetting) WithDefaults(agent Agent) AgentSetting {
+	if s.Model == "" {
+		s.Model = agent.Model
+		if s.Provider == "" {
+			s.Provider = agent.Provider
+		}
+	}
+	if s.MaxSteps == 0 {
+		s.MaxSteps = agent.MaxSteps
+	}
+	return s
+}
+
+// Agents are the settings of agent roles, such as a small model for planning and a frontier model for development.
+type Agents struct {
+	Planning       AgentSetting `yaml:"planning"`
+	Developer      AgentSetting `yaml:"developer"`
+	SubAgent       AgentSetting `yaml:"sub_agent"`
+	CommentReactor AgentSetting `yaml:"comment_reactor"`
+}
+
+const (
+	TracingOTLP   = "otlp"
+	TracingStdout = "stdout"
+	TracingFile   = "file"
+)
+
+// Tracing is the setting of OpenTelemetry tracing. Tracing is disabled when the exporter is empty.
+type Tracing struct {
+	// Exporter is one of otlp, stdout and file
+	Exporter string `yaml:"exporter" validate:"omitempty,oneof=otlp stdout file"`
+
+	// Endpoint is the URL of the OTLP/HTTP endpoint, such as http://localhost:4318/v1/traces.
+	// OTEL_EXPORTER_OTLP_* environment variables are used when it is empty.
+	Endpoint string            `yaml:"endpoint"`
+	Headers  map[string]string `yaml:"headers"`
+
+	// FilePath is the file the file exporter writes spans to
+	FilePath string `yaml:"file_path" validate:"required_if=Exporter file"`
+
+	ServiceName string `yaml:"service_name"`
 }
 
 type Config struct {
-	Language string `yaml:"language"`
-	WorkDir  string `yaml:"workdir"`
-	LogLevel string `yaml:"log_level" validate:"log_level"`
-	Agent    Agent  `yaml:"agent" validate:"required"`
+	Language string  `yaml:"language"`
+	WorkDir  string  `yaml:"workdir"`
+	LogLevel string  `yaml:"log_level" validate:"log_level"`
+	Agent    Agent   `yaml:"agent" validate:"required"`
+	Agents   Agents  `yaml:"agents"`
+	Tracing  Tracing `yaml:"tracing"`
 }
 
 func isValidLogLevel(fl validator.FieldLevel) bool {
@@ -59,8 +340,12 @@ func isValidLogLevel(fl validator.FieldLevel) bool {
 	return false
 }
 
+func isValidProvider(fl validator.FieldLevel) bool {
+	return slices.Contains(Providers(), fl.Field().String())
+}
+
 // LoadInCommand loads the configuration in command mode.
-// In command, the config file is mounted to a fixed path.
+// In command, the config file and the private key of GitHub App are mounted to the fixed paths.
 func LoadInCommand(path string) (Config, error) {
 	if path == "" {
 		return Load("")
@@ -70,6 +355,10 @@ func LoadInCommand(path string) (Config, error) {
 		return cf, err
 	}
 
+	if cf.Agent.GitHub.App.PrivateKeyPath != "" {
+		cf.Agent.GitHub.App.PrivateKeyPath = GitHubAppPrivateKeyFilePath
+	}
+
 	return cf, nil
 }
 
@@ -102,14 +391,44 @@ func Load(path string) (Config, error) {
 }
 
 func Validate(config Config) error {
+	if err := validateConfig(config); err != nil {
+		return err
+	}
+
+	if config.Agent.Owner() == "" {
+		return fmt.Errorf("validation failed: agent.github.owner or the owner of the platform is required")
+	}
+	return nil
+}
+
+// ValidateLocal validates the configuration of the run on a local repository,
+// which does not require the owner of the repository on the platform.
+func ValidateLocal(config Config) error {
+	return validateConfig(config)
+}
+
+func validateConfig(config Config) error {
 	validate := validator.New()
 	if err := validate.RegisterValidation("log_level", isValidLogLevel); err != nil {
 		return err
 	}
+	if err := validate.RegisterValidation("provider", isValidProvider); err != nil {
+		return err
+	}
 	if err := validate.Struct(config); err != nil {
 		errs := err.(validator.ValidationErrors)
+		for _, e := range errs {
+			if e.Tag() == "provider" {
+				return fmt.Errorf("validation failed: provider %s is not supported, supported providers: %s",
+					e.Value(), strings.Join(Providers(), ", "))
+			}
+		}
 		return fmt.Errorf("validation failed: %w", errs)
 	}
+
+	if config.Agent.Platform == PlatformGitea && config.Agent.Gitea.BaseURL == "" {
+		return fmt.Errorf("validation failed: agent.gitea.base_url is required for gitea platform")
+	}
 	return nil
 }
 
@@ -126,6 +445,25 @@ func setDefaults(conf Config) Config {
 		conf.WorkDir = DefaultWorkDir
 	}
 
+	if conf.Agent.Platform == "" {
+		conf.Agent.Platform = PlatformGitHub
+	}
+
+	if conf.Agent.GitHub.GitURL == "" {
+		conf.Agent.GitHub.GitURL = "https://github.com"
+		if u, err := url.Parse(conf.Agent.GitHub.BaseURL); err == nil && u.Host != "" {
+			conf.Agent.GitHub.GitURL = u.Scheme + "://" + u.Host
+		}
+	}
+
+	if conf.Agent.GitHub.App.PrivateKeyEnv == "" {
+		conf.Agent.GitHub.App.PrivateKeyEnv = "GITHUB_APP_PRIVATE_KEY"
+	}
+
+	if conf.Agent.GitLab.BaseURL == "" {
+		conf.Agent.GitLab.BaseURL = "https://gitlab.com"
+	}
+
 	if conf.Agent.Git.UserName == "" {
 		conf.Agent.Git.UserName = "github-actions[bot]"
 	}
@@ -139,5 +477,40 @@ func setDefaults(conf Config) Config {
 		conf.Agent.GitHub.CloneRepository = &clone
 	}
 
+	if conf.Agent.Functions.OpenFile.MaxBytes == 0 {
+		conf.Agent.Functions.OpenFile.MaxBytes = DefaultOpenFileMaxBytes
+	}
+
+	for i, c := range conf.Agent.Functions.RunCommand.Commands {
+		if c.Timeout == 0 {
+			conf.Agent.Functions.RunCommand.Commands[i].Timeout = DefaultRunCommandTimeout
+		}
+	}
+
+	if conf.Agent.Functions.RunCommand.MaxOutputBytes == 0 {
+		conf.Agent.Functions.RunCommand.MaxOutputBytes = DefaultRunCommandMaxOutputBytes
+	}
+
+	for i, e := range conf.Agent.OpenAICompatible {
+		if e.Capabilities.ParallelToolCalls == nil {
+			conf.Agent.OpenAICompatible[i].Capabilities.ParallelToolCalls = pointer.Ptr(true)
+		}
+		if e.Capabilities.Temperature == nil {
+			conf.Agent.OpenAICompatible[i].Capabilities.Temperature = pointer.Ptr(true)
+		}
+	}
+
+	if conf.Agent.ContextWindow.KeepRecentToolResults == nil {
+		conf.Agent.ContextWindow.KeepRecentToolResults = pointer.Ptr(10)
+	}
+
+	if conf.Agent.Streaming.IdleTimeout == 0 {
+		conf.Agent.Streaming.IdleTimeout = 2 * time.Minute
+	}
+
+	if conf.Tracing.ServiceName == "" {
+		conf.Tracing.ServiceName = "issue-agent"
+	}
+
 	return conf
 }
@@ -4,11 +4,13 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 
 	"github.com/clover0/issue-agent/config"
 	"github.com/clover0/issue-agent/test/assert"
+	"github.com/clover0/issue-agent/util/pointer"
 )
 
 func TestIsValidLogLevel(t *testing.T) {
@@ -106,6 +108,20 @@ agent:
     owner: test-owner
     pr_labels:
       - test-label
+  functions:
+    ignore_patterns:
+      - vendor/
+    run_command:
+      commands:
+        - command: go test ./...
+          timeout: 10m
+        - command: make lint
+  budget:
+    agent:
+      max_output_tokens: 20000
+    run:
+      max_input_tokens: 1000000
+      max_cost_usd: 2.5
 `
 		err := os.WriteFile(configPath, []byte(configContent), 0644)
 		assert.Nil(t, err)
@@ -123,6 +139,17 @@ agent:
 		assert.Equal(t, cfg.Agent.GitHub.Owner, "test-owner")
 		assert.Equal(t, len(cfg.Agent.GitHub.PRLabels), 1)
 		assert.Equal(t, cfg.Agent.GitHub.PRLabels[0], "test-label")
+		assert.EqualStringSlices(t, cfg.Agent.Functions.IgnorePatterns, []string{"vendor/"})
+		assert.Equal(t, len(cfg.Agent.Functions.RunCommand.Commands), 2)
+		assert.Equal(t, cfg.Agent.Functions.RunCommand.Commands[0].Command, "go test ./...")
+		assert.Equal(t, cfg.Agent.Functions.RunCommand.Commands[0].Timeout, 10*time.Minute)
+		assert.Equal(t, cfg.Agent.Functions.RunCommand.Commands[1].Timeout, 5*time.Minute)
+		assert.Equal(t, cfg.Agent.Functions.RunCommand.MaxOutputBytes, 10000)
+		assert.Equal(t, cfg.Tracing.ServiceName, "issue-agent")
+		assert.Equal(t, cfg.Agent.Budget.Agent.MaxOutputTokens, int64(20000))
+		assert.Equal(t, cfg.Agent.Budget.Agent.MaxCostUSD, 0.0)
+		assert.Equal(t, cfg.Agent.Budget.Run.MaxInputTokens, int64(1000000))
+		assert.Equal(t, cfg.Agent.Budget.Run.MaxCostUSD, 2.5)
 	})
 
 	t.Run("non-existent file", func(t *testing.T) {
@@ -244,6 +271,208 @@ func TestValidateConfig(t *testing.T) {
 
 		assert.HasError(t, err)
 	})
+
+	t.Run("owner of the platform without github owner", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := config.Config{
+			LogLevel: config.LogDebug,
+			Agent: config.Agent{
+				Model:    "gpt-4",
+				Platform: config.PlatformGitLab,
+				GitLab: config.GitLab{
+					Owner: "group/subgroup",
+				},
+			},
+		}
+
+		err := config.Validate(cfg)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("unsupported provider", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := config.Config{
+			LogLevel: config.LogDebug,
+			Agent: config.Agent{
+				Model:    "o3",
+				Provider: "azure",
+				GitHub: config.GitHub{
+					Owner: "test-owner",
+				},
+			},
+		}
+
+		err := config.Validate(cfg)
+
+		assert.HasError(t, err)
+		assert.Contains(t, err.Error(), "supported providers: anthropic, openai, bedrock, vertex, gemini")
+	})
+
+	t.Run("file exporter without file path", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := config.Config{
+			LogLevel: config.LogDebug,
+			Agent: config.Agent{
+				Model: "gpt-4",
+				GitHub: config.GitHub{
+					Owner: "test-owner",
+				},
+			},
+			Tracing: config.Tracing{
+				Exporter: config.TracingFile,
+			},
+		}
+
+		err := config.Validate(cfg)
+
+		assert.HasError(t, err)
+	})
+}
+
+func TestValidateLocal(t *testing.T) {
+	t.Parallel()
+
+	t.Run("owner is not required", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := config.Config{
+			LogLevel: config.LogDebug,
+			Agent: config.Agent{
+				Model: "gpt-4",
+			},
+		}
+
+		assert.HasError(t, config.Validate(cfg))
+		assert.NoError(t, config.ValidateLocal(cfg))
+	})
+
+	t.Run("other fields are validated", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := config.Config{
+			LogLevel: "warning",
+			Agent: config.Agent{
+				Model: "gpt-4",
+			},
+		}
+
+		assert.HasError(t, config.ValidateLocal(cfg))
+	})
+}
+
+func TestAgent_Owner(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		agent config.Agent
+		want  string
+	}{
+		"github": {
+			agent: config.Agent{
+				Platform: config.PlatformGitHub,
+				GitHub:   config.GitHub{Owner: "owner"},
+				GitLab:   config.GitLab{Owner: "group"},
+			},
+			want: "owner",
+		},
+		"gitlab owner": {
+			agent: config.Agent{
+				Platform: config.PlatformGitLab,
+				GitHub:   config.GitHub{Owner: "owner"},
+				GitLab:   config.GitLab{Owner: "group/subgroup"},
+			},
+			want: "group/subgroup",
+		},
+		"gitlab falls back to github owner": {
+			agent: config.Agent{
+				Platform: config.PlatformGitLab,
+				GitHub:   config.GitHub{Owner: "owner"},
+			},
+			want: "owner",
+		},
+		"gitea owner": {
+			agent: config.Agent{
+				Platform: config.PlatformGitea,
+				GitHub:   config.GitHub{Owner: "owner"},
+				Gitea:    config.Gitea{Owner: "org"},
+			},
+			want: "org",
+		},
+		"gitea falls back to github owner": {
+			agent: config.Agent{
+				Platform: config.PlatformGitea,
+				GitHub:   config.GitHub{Owner: "owner"},
+			},
+			want: "owner",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.agent.Owner(), tt.want)
+		})
+	}
+}
+
+func TestAgent_SetOwner(t *testing.T) {
+	t.Parallel()
+
+	agent := config.Agent{
+		Platform: config.PlatformGitLab,
+		GitHub:   config.GitHub{Owner: "owner"},
+		GitLab:   config.GitLab{Owner: "group"},
+	}
+
+	agent.SetOwner("group/subgroup")
+
+	assert.Equal(t, agent.Owner(), "group/subgroup")
+	assert.Equal(t, agent.GitHub.Owner, "owner")
+}
+
+func TestAgentSetting_WithDefaults(t *testing.T) {
+	t.Parallel()
+
+	agent := config.Agent{
+		Model:    "claude-sonnet-4-20250514",
+		Provider: config.ProviderAnthropic,
+		MaxSteps: 70,
+	}
+
+	tests := map[string]struct {
+		setting config.AgentSetting
+		want    config.AgentSetting
+	}{
+		"empty setting": {
+			setting: config.AgentSetting{},
+			want:    config.AgentSetting{Model: "claude-sonnet-4-20250514", Provider: config.ProviderAnthropic, MaxSteps: 70},
+		},
+		"overridden setting": {
+			setting: config.AgentSetting{Model: "gpt-4o-mini", Provider: config.ProviderOpenAI, MaxSteps: 20},
+			want:    config.AgentSetting{Model: "gpt-4o-mini", Provider: config.ProviderOpenAI, MaxSteps: 20},
+		},
+		"overridden model only": {
+			setting: config.AgentSetting{Model: "gpt-4o-mini"},
+			want:    config.AgentSetting{Model: "gpt-4o-mini", MaxSteps: 70},
+		},
+		"overridden provider only": {
+			setting: config.AgentSetting{Provider: config.ProviderVertex},
+			want:    config.AgentSetting{Model: "claude-sonnet-4-20250514", Provider: config.ProviderVertex, MaxSteps: 70},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.setting.WithDefaults(agent), tt.want)
+		})
+	}
 }
 
 func TestSetDefaults(t *testing.T) {
@@ -261,6 +490,19 @@ func TestSetDefaults(t *testing.T) {
 		assert.Equal(t, cfg.Agent.Git.UserName, "github-actions[bot]")
 		assert.Equal(t, cfg.Agent.Git.UserEmail, "41898282+github-actions[bot]@users.noreply.github.com")
 		assert.Equal(t, *cfg.Agent.GitHub.CloneRepository, true)
+		assert.Equal(t, cfg.Agent.GitHub.GitURL, "https://github.com")
+		assert.Equal(t, cfg.Agent.Functions.RunCommand.MaxOutputBytes, 10000)
+		assert.Equal(t, *cfg.Agent.ContextWindow.KeepRecentToolResults, 10)
+	})
+
+	t.Run("git url of GitHub Enterprise Server", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := config.Config{}
+		cfg.Agent.GitHub.BaseURL = "https://ghe.example.com/api/v3/"
+		cfg = config.SetDefaults(cfg)
+
+		assert.Equal(t, cfg.Agent.GitHub.GitURL, "https://ghe.example.com")
 	})
 
 	t.Run("preserve existing values", func(t *testing.T) {
@@ -282,6 +524,9 @@ func TestSetDefaults(t *testing.T) {
 					Owner:           "test-owner",
 				},
 				AllowFunctions: []string{"custom-function"},
+				ContextWindow: config.ContextWindow{
+					KeepRecentToolResults: pointer.Ptr(0),
+				},
 			},
 		}
 
@@ -296,5 +541,6 @@ func TestSetDefaults(t *testing.T) {
 		assert.Equal(t, *cfg.Agent.GitHub.CloneRepository, false)
 		assert.Equal(t, len(cfg.Agent.AllowFunctions), 1)
 		assert.Equal(t, cfg.Agent.AllowFunctions[0], "custom-function")
+		assert.Equal(t, *cfg.Agent.ContextWindow.KeepRecentToolResults, 0)
 	})
 }
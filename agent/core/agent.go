@@ -3,14 +3,19 @@ package core
 import (
 	"context"
 	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/clover0/issue-agent/core/functions"
 	"github.com/clover0/issue-agent/core/prompt"
 	"github.com/clover0/issue-agent/logger"
+	"github.com/clover0/issue-agent/telemetry"
+	"github.com/clover0/issue-agent/util"
 )
 
 type AgentLike interface {
-	Work() (lastOutput string, err error)
+	Work(ctx context.Context) (lastOutput string, err error)
 	History() []LLMMessage
 	LastHistory() LLMMessage
 }
@@ -24,6 +29,10 @@ type Agent struct {
 	prompt       prompt.Prompt
 	history      []LLMMessage
 	tools        []functions.Function
+	checkpoint   CheckpointStore
+	meter        *UsageMeter
+	runReport    *RunReport
+	report       *AgentReport
 }
 
 func NewAgent(
@@ -33,6 +42,9 @@ func NewAgent(
 	prompt prompt.Prompt,
 	forwarder LLMForwarder,
 	tools []functions.Function,
+	checkpoint CheckpointStore,
+	meter *UsageMeter,
+	runReport *RunReport,
 ) AgentLike {
 	return &Agent{
 		name:         name,
@@ -42,36 +54,89 @@ func NewAgent(
 		prompt:       prompt,
 		llmForwarder: forwarder,
 		tools:        tools,
+		checkpoint:   checkpoint,
+		meter:        meter,
+		runReport:    runReport,
 	}
 }
 
-func (a *Agent) Work() (lastOutput string, err error) {
-	ctx := context.Background()
+// Work runs the agent until LLM waits for the next instruction.
+// Sub agents invoked by the functions are traced as the children of the span of the agent in the context.
+func (a *Agent) Work(ctx context.Context) (lastOutput string, err error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "invoke_agent "+a.name, trace.WithAttributes(
+		telemetry.AttrAgentName.String(a.name),
+		telemetry.AttrModel.String(a.parameter.Model),
+	))
+	defer func() {
+		span.SetAttributes(
+			telemetry.AttrInputTokens.Int64(a.report.Usage.InputTokens),
+			telemetry.AttrOutputTokens.Int64(a.report.Usage.OutputTokens),
+		)
+		telemetry.End(span, err)
+	}()
+
 	a.logg.Info("[%s]agent starts work\n", a.name)
+	a.report = a.runReport.StartAgent(a.name, a.parameter.Model)
+	defer func() { a.report.finish(err) }()
 
 	completionInput := StartCompletionInput{
 		Model:           a.parameter.Model,
 		SystemPrompt:    a.prompt.SystemPrompt,
 		StartUserPrompt: a.prompt.StartUserPrompt,
-		Functions:       a.tools,
+		Tools:           functions.ToolDefinitions(a.tools),
 	}
 
-	logGreen, logBlue, logRed := a.logg.SetColor(logger.Green), a.logg.SetColor(logger.Blue), a.logg.SetColor(logger.Red)
-	logGreen.Info("[STEP:1]start communication with LLM\n")
-	history, err := a.llmForwarder.StartForward(completionInput)
-	if err != nil {
-		return lastOutput, fmt.Errorf("start llm forward error: %w", err)
-	}
-	a.updateHistory(history)
-
-	a.currentStep = a.llmForwarder.ForwardStep(ctx, history)
+	logGreen, logBlue := a.logg.SetColor(logger.Green), a.logg.SetColor(logger.Blue)
 
+	var history []LLMMessage
 	var steps = 1
+	checkpoint, resumed := a.checkpoint.LoadAgent(a.name)
+	switch {
+	case resumed && checkpoint.Finished:
+		a.logg.Info("[%s]agent has already finished in the checkpoint\n", a.name)
+		a.updateHistory(checkpoint.History)
+		a.report.Resumed = true
+		a.report.addUsage(a.meter.usageOf(a.parameter.Model, usages(checkpoint.History)...))
+		// the agent has finished, so the budget is not checked
+		_ = a.meter.Record(a.name, a.parameter.Model, usages(checkpoint.History)...)
+		return checkpoint.LastOutput, nil
+
+	case resumed:
+		a.logg.Info("[%s]agent resumes from the checkpoint of step %d\n", a.name, checkpoint.Steps)
+		history = checkpoint.History
+		a.updateHistory(history)
+		a.currentStep = checkpoint.Step.Restore()
+		steps = checkpoint.Steps
+		a.report.Resumed = true
+		a.report.addUsage(a.meter.usageOf(a.parameter.Model, usages(history)...))
+		if err := a.meter.Record(a.name, a.parameter.Model, usages(history)...); err != nil {
+			return a.stopByBudget(steps, err)
+		}
+
+	default:
+		logGreen.Info("[STEP:1]start communication with LLM\n")
+		started := time.Now()
+		history, err = a.traceLLM(ctx, func(context.Context) ([]LLMMessage, error) {
+			return a.llmForwarder.StartForward(completionInput)
+		})
+		if err != nil {
+			return lastOutput, fmt.Errorf("start llm forward error: %w", err)
+		}
+		a.updateHistory(history)
+
+		a.currentStep = a.nextStep(ctx, history)
+		if err := a.recordResponse(steps, started, history); err != nil {
+			return a.stopByBudget(steps, err)
+		}
+		a.saveCheckpoint(steps, false, "")
+	}
+
 	loop := true
 	for loop {
 		steps++
 		if steps > a.parameter.MaxSteps {
 			a.logg.Info(fmt.Sprintf("reached to the max steps %d\n", a.parameter.MaxSteps))
+			a.report.ExitReason = ExitMaxSteps
 			break
 		}
 		stepLabel := fmt.Sprintf("[STEP:%d]", steps)
@@ -79,42 +144,33 @@ func (a *Agent) Work() (lastOutput string, err error) {
 		switch a.currentStep.Do {
 		case Exec:
 			logBlue.Info(stepLabel + "execute functions:\n")
-			var input []ReturnToLLMInput
-			for _, fnCtx := range a.currentStep.FunctionContexts {
-				var returningStr string
-				returningStr, err = functions.ExecFunction(
-					a.logg.AddPrefix(stepLabel),
-					fnCtx.Function.Name,
-					fnCtx.FunctionArgs.String(),
-				)
-
-				if err != nil {
-					logRed.Error("function error"+": %s\n", err)
-					returningStr = fmt.Sprintf("Error caused. error message: %s\nChange the arguments before using it again. "+
-						"If you still get an error, change the tool you are using", err.Error())
-				}
-
-				input = append(input, ReturnToLLMInput{
-					ToolCallerID: fnCtx.ToolCallerID,
-					Content:      returningStr,
-				})
-			}
-			a.currentStep = NewReturnToLLMStep(input)
+			started := time.Now()
+			results := execFunctions(ctx, a.logg.AddPrefix(stepLabel), a.currentStep.FunctionContexts, maxParallelFunctions)
+			a.report.addStep(newExecStepReport(steps, started, a.currentStep.FunctionContexts, results))
+			a.currentStep = NewReturnToLLMStep(util.Map(results, func(r functionResult) ReturnToLLMInput { return r.ReturnToLLMInput }))
 
 		case ReturnToLLM:
 			logGreen.Info(stepLabel + "forwarding message to LLM and waiting for response\n")
-			history, err = a.llmForwarder.ForwardLLM(ctx, completionInput, a.currentStep.ReturnToLLMContexts, history)
+			started := time.Now()
+			history, err = a.traceLLM(ctx, func(ctx context.Context) ([]LLMMessage, error) {
+				return a.llmForwarder.ForwardLLM(ctx, completionInput, a.currentStep.ReturnToLLMContexts, history)
+			})
 			if err != nil {
 				a.logg.Error("unrecoverable error: %s\n", err)
 				return lastOutput, err
 			}
 			a.updateHistory(history)
-			a.currentStep = a.llmForwarder.ForwardStep(ctx, history)
+			a.currentStep = a.nextStep(ctx, history)
+			if err := a.recordResponse(steps, started, history); err != nil {
+				return a.stopByBudget(steps, err)
+			}
 
 		case WaitingInstruction:
 			a.logg.Info(stepLabel + "finish instructions\n")
 			lastOutput = a.currentStep.LastOutput
 			loop = false
+			a.saveCheckpoint(steps, true, lastOutput)
+			continue
 
 		case Unrecoverable, Unknown:
 			a.logg.Error("unrecoverable error: %s\n", a.currentStep.UnrecoverableErr)
@@ -124,6 +180,8 @@ func (a *Agent) Work() (lastOutput string, err error) {
 			a.logg.Error("%s does not exist in step types\n", a.currentStep.Do)
 			return lastOutput, fmt.Errorf("%s does not exist in step type", a.currentStep.Do)
 		}
+
+		a.saveCheckpoint(steps, false, "")
 	}
 
 	a.logg.Info("[%s][consumed tokens] total input tokens:%d, total output tokens: %d\n",
@@ -132,6 +190,93 @@ func (a *Agent) Work() (lastOutput string, err error) {
 	return lastOutput, nil
 }
 
+// nextStep decides the next step by the response of LLM.
+func (a *Agent) nextStep(ctx context.Context, history []LLMMessage) Step {
+	step := a.llmForwarder.ForwardStep(ctx, history)
+	if step.Do == WaitingInstruction {
+		step.LastOutput = continuedOutput(history, step.LastOutput)
+	}
+	return step
+}
+
+// saveCheckpoint saves the progress of the agent.
+// Failing to save does not stop the agent, because the checkpoint is only needed to resume.
+func (a *Agent) saveCheckpoint(steps int, finished bool, lastOutput string) {
+	if err := a.checkpoint.SaveAgent(AgentCheckpoint{
+		Name:       a.name,
+		Steps:      steps,
+		Step:       NewStepCheckpoint(a.currentStep),
+		History:    a.history,
+		Finished:   finished,
+		LastOutput: lastOutput,
+	}); err != nil {
+		a.logg.Error("[%s]failed to save checkpoint: %s\n", a.name, err)
+	}
+}
+
+// traceLLM records the span of a LLM call with the usage and the finish reason of the response.
+func (a *Agent) traceLLM(ctx context.Context, forward func(ctx context.Context) ([]LLMMessage, error)) ([]LLMMessage, error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "chat "+a.parameter.Model,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			telemetry.AttrAgentName.String(a.name),
+			telemetry.AttrModel.String(a.parameter.Model),
+		))
+
+	history, err := forward(ctx)
+	if err == nil && len(history) > 0 {
+		last := history[len(history)-1]
+		span.SetAttributes(
+			telemetry.AttrInputTokens.Int64(last.Usage.InputToken),
+			telemetry.AttrOutputTokens.Int64(last.Usage.OutputToken),
+			telemetry.AttrCacheReadTokens.Int64(last.Usage.CacheReadToken),
+			telemetry.AttrCacheCreateTokens.Int64(last.Usage.CacheCreateToken),
+			telemetry.AttrFinishReason.String(string(last.FinishReason)),
+		)
+	}
+	telemetry.End(span, err)
+
+	return history, err
+}
+
+// recordResponse records the last response from LLM to the report and the usage meter.
+func (a *Agent) recordResponse(steps int, started time.Time, history []LLMMessage) error {
+	if len(history) == 0 {
+		return nil
+	}
+	last := history[len(history)-1]
+	model := a.parameter.Model
+	if last.Model != "" {
+		model = last.Model
+	}
+
+	a.report.addUsage(a.meter.usageOf(model, last.Usage))
+	a.report.addStep(StepReport{
+		Step:       steps,
+		Do:         ReturnToLLM,
+		StartedAt:  started,
+		DurationMS: time.Since(started).Milliseconds(),
+		Message:    newMessageReport(last),
+	})
+
+	return a.meter.Record(a.name, model, last.Usage)
+}
+
+// stopByBudget stops the agent keeping the checkpoint, so that the agent can resume with a larger budget.
+func (a *Agent) stopByBudget(steps int, err error) (string, error) {
+	a.saveCheckpoint(steps, false, "")
+	a.logg.Error("[%s]agent stops: %s\n", a.name, err)
+	return "", err
+}
+
+func usages(history []LLMMessage) []LLMUsage {
+	var u []LLMUsage
+	for _, m := range history {
+		u = append(u, m.Usage)
+	}
+	return u
+}
+
 func (a *Agent) updateHistory(history []LLMMessage) {
 	a.history = history
 }
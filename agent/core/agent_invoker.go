@@ -1,6 +1,7 @@
 package core
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/clover0/issue-agent/core/functions"
@@ -13,6 +14,8 @@ type AgentInvoker struct {
 	logg      logger.Logger
 	forwarder LLMForwarder
 	tools     []functions.Function
+	meter     *UsageMeter
+	report    *RunReport
 }
 
 func NewAgentInvoker(
@@ -20,16 +23,20 @@ func NewAgentInvoker(
 	logg logger.Logger,
 	forwarder LLMForwarder,
 	tools []functions.Function,
+	meter *UsageMeter,
+	report *RunReport,
 ) functions.AgentInvokerIF {
 	return &AgentInvoker{
 		params:    params,
 		logg:      logg,
 		forwarder: forwarder,
 		tools:     tools,
+		meter:     meter,
+		report:    report,
 	}
 }
 
-func (a AgentInvoker) Invoke(input functions.InvokeAgentInput) (functions.InvokeAgentOutput, error) {
+func (a AgentInvoker) Invoke(ctx context.Context, input functions.InvokeAgentInput) (functions.InvokeAgentOutput, error) {
 	if input.Name == "" {
 		return functions.InvokeAgentOutput{}, fmt.Errorf("name is required")
 	}
@@ -51,9 +58,14 @@ func (a AgentInvoker) Invoke(input functions.InvokeAgentInput) (functions.Invoke
 		},
 		a.forwarder,
 		a.tools,
+		// sub agents are not resumed, because their names are decided by LLM.
+		// The step invoking the sub agent is executed again on resume.
+		NopCheckpointStore{},
+		a.meter,
+		a.report,
 	)
 
-	lastOutput, err := agent.Work()
+	lastOutput, err := agent.Work(ctx)
 	if err != nil {
 		return functions.InvokeAgentOutput{}, fmt.Errorf("failed to run agent: %w", err)
 	}
@@ -0,0 +1,150 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// Budget limits the usage of LLM. Zero means unlimited.
+type Budget struct {
+	MaxInputTokens  int64
+	MaxOutputTokens int64
+	MaxCostUSD      float64
+}
+
+// exceeded returns the reason when the usage exceeds the budget, otherwise empty.
+func (b Budget) exceeded(u Usage) string {
+	switch {
+	case b.MaxInputTokens > 0 && u.InputTokens > b.MaxInputTokens:
+		return fmt.Sprintf("input tokens %d exceeded the budget %d", u.InputTokens, b.MaxInputTokens)
+	case b.MaxOutputTokens > 0 && u.OutputTokens > b.MaxOutputTokens:
+		return fmt.Sprintf("output tokens %d exceeded the budget %d", u.OutputTokens, b.MaxOutputTokens)
+	case b.MaxCostUSD > 0 && u.CostUSD > b.MaxCostUSD:
+		return fmt.Sprintf("cost $%.4f exceeded the budget $%.4f", u.CostUSD, b.MaxCostUSD)
+	}
+	return ""
+}
+
+// ModelPrice is the price of a model in USD per million tokens.
+type ModelPrice struct {
+	Input      float64
+	Output     float64
+	CacheRead  float64
+	CacheWrite float64
+}
+
+func (p ModelPrice) Cost(u LLMUsage) float64 {
+	return (float64(u.InputToken)*p.Input +
+		float64(u.OutputToken)*p.Output +
+		float64(u.CacheReadToken)*p.CacheRead +
+		float64(u.CacheCreateToken)*p.CacheWrite) / 1_000_000
+}
+
+// PriceOf returns the price of the model. It returns false when the price is unknown.
+type PriceOf = func(model string) (ModelPrice, bool)
+
+// Usage is the accumulated usage of LLM.
+type Usage struct {
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+}
+
+func (u Usage) String() string {
+	return fmt.Sprintf("input tokens: %d, output tokens: %d, cost: $%.4f", u.InputTokens, u.OutputTokens, u.CostUSD)
+}
+
+func (u Usage) add(o Usage) Usage {
+	return Usage{
+		InputTokens:  u.InputTokens + o.InputTokens,
+		OutputTokens: u.OutputTokens + o.OutputTokens,
+		CostUSD:      u.CostUSD + o.CostUSD,
+	}
+}
+
+// UsageMeter accumulates the usage of LLM by agents in a run and enforces the budgets.
+// Agents in a run share one UsageMeter.
+type UsageMeter struct {
+	mu          sync.Mutex
+	priceOf     PriceOf
+	agentBudget Budget
+	runBudget   Budget
+	total       Usage
+	agents      map[string]Usage
+	order       []string
+}
+
+// NewUsageMeter creates the meter. The cost of models without the price is counted as zero.
+func NewUsageMeter(priceOf PriceOf, agentBudget Budget, runBudget Budget) *UsageMeter {
+	return &UsageMeter{
+		priceOf:     priceOf,
+		agentBudget: agentBudget,
+		runBudget:   runBudget,
+		agents:      map[string]Usage{},
+	}
+}
+
+// Record adds the usage of a LLM response to the agent and the run.
+// It returns ErrBudgetExceeded when the usage of the agent or the run exceeds the budget.
+func (m *UsageMeter) Record(agentName string, model string, usages ...LLMUsage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	agent, ok := m.agents[agentName]
+	if !ok {
+		m.order = append(m.order, agentName)
+	}
+	u := m.usageOf(model, usages...)
+	agent = agent.add(u)
+	m.agents[agentName] = agent
+	m.total = m.total.add(u)
+
+	if reason := m.agentBudget.exceeded(agent); reason != "" {
+		return fmt.Errorf("%w: agent %s: %s", ErrBudgetExceeded, agentName, reason)
+	}
+	if reason := m.runBudget.exceeded(m.total); reason != "" {
+		return fmt.Errorf("%w: run: %s", ErrBudgetExceeded, reason)
+	}
+
+	return nil
+}
+
+// usageOf calculates the usage of LLM responses without recording.
+func (m *UsageMeter) usageOf(model string, usages ...LLMUsage) Usage {
+	var sum Usage
+	for _, u := range usages {
+		sum.InputTokens += u.TotalInputToken()
+		sum.OutputTokens += u.TotalOutputToken()
+		if m.priceOf != nil {
+			if price, ok := m.priceOf(model); ok {
+				sum.CostUSD += price.Cost(u)
+			}
+		}
+	}
+	return sum
+}
+
+func (m *UsageMeter) Total() Usage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.total
+}
+
+// Summary reports the usage of each agent and the total in the order the agents started.
+func (m *UsageMeter) Summary() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b strings.Builder
+	for _, name := range m.order {
+		b.WriteString(fmt.Sprintf("%s: %s\n", name, m.agents[name]))
+	}
+	b.WriteString(fmt.Sprintf("total: %s\n", m.total))
+
+	return b.String()
+}
@@ -0,0 +1,78 @@
+package core_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/clover0/issue-agent/core"
+	"github.com/clover0/issue-agent/test/assert"
+)
+
+func TestUsageMeter_Record(t *testing.T) {
+	t.Parallel()
+
+	priceOf := func(model string) (core.ModelPrice, bool) {
+		if model == "known" {
+			return core.ModelPrice{Input: 2, Output: 10, CacheRead: 0.5, CacheWrite: 2.5}, true
+		}
+		return core.ModelPrice{}, false
+	}
+	usage := core.LLMUsage{InputToken: 100_000, OutputToken: 10_000, CacheReadToken: 200_000, CacheCreateToken: 40_000}
+
+	tests := map[string]struct {
+		model        string
+		agentBudget  core.Budget
+		runBudget    core.Budget
+		wantErrAt    int
+		wantContains string
+		wantTotal    core.Usage
+	}{
+		"within the budgets": {
+			model:       "known",
+			agentBudget: core.Budget{MaxInputTokens: 1_000_000},
+			runBudget:   core.Budget{MaxCostUSD: 10},
+			wantTotal:   core.Usage{InputTokens: 900_000, OutputTokens: 150_000, CostUSD: 1.5},
+		},
+		"agent input tokens exceeded": {
+			model:        "known",
+			agentBudget:  core.Budget{MaxInputTokens: 500_000},
+			wantErrAt:    2,
+			wantContains: "agent a: input tokens 600000",
+			wantTotal:    core.Usage{InputTokens: 600_000, OutputTokens: 100_000, CostUSD: 1},
+		},
+		"run cost exceeded across agents": {
+			model:        "known",
+			runBudget:    core.Budget{MaxCostUSD: 1.2},
+			wantErrAt:    3,
+			wantContains: "run: cost $1.5000",
+			wantTotal:    core.Usage{InputTokens: 900_000, OutputTokens: 150_000, CostUSD: 1.5},
+		},
+		"cost of unknown model is zero": {
+			model:     "unknown",
+			runBudget: core.Budget{MaxCostUSD: 0.1},
+			wantTotal: core.Usage{InputTokens: 900_000, OutputTokens: 150_000},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			meter := core.NewUsageMeter(priceOf, tt.agentBudget, tt.runBudget)
+			for i, agent := range []string{"a", "a", "b"} {
+				err := meter.Record(agent, tt.model, usage)
+				if i+1 == tt.wantErrAt {
+					assert.Equal(t, errors.Is(err, core.ErrBudgetExceeded), true)
+					assert.Contains(t, err.Error(), tt.wantContains)
+					break
+				}
+				assert.Nil(t, err)
+			}
+
+			got := meter.Total()
+			assert.Equal(t, got.InputTokens, tt.wantTotal.InputTokens)
+			assert.Equal(t, got.OutputTokens, tt.wantTotal.OutputTokens)
+			assert.Equal(t, got.CostUSD, tt.wantTotal.CostUSD)
+		})
+	}
+}
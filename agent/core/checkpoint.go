@@ -0,0 +1,211 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// CheckpointStore saves the progress of agents so that a run can be resumed.
+type CheckpointStore interface {
+	// LoadAgent returns the last checkpoint of the agent.
+	// ok is false when no checkpoint of the agent exists.
+	LoadAgent(name string) (checkpoint AgentCheckpoint, ok bool)
+
+	// SaveAgent saves the checkpoint of the agent.
+	SaveAgent(checkpoint AgentCheckpoint) error
+}
+
+// Checkpoint is the persisted state of one command run.
+type Checkpoint struct {
+	// Command is the CLI command and its arguments to resume the run.
+	Command     string   `json:"command"`
+	CommandArgs []string `json:"command_args"`
+
+	Agents    map[string]AgentCheckpoint `json:"agents"`
+	Workdir   WorkdirState               `json:"workdir"`
+	UpdatedAt time.Time                  `json:"updated_at"`
+}
+
+// AgentCheckpoint is the persisted state of an agent after a step.
+type AgentCheckpoint struct {
+	Name     string         `json:"name"`
+	Steps    int            `json:"steps"`
+	Step     StepCheckpoint `json:"step"`
+	History  []LLMMessage   `json:"history"`
+	Finished bool           `json:"finished"`
+
+	// LastOutput is the output of the agent when Finished is true.
+	LastOutput string `json:"last_output"`
+}
+
+// StepCheckpoint is a serializable form of Step.
+type StepCheckpoint struct {
+	Do                  DoType               `json:"do"`
+	ReturnToLLMContexts []ReturnToLLMContext `json:"return_to_llm_contexts"`
+	FunctionInputs      []FunctionsInput     `json:"function_inputs"`
+	UnrecoverableErr    string               `json:"unrecoverable_err"`
+	LastOutput          string               `json:"last_output"`
+}
+
+func NewStepCheckpoint(step Step) StepCheckpoint {
+	s := StepCheckpoint{
+		Do:                  step.Do,
+		ReturnToLLMContexts: step.ReturnToLLMContexts,
+		LastOutput:          step.LastOutput,
+	}
+	for _, v := range step.FunctionContexts {
+		s.FunctionInputs = append(s.FunctionInputs, FunctionsInput{
+			FuncName:     v.Function.Name.String(),
+			FunctionArgs: v.FunctionArgs.String(),
+			ToolCallerID: v.ToolCallerID,
+		})
+	}
+	if step.UnrecoverableErr != nil {
+		s.UnrecoverableErr = step.UnrecoverableErr.Error()
+	}
+
+	return s
+}
+
+// Restore rebuilds the Step.
+// Functions are looked up again, so functions must be initialized before calling Restore.
+func (s StepCheckpoint) Restore() Step {
+	switch s.Do {
+	case Exec:
+		return NewExecStep(s.FunctionInputs)
+	case ReturnToLLM:
+		return Step{
+			Do:                  ReturnToLLM,
+			ReturnToLLMContexts: s.ReturnToLLMContexts,
+		}
+	case WaitingInstruction:
+		return NewWaitingInstructionStep(s.LastOutput)
+	case Unrecoverable:
+		return NewUnrecoverableStep(errors.New(s.UnrecoverableErr))
+	}
+
+	return Step{Do: s.Do}
+}
+
+// FileCheckpointStore saves the checkpoint as a JSON file.
+// The whole checkpoint is written after every save, including the working directory state.
+type FileCheckpointStore struct {
+	path string
+
+	mu         sync.Mutex
+	checkpoint Checkpoint
+}
+
+// NewFileCheckpointStore creates a store for a new run.
+// An existing checkpoint file on the path is overwritten at the first save.
+func NewFileCheckpointStore(path string, command string, commandArgs []string) *FileCheckpointStore {
+	return &FileCheckpointStore{
+		path: path,
+		checkpoint: Checkpoint{
+			Command:     command,
+			CommandArgs: commandArgs,
+			Agents:      map[string]AgentCheckpoint{},
+		},
+	}
+}
+
+// LoadFileCheckpointStore creates a store from the checkpoint file saved by a previous run.
+func LoadFileCheckpointStore(path string) (*FileCheckpointStore, error) {
+	cp, err := ReadCheckpoint(path)
+	if err != nil {
+		return nil, err
+	}
+	if cp.Agents == nil {
+		cp.Agents = map[string]AgentCheckpoint{}
+	}
+
+	return &FileCheckpointStore{
+		path:       path,
+		checkpoint: cp,
+	}, nil
+}
+
+// ReadCheckpoint reads the checkpoint file.
+func ReadCheckpoint(path string) (Checkpoint, error) {
+	var cp Checkpoint
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cp, fmt.Errorf("read checkpoint %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return cp, fmt.Errorf("unmarshal checkpoint %s: %w", path, err)
+	}
+
+	return cp, nil
+}
+
+func (s *FileCheckpointStore) Path() string {
+	return s.path
+}
+
+func (s *FileCheckpointStore) LoadAgent(name string) (AgentCheckpoint, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cp, ok := s.checkpoint.Agents[name]
+	return cp, ok
+}
+
+func (s *FileCheckpointStore) SaveAgent(checkpoint AgentCheckpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.checkpoint.Agents[checkpoint.Name] = checkpoint
+	s.checkpoint.UpdatedAt = time.Now()
+
+	workdir, err := CaptureWorkdir(".")
+	if err != nil {
+		return fmt.Errorf("capture working directory: %w", err)
+	}
+	s.checkpoint.Workdir = workdir
+
+	return s.write()
+}
+
+// RestoreWorkdir restores the working directory state saved in the checkpoint to the current directory.
+func (s *FileCheckpointStore) RestoreWorkdir() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.checkpoint.Workdir.Restore(".")
+}
+
+// write writes the checkpoint atomically to avoid a broken file when the process is killed.
+func (s *FileCheckpointStore) write() error {
+	data, err := json.MarshalIndent(s.checkpoint, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal checkpoint: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("create checkpoint directory: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+
+	return os.Rename(tmp, s.path)
+}
+
+// NopCheckpointStore implements CheckpointStore as a no-op store.
+type NopCheckpointStore struct{}
+
+func (NopCheckpointStore) LoadAgent(_ string) (AgentCheckpoint, bool) {
+	return AgentCheckpoint{}, false
+}
+
+func (NopCheckpointStore) SaveAgent(_ AgentCheckpoint) error {
+	return nil
+}
@@ -0,0 +1,104 @@
+package core_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/clover0/issue-agent/core"
+	"github.com/clover0/issue-agent/test/assert"
+)
+
+func TestStepCheckpoint_Restore(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		step core.Step
+	}{
+		"return to llm": {
+			step: core.NewReturnToLLMStep([]core.ReturnToLLMInput{
+				{ToolCallerID: "call_1", ToolName: "open_file", Content: "content"},
+			}),
+		},
+		"waiting instruction": {
+			step: core.NewWaitingInstructionStep("done"),
+		},
+		"unrecoverable": {
+			step: core.NewUnrecoverableStep(errors.New("failed")),
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := core.NewStepCheckpoint(tt.step).Restore()
+
+			assert.Equal(t, got.Do, tt.step.Do)
+			assert.Equal(t, got.LastOutput, tt.step.LastOutput)
+			assert.Equal(t, len(got.ReturnToLLMContexts), len(tt.step.ReturnToLLMContexts))
+			for i := range got.ReturnToLLMContexts {
+				assert.Equal(t, got.ReturnToLLMContexts[i], tt.step.ReturnToLLMContexts[i])
+			}
+			if tt.step.UnrecoverableErr != nil {
+				assert.Equal(t, got.UnrecoverableErr.Error(), tt.step.UnrecoverableErr.Error())
+			}
+		})
+	}
+}
+
+func TestWorkdirState_Restore(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	assert.NoError(t, err)
+	wt, err := repo.Worktree()
+	assert.NoError(t, err)
+
+	writeFile(t, filepath.Join(dir, "tracked.txt"), "tracked")
+	writeFile(t, filepath.Join(dir, "removed.txt"), "removed")
+	_, err = wt.Add(".")
+	assert.NoError(t, err)
+	_, err = wt.Commit("initial", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	assert.NoError(t, err)
+
+	writeFile(t, filepath.Join(dir, "tracked.txt"), "modified")
+	writeFile(t, filepath.Join(dir, "sub", "new.txt"), "new")
+	assert.NoError(t, os.Remove(filepath.Join(dir, "removed.txt")))
+
+	state, err := core.CaptureWorkdir(dir)
+	assert.NoError(t, err)
+
+	// lose the uncommitted changes
+	assert.NoError(t, wt.Reset(&git.ResetOptions{Mode: git.HardReset}))
+	assert.NoError(t, os.RemoveAll(filepath.Join(dir, "sub")))
+
+	assert.NoError(t, state.Restore(dir))
+
+	assert.Equal(t, readFile(t, filepath.Join(dir, "tracked.txt")), "modified")
+	assert.Equal(t, readFile(t, filepath.Join(dir, "sub", "new.txt")), "new")
+	_, err = os.Stat(filepath.Join(dir, "removed.txt"))
+	assert.Equal(t, os.IsNotExist(err), true)
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+
+	return string(data)
+}
@@ -0,0 +1,126 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+
+	"github.com/clover0/issue-agent/core/store"
+)
+
+// WorkdirState is the state of the git working directory that agents are changing.
+// Uncommitted files are saved with their content so that they survive losing the working directory.
+type WorkdirState struct {
+	Branch  string       `json:"branch"`
+	Head    string       `json:"head"`
+	Files   []store.File `json:"files"`
+	Removed []string     `json:"removed"`
+}
+
+// CaptureWorkdir captures the state of the git repository in dir.
+func CaptureWorkdir(dir string) (WorkdirState, error) {
+	var state WorkdirState
+
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return state, fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return state, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	state.Branch = head.Name().Short()
+	state.Head = head.Hash().String()
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		return state, fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	statuses, err := wt.Status()
+	if err != nil {
+		return state, fmt.Errorf("failed to get worktree status: %w", err)
+	}
+
+	for path, status := range statuses {
+		if status.Worktree == git.Deleted || (status.Staging == git.Deleted && status.Worktree != git.Untracked) {
+			state.Removed = append(state.Removed, path)
+			continue
+		}
+		if status.Worktree == git.Unmodified && status.Staging == git.Unmodified {
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(dir, path))
+		if err != nil {
+			return state, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		state.Files = append(state.Files, store.File{
+			Path:    path,
+			Content: string(content),
+		})
+	}
+
+	return state, nil
+}
+
+// Restore switches the repository in dir to the saved branch and writes back the saved files.
+// When the branch does not exist, it is created from the saved HEAD commit if the commit exists.
+func (w WorkdirState) Restore(dir string) error {
+	if w.Branch == "" {
+		return nil
+	}
+
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	if head.Name().Short() != w.Branch {
+		branchRef := plumbing.NewBranchReferenceName(w.Branch)
+		opts := &git.CheckoutOptions{Branch: branchRef, Keep: true}
+		if _, err := repo.Reference(branchRef, true); errors.Is(err, plumbing.ErrReferenceNotFound) {
+			opts.Create = true
+			hash := plumbing.NewHash(w.Head)
+			if _, err := repo.CommitObject(hash); err == nil {
+				opts.Hash = hash
+			}
+		}
+		if err := wt.Checkout(opts); err != nil {
+			return fmt.Errorf("failed to checkout branch %s: %w", w.Branch, err)
+		}
+	}
+
+	for _, f := range w.Files {
+		path := filepath.Join(dir, f.Path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("mkdir all %s error: %w", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(f.Content), 0644); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", f.Path, err)
+		}
+	}
+
+	for _, path := range w.Removed {
+		if err := os.Remove(filepath.Join(dir, path)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,178 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/clover0/issue-agent/config"
+	"github.com/clover0/issue-agent/core/functions"
+	"github.com/clover0/issue-agent/logger"
+)
+
+// compactedPrefix marks the tool results compacted by ContextWindowForwarder.
+const compactedPrefix = "[compacted] "
+
+// ContextWindowForwarder compacts the old tool results in the history
+// before forwarding, when the context exceeds the threshold.
+// The tool results are replaced with short notes instead of being removed,
+// so that the pairs of the tool calls and the results stay valid for every provider.
+type ContextWindowForwarder struct {
+	LLMForwarder
+	lo      logger.Logger
+	setting config.ContextWindow
+}
+
+func NewContextWindowForwarder(lo logger.Logger, forwarder LLMForwarder, setting config.ContextWindow) ContextWindowForwarder {
+	return ContextWindowForwarder{
+		LLMForwarder: forwarder,
+		lo:           lo.AddPrefix("[ContextWindow] "),
+		setting:      setting,
+	}
+}
+
+func (f ContextWindowForwarder) ForwardLLM(
+	ctx context.Context,
+	input StartCompletionInput,
+	llmContexts []ReturnToLLMContext,
+	history []LLMMessage,
+) ([]LLMMessage, error) {
+	tokens := contextTokens(history, llmContexts)
+	if tokens > f.setting.CompactThresholdTokens {
+		var saved int64
+		history, saved = compactHistory(history, tokens-f.setting.CompactThresholdTokens, *f.setting.KeepRecentToolResults)
+		f.lo.Info("context has about %d tokens over the threshold %d, compacted about %d tokens\n",
+			tokens, f.setting.CompactThresholdTokens, saved)
+	}
+
+	return f.LLMForwarder.ForwardLLM(ctx, input, llmContexts, history)
+}
+
+// estimateTokens estimates the tokens of the text, assuming about 4 characters per token.
+func estimateTokens(s string) int64 {
+	return int64(len(s) / 4)
+}
+
+// messageTokens estimates the tokens of the message.
+func messageTokens(m LLMMessage) int64 {
+	tokens := estimateTokens(m.RawContent)
+	for _, c := range m.ReturnedToolCalls {
+		tokens += estimateTokens(c.Argument)
+	}
+	return tokens
+}
+
+// contextTokens returns the tokens of the context including the new contexts.
+// The usage of the last response is the actual tokens of the history until the response,
+// and the tokens of the following messages are estimated.
+func contextTokens(history []LLMMessage, llmContexts []ReturnToLLMContext) int64 {
+	var tokens int64
+	for i := len(history) - 1; i >= 0; i-- {
+		u := history[i].Usage
+		if history[i].Role == LLMAssistant && u != (LLMUsage{}) {
+			tokens += u.InputToken + u.CacheReadToken + u.CacheCreateToken + u.OutputToken
+			break
+		}
+		tokens += messageTokens(history[i])
+	}
+
+	for _, c := range llmContexts {
+		tokens += estimateTokens(c.Content)
+	}
+	return tokens
+}
+
+// compactHistory compacts the tool results until about the excess tokens are saved.
+// The results of open_file superseded by the later operations to the same file are compacted first,
+// and then the old tool results except the recent ones.
+// It returns the new history and the estimated saved tokens.
+func compactHistory(history []LLMMessage, excess int64, keepRecent int) ([]LLMMessage, int64) {
+	compacted := slices.Clone(history)
+	calls := toolCallsByID(history)
+
+	var saved int64
+	compact := func(i int, note string) {
+		if len(compacted[i].RawContent) <= len(compactedPrefix+note) {
+			return
+		}
+		saved += messageTokens(compacted[i]) - estimateTokens(compactedPrefix+note)
+		compacted[i].RawContent = compactedPrefix + note
+	}
+
+	var results []int
+	for i, m := range compacted {
+		if m.Role == LLMTool && !strings.HasPrefix(m.RawContent, compactedPrefix) {
+			results = append(results, i)
+		}
+	}
+
+	for _, i := range results {
+		call := calls[compacted[i].RespondToolCall.ToolCallerID]
+		if call.ToolName != functions.FuncOpenFile {
+			continue
+		}
+		path := pathOf(call)
+		if path == "" || !operatedLater(compacted[i+1:], calls, path) {
+			continue
+		}
+		compact(i, fmt.Sprintf("the content of %s was removed, because the file was opened or changed later", path))
+	}
+
+	for n, i := range results {
+		if saved >= excess || n >= len(results)-keepRecent {
+			break
+		}
+		if strings.HasPrefix(compacted[i].RawContent, compactedPrefix) {
+			continue
+		}
+		name := calls[compacted[i].RespondToolCall.ToolCallerID].ToolName
+		compact(i, fmt.Sprintf("the result of %s was removed to save the context, call the function again if needed", name))
+	}
+
+	return compacted, saved
+}
+
+// toolCallsByID returns the tool calls returned by LLM by the tool caller ID.
+func toolCallsByID(history []LLMMessage) map[string]ToolCall {
+	calls := map[string]ToolCall{}
+	for _, m := range history {
+		for _, c := range m.ReturnedToolCalls {
+			calls[c.ToolCallerID] = c
+		}
+	}
+	return calls
+}
+
+// operatedLater reports whether the messages have a result of the file functions to the path.
+func operatedLater(messages []LLMMessage, calls map[string]ToolCall, path string) bool {
+	fileFunctions := []string{
+		functions.FuncOpenFile,
+		functions.FuncModifyFile,
+		functions.FuncPutFile,
+		functions.FuncReplaceInFile,
+		functions.FuncRemoveFile,
+	}
+	for _, m := range messages {
+		if m.Role != LLMTool {
+			continue
+		}
+		call := calls[m.RespondToolCall.ToolCallerID]
+		if slices.Contains(fileFunctions, call.ToolName) && pathOf(call) == path {
+			return true
+		}
+	}
+	return false
+}
+
+// pathOf returns the path argument of the file functions.
+func pathOf(call ToolCall) string {
+	var args struct {
+		Path string `json:"path"`
+	}
+	if err := json.Unmarshal([]byte(call.Argument), &args); err != nil {
+		return ""
+	}
+	return args.Path
+}
@@ -0,0 +1,103 @@
+package core_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/clover0/issue-agent/config"
+	"github.com/clover0/issue-agent/core"
+	"github.com/clover0/issue-agent/test/assert"
+	"github.com/clover0/issue-agent/test/loggertest"
+	"github.com/clover0/issue-agent/util/pointer"
+)
+
+// capturingForwarder keeps the history forwarded to LLM.
+type capturingForwarder struct {
+	core.LLMForwarder
+	history []core.LLMMessage
+}
+
+func (f *capturingForwarder) ForwardLLM(
+	_ context.Context, _ core.StartCompletionInput, _ []core.ReturnToLLMContext, history []core.LLMMessage,
+) ([]core.LLMMessage, error) {
+	f.history = history
+	return history, nil
+}
+
+func TestContextWindowForwarder(t *testing.T) {
+	t.Parallel()
+
+	fileContent := strings.Repeat("line of file\n", 1000)
+	toolCall := func(id string, name string, args string) core.LLMMessage {
+		return core.LLMMessage{
+			Role:              core.LLMAssistant,
+			ReturnedToolCalls: []core.ToolCall{{ToolCallerID: id, ToolName: name, Argument: args}},
+			Usage:             core.LLMUsage{InputToken: 100},
+		}
+	}
+	toolResult := func(id string, content string) core.LLMMessage {
+		return core.LLMMessage{Role: core.LLMTool, RawContent: content, RespondToolCall: core.ToolCall{ToolCallerID: id}}
+	}
+	history := []core.LLMMessage{
+		{Role: core.LLMUser, RawContent: "fix the issue"},
+		toolCall("1", "open_file", `{"path":"a.go"}`),
+		toolResult("1", fileContent),
+		toolCall("2", "open_file", `{"path":"b.go"}`),
+		toolResult("2", fileContent),
+		toolCall("3", "modify_file", `{"path":"a.go","content_text":"package a"}`),
+		toolResult("3", "modified"),
+		toolCall("4", "list_files", `{"path":"."}`),
+		toolResult("4", fileContent),
+	}
+	// the context is about 6250 tokens by the usage of the last response and the estimated tool result
+	history[7].Usage = core.LLMUsage{InputToken: 3000}
+
+	tests := map[string]struct {
+		threshold     int64
+		keepRecent    int
+		wantCompacted []bool
+	}{
+		"under the threshold": {
+			threshold:     10000,
+			keepRecent:    1,
+			wantCompacted: []bool{false, false, false, false},
+		},
+		"superseded open_file only": {
+			threshold:     5500,
+			keepRecent:    1,
+			wantCompacted: []bool{true, false, false, false},
+		},
+		"old tool results except recent ones": {
+			threshold:     1000,
+			keepRecent:    1,
+			wantCompacted: []bool{true, true, false, false},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			capturing := &capturingForwarder{}
+			forwarder := core.NewContextWindowForwarder(loggertest.NewTestLogger(), capturing, config.ContextWindow{
+				CompactThresholdTokens: tt.threshold,
+				KeepRecentToolResults:  pointer.Ptr(tt.keepRecent),
+			})
+
+			_, err := forwarder.ForwardLLM(t.Context(), core.StartCompletionInput{}, nil, history)
+			assert.NoError(t, err)
+
+			// the pairs of the tool calls and the results are kept
+			assert.Equal(t, len(capturing.history), len(history))
+			for i, resultIndex := range []int{2, 4, 6, 8} {
+				got := capturing.history[resultIndex]
+				assert.Equal(t, got.RespondToolCall, history[resultIndex].RespondToolCall)
+				assert.Equal(t, strings.HasPrefix(got.RawContent, "[compacted] "), tt.wantCompacted[i])
+			}
+
+			// the history of the agent is not modified
+			assert.Equal(t, history[2].RawContent, fileContent)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/clover0/issue-agent/core/functions"
+	"github.com/clover0/issue-agent/logger"
+)
+
+// maxParallelFunctions is the number of read-only functions executed at the same time in a step.
+const maxParallelFunctions = 4
+
+// functionResult is the result of a function returned to LLM with the execution details for the report.
+type functionResult struct {
+	ReturnToLLMInput
+	Duration time.Duration
+	Err      error
+}
+
+// execFunctions executes the functions called by LLM in a step.
+// Consecutive read-only functions run concurrently up to parallelism,
+// and the other functions run one by one after the former functions finish,
+// so that every function sees the changes by the functions called before it.
+// The results are in the same order as the function contexts.
+func execFunctions(ctx context.Context, l logger.Logger, fnCtxs []FunctionContext, parallelism int) []functionResult {
+	results := make([]functionResult, len(fnCtxs))
+	sem := make(chan struct{}, max(parallelism, 1))
+	var wg sync.WaitGroup
+
+	for i, fnCtx := range fnCtxs {
+		if !fnCtx.Function.ReadOnly {
+			wg.Wait()
+			results[i] = execFunction(ctx, l, fnCtx)
+			continue
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = execFunction(ctx, l, fnCtx)
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+func execFunction(ctx context.Context, l logger.Logger, fnCtx FunctionContext) functionResult {
+	started := time.Now()
+	returningStr, err := functions.ExecFunction(ctx, l, fnCtx.Function.Name, fnCtx.FunctionArgs.String())
+	duration := time.Since(started)
+	if err != nil {
+		l.SetColor(logger.Red).Error("function error"+": %s\n", err)
+		returningStr = fmt.Sprintf("Error caused. error message: %s\nChange the arguments before using it again. "+
+			"If you still get an error, change the tool you are using", err.Error())
+	}
+
+	return functionResult{
+		ReturnToLLMInput: ReturnToLLMInput{
+			ToolCallerID: fnCtx.ToolCallerID,
+			ToolName:     fnCtx.Function.Name.String(),
+			Content:      returningStr,
+		},
+		Duration: duration,
+		Err:      err,
+	}
+}
@@ -0,0 +1,81 @@
+package core_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/clover0/issue-agent/core"
+	"github.com/clover0/issue-agent/core/functions"
+	"github.com/clover0/issue-agent/test/assert"
+	"github.com/clover0/issue-agent/test/loggertest"
+)
+
+type execTestInput struct {
+	ID    string `json:"id"`
+	Sleep int    `json:"sleep"`
+}
+
+func TestExecFunctions(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu         sync.Mutex
+		events     []string
+		running    atomic.Int32
+		maxRunning atomic.Int32
+	)
+	record := func(e string) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	}
+	handler := func(_ context.Context, input execTestInput) (string, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(time.Duration(input.Sleep) * time.Millisecond)
+		record(input.ID)
+		if input.ID == "error" {
+			return "", fmt.Errorf("failed")
+		}
+		return "result " + input.ID, nil
+	}
+
+	read := functions.NewReadOnlyFunction("test_exec_read", "read", handler)
+	write := functions.NewFunction("test_exec_write", "write", handler)
+	functions.Register(read)
+	functions.Register(write)
+
+	fnCtxs := []core.FunctionContext{
+		{Function: read, FunctionArgs: `{"id": "r1", "sleep": 60}`, ToolCallerID: "1"},
+		{Function: read, FunctionArgs: `{"id": "r2", "sleep": 40}`, ToolCallerID: "2"},
+		{Function: read, FunctionArgs: `{"id": "r3", "sleep": 20}`, ToolCallerID: "3"},
+		{Function: write, FunctionArgs: `{"id": "w1"}`, ToolCallerID: "4"},
+		{Function: read, FunctionArgs: `{"id": "error"}`, ToolCallerID: "5"},
+	}
+
+	got := core.ExecFunctions(t.Context(), loggertest.NewTestLogger(), fnCtxs, 2)
+
+	assert.Equal(t, len(got), len(fnCtxs))
+	for i, r := range got {
+		assert.Equal(t, r.ToolCallerID, fnCtxs[i].ToolCallerID)
+	}
+	assert.Equal(t, got[0].Content, "result r1")
+	assert.Equal(t, got[2].Content, "result r3")
+	assert.Equal(t, got[3].Content, "result w1")
+	assert.Contains(t, got[4].Content, "error message: failed")
+	assert.HasError(t, got[4].Err)
+
+	// the write function runs after all the former read-only functions
+	assert.Equal(t, events[3], "w1")
+	assert.Equal(t, maxRunning.Load(), int32(2))
+}
@@ -0,0 +1,5 @@
+package core
+
+var ExecFunctions = execFunctions
+
+var ContinuedOutput = continuedOutput
@@ -0,0 +1,130 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/clover0/issue-agent/logger"
+	"github.com/clover0/issue-agent/util"
+)
+
+// ModelForwarder is the forwarder of a model.
+type ModelForwarder struct {
+	Model     string
+	Forwarder LLMForwarder
+}
+
+// FallbackForwarder forwards to the next model when the current model fails temporarily,
+// such as the rate limit, the outage or the overload of the provider after the retries of the client.
+// Invalid requests and authentication errors are returned without falling back.
+// The history is passed to the next forwarder as it is, because LLMMessage is provider-neutral.
+// Once it falls back, the following requests are forwarded to the next model.
+type FallbackForwarder struct {
+	lo         logger.Logger
+	forwarders []ModelForwarder
+
+	mu      *sync.Mutex
+	current *int
+}
+
+// NewFallbackForwarder creates the forwarder trying the forwarders in order.
+// The first forwarder is the forwarder of the model of the agent.
+func NewFallbackForwarder(lo logger.Logger, forwarders []ModelForwarder) FallbackForwarder {
+	return FallbackForwarder{
+		lo:         lo.AddPrefix("[Fallback] "),
+		forwarders: forwarders,
+		mu:         &sync.Mutex{},
+		current:    new(int),
+	}
+}
+
+func (f FallbackForwarder) StartForward(input StartCompletionInput) ([]LLMMessage, error) {
+	return f.forward(context.Background(), func(mf ModelForwarder, input StartCompletionInput) ([]LLMMessage, error) {
+		return mf.Forwarder.StartForward(input)
+	}, input)
+}
+
+func (f FallbackForwarder) ForwardLLM(
+	ctx context.Context,
+	input StartCompletionInput,
+	llmContexts []ReturnToLLMContext,
+	history []LLMMessage,
+) ([]LLMMessage, error) {
+	return f.forward(ctx, func(mf ModelForwarder, input StartCompletionInput) ([]LLMMessage, error) {
+		// the forwarder may append to the backing array of the history
+		return mf.Forwarder.ForwardLLM(ctx, input, llmContexts, append([]LLMMessage{}, history...))
+	}, input)
+}
+
+func (f FallbackForwarder) ForwardStep(ctx context.Context, history []LLMMessage) Step {
+	return f.currentForwarder().Forwarder.ForwardStep(ctx, history)
+}
+
+// forward sends the request to the current model, and falls back to the next models in order when it fails.
+func (f FallbackForwarder) forward(
+	ctx context.Context,
+	send func(mf ModelForwarder, input StartCompletionInput) ([]LLMMessage, error),
+	input StartCompletionInput,
+) ([]LLMMessage, error) {
+	for {
+		f.mu.Lock()
+		i := *f.current
+		f.mu.Unlock()
+
+		mf := f.forwarders[i]
+		modelInput := input
+		modelInput.Model = mf.Model
+
+		history, err := send(mf, modelInput)
+		if err == nil {
+			if mf.Model != input.Model && len(history) > 0 {
+				history[len(history)-1].Model = mf.Model
+			}
+			return history, nil
+		}
+		if ctx.Err() != nil || i+1 >= len(f.forwarders) || !isFallbackError(err) {
+			return nil, err
+		}
+
+		f.mu.Lock()
+		// other agents sharing the forwarder may have fallen back already
+		if *f.current == i {
+			*f.current = i + 1
+		}
+		f.mu.Unlock()
+		f.lo.Error("%s failed, falling back to %s: %s\n", mf.Model, f.forwarders[i+1].Model, err)
+	}
+}
+
+func (f FallbackForwarder) currentForwarder() ModelForwarder {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.forwarders[*f.current]
+}
+
+// isFallbackError reports whether the error is the rate limit, the server error, the overload
+// or the failure to reach the provider, which another model may not be affected by.
+func isFallbackError(err error) bool {
+	var status interface{ HTTPStatusCode() int }
+	if errors.As(err, &status) {
+		code := status.HTTPStatusCode()
+		return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	// the connection refused, the DNS failure, the TLS error, the reset connection and so on
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var retryable *util.RetryableError
+	return errors.As(err, &retryable)
+}
@@ -0,0 +1,124 @@
+package core_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"testing"
+
+	"github.com/clover0/issue-agent/core"
+	"github.com/clover0/issue-agent/test/assert"
+	"github.com/clover0/issue-agent/test/loggertest"
+	"github.com/clover0/issue-agent/util"
+)
+
+// modelForwarder fails or returns a message with the requested model.
+type modelForwarder struct {
+	err    error
+	models []string
+}
+
+func (f *modelForwarder) StartForward(input core.StartCompletionInput) ([]core.LLMMessage, error) {
+	return f.respond(input, nil)
+}
+
+func (f *modelForwarder) ForwardLLM(
+	_ context.Context, input core.StartCompletionInput, _ []core.ReturnToLLMContext, history []core.LLMMessage,
+) ([]core.LLMMessage, error) {
+	return f.respond(input, history)
+}
+
+func (f *modelForwarder) respond(input core.StartCompletionInput, history []core.LLMMessage) ([]core.LLMMessage, error) {
+	f.models = append(f.models, input.Model)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return append(history, core.LLMMessage{Role: core.LLMAssistant, RawContent: input.Model}), nil
+}
+
+func (f *modelForwarder) ForwardStep(_ context.Context, history []core.LLMMessage) core.Step {
+	return core.NewWaitingInstructionStep(history[len(history)-1].RawContent)
+}
+
+func TestFallbackForwarder(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		errs        []error
+		wantErr     bool
+		wantContent string
+		wantModel   string
+	}{
+		"primary model succeeds": {
+			errs:        []error{nil, nil},
+			wantContent: "primary",
+			wantModel:   "",
+		},
+		"falls back to the next model": {
+			errs:        []error{util.NewRetryableError(errors.New("overloaded"), 0), nil},
+			wantContent: "fallback",
+			wantModel:   "fallback",
+		},
+		"falls back on the server error": {
+			errs:        []error{fmt.Errorf("wrapped: %w", &util.StatusError{StatusCode: http.StatusInternalServerError}), nil},
+			wantContent: "fallback",
+			wantModel:   "fallback",
+		},
+		"falls back on the connection error": {
+			errs: []error{fmt.Errorf("failed to send request: %w", &url.Error{
+				Op: "Post", URL: "https://api.example.com", Err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED},
+			}), nil},
+			wantContent: "fallback",
+			wantModel:   "fallback",
+		},
+		"does not fall back on the canceled request": {
+			errs:    []error{&url.Error{Op: "Post", URL: "https://api.example.com", Err: context.Canceled}, nil},
+			wantErr: true,
+		},
+		"does not fall back on the invalid request": {
+			errs:    []error{&util.StatusError{StatusCode: http.StatusBadRequest}, nil},
+			wantErr: true,
+		},
+		"does not fall back on the unknown error": {
+			errs:    []error{errors.New("failed to unmarshal"), nil},
+			wantErr: true,
+		},
+		"all models fail": {
+			errs:    []error{&util.StatusError{StatusCode: http.StatusTooManyRequests}, &util.StatusError{StatusCode: http.StatusServiceUnavailable}},
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			primary := &modelForwarder{err: tt.errs[0]}
+			fallback := &modelForwarder{err: tt.errs[1]}
+			forwarder := core.NewFallbackForwarder(loggertest.NewTestLogger(), []core.ModelForwarder{
+				{Model: "primary", Forwarder: primary},
+				{Model: "fallback", Forwarder: fallback},
+			})
+			input := core.StartCompletionInput{Model: "primary"}
+
+			history, err := forwarder.StartForward(input)
+			if tt.wantErr {
+				assert.HasError(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, history[0].RawContent, tt.wantContent)
+			assert.Equal(t, history[0].Model, tt.wantModel)
+
+			// the following requests are forwarded to the same model
+			history, err = forwarder.ForwardLLM(t.Context(), input, nil, history)
+			assert.NoError(t, err)
+			assert.Equal(t, history[1].RawContent, tt.wantContent)
+			assert.Equal(t, forwarder.ForwardStep(t.Context(), history).LastOutput, tt.wantContent)
+		})
+	}
+}
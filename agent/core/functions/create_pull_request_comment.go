@@ -1,39 +1,28 @@
 package functions
 
+import "context"
+
 const FuncCreatePullRequestComment = "create_pull_request_comment"
 
-type CreatePullRequestCommentType func(input CreatePullRequestCommentInput) (CreateIssueCommentOutput, error)
+type CreatePullRequestCommentType func(ctx context.Context, input CreatePullRequestCommentInput) (CreateIssueCommentOutput, error)
 
 func InitCreatePullRequestCommentFunction(service GitHubService) Function {
-	f := Function{
-		Name:        FuncCreatePullRequestComment,
-		Description: "Create a comment on a GitHub pull request from `owner/repo` passed as CLI input.",
-		Func:        CreatePullRequestCommentCaller(service),
-		Parameters: map[string]any{
-			"type": "object",
-			"properties": map[string]any{
-				"pr_number": map[string]any{
-					"type":        "string",
-					"description": "GitHub Pull Request Number to create comment to",
-				},
-				"comment": map[string]any{
-					"type":        "string",
-					"description": "Comment by markdown on the pull request",
-				},
-			},
-			"required":             []string{"pr_number", "comment"},
-			"additionalProperties": false,
+	return NewFunction(
+		FuncCreatePullRequestComment,
+		"Create a comment on a GitHub pull request from 'owner/repo' passed as CLI input.",
+		func(ctx context.Context, input CreatePullRequestCommentInput) (string, error) {
+			out, err := CreatePullRequestCommentCaller(service)(ctx, input)
+			if err != nil {
+				return "", err
+			}
+			return out.ToLLMString(), nil
 		},
-	}
-
-	register(f)
-
-	return f
+	)
 }
 
 type CreatePullRequestCommentInput struct {
-	PRNumber string `json:"pr_number"`
-	Comment  string `json:"comment"`
+	PRNumber string `json:"pr_number" jsonschema:"required" description:"GitHub Pull Request Number to create comment to"`
+	Comment  string `json:"comment" jsonschema:"required" description:"Comment by markdown on the pull request"`
 }
 
 type CreateIssueCommentOutput struct{}
@@ -43,7 +32,7 @@ func (g CreateIssueCommentOutput) ToLLMString() string {
 }
 
 func CreatePullRequestCommentCaller(service GitHubService) CreatePullRequestCommentType {
-	return func(input CreatePullRequestCommentInput) (CreateIssueCommentOutput, error) {
-		return service.CreateIssueComment(input.PRNumber, input.Comment)
+	return func(ctx context.Context, input CreatePullRequestCommentInput) (CreateIssueCommentOutput, error) {
+		return service.CreateIssueComment(ctx, input.PRNumber, input.Comment)
 	}
 }
@@ -1,3 +1,7 @@
 package functions
 
 var GuardPathInner = guardPathInner
+
+func NewWalkerInDir(setting WalkerSetting, base string) Walker {
+	return Walker{setting: setting, base: base}
+}
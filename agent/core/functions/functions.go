@@ -1,14 +1,15 @@
 package functions
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"slices"
-	"strings"
 
-	"github.com/openai/openai-go"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/clover0/issue-agent/logger"
+	"github.com/clover0/issue-agent/telemetry"
 )
 
 func InitializeFunctions(
@@ -16,65 +17,56 @@ func InitializeFunctions(
 	submitFilesService SubmitFilesService,
 	submitRevisionService SubmitRevisionService,
 	allowFunctions []string,
+	setting Setting,
 ) {
-	if allowFunction(allowFunctions, FuncOpenFile) {
-		InitOpenFileFunction()
-	}
-	if allowFunction(allowFunctions, FuncListFiles) {
-		InitListFilesFunction()
-	}
-	if allowFunction(allowFunctions, FuncPutFile) {
-		InitPutFileFunction()
-	}
-	if allowFunction(allowFunctions, FuncModifyFile) {
-		InitModifyFileFunction()
-	}
-	if allowFunction(allowFunctions, FuncSubmitFiles) {
-		InitSubmitFilesGitHubFunction(submitFilesService)
-	}
-	if allowFunction(allowFunctions, FuncGetWebSearchResult) {
-		InitGetWebSearchResult()
-	}
-	if allowFunction(allowFunctions, FuncGetWebPageFromURL) {
-		InitFuncGetWebPageFromURLFunction()
-	}
-	if allowFunction(allowFunctions, FuncGetPullRequest) {
-		InitGetPullRequestFunction(repoService)
-	}
-	if allowFunction(allowFunctions, FuncSearchFiles) {
-		InitSearchFilesFunction()
-	}
-	if allowFunction(allowFunctions, FuncRemoveFile) {
-		InitRemoveFileFunction()
-	}
-	if allowFunction(allowFunctions, FuncSwitchBranch) {
-		InitSwitchBranchFunction()
-	}
-	if allowFunction(allowFunctions, FuncSubmitRevision) {
-		InitSubmitRevisionFunction(submitRevisionService)
-	}
-	if allowFunction(allowFunctions, FuncGetIssue) {
-		InitGetIssueFunction(repoService)
-	}
-	if allowFunction(allowFunctions, FuncCreatePullRequestComment) {
-		InitCreatePullRequestCommentFunction(repoService)
-	}
-	if allowFunction(allowFunctions, FuncCreatePullRequestReviewComment) {
-		InitCreatePullRequestReviewCommentFunction(repoService)
-	}
-	if allowFunction(allowFunctions, FuncGetRepositoryContent) {
-		InitGetRepositoryContentFunction(repoService)
-	}
-	if allowFunction(allowFunctions, FuncRequestReviewers) {
-		InitRequestReviewersFunction(repoService)
+	walker := NewWalker(setting.Walker)
+
+	// the functions are initialized in this order
+	initializers := []struct {
+		name string
+		init func() Function
+	}{
+		{FuncOpenFile, func() Function { return InitOpenFileFunction(setting.OpenFile) }},
+		{FuncListFiles, func() Function { return InitListFilesFunction(walker) }},
+		{FuncPutFile, InitPutFileFunction},
+		{FuncModifyFile, InitModifyFileFunction},
+		{FuncReplaceInFile, InitReplaceInFileFunction},
+		{FuncSubmitFiles, func() Function { return InitSubmitFilesGitHubFunction(submitFilesService) }},
+		{FuncGetWebSearchResult, InitGetWebSearchResult},
+		{FuncGetWebPageFromURL, InitFuncGetWebPageFromURLFunction},
+		{FuncGetPullRequest, func() Function { return InitGetPullRequestFunction(repoService) }},
+		{FuncSearchFiles, func() Function { return InitSearchFilesFunction(walker) }},
+		{FuncGrepFiles, func() Function { return InitGrepFilesFunction(walker) }},
+		{FuncRemoveFile, InitRemoveFileFunction},
+		{FuncSwitchBranch, InitSwitchBranchFunction},
+		{FuncSubmitRevision, func() Function { return InitSubmitRevisionFunction(submitRevisionService) }},
+		{FuncGetIssue, func() Function { return InitGetIssueFunction(repoService) }},
+		{FuncCreatePullRequestComment, func() Function { return InitCreatePullRequestCommentFunction(repoService) }},
+		{FuncCreatePullRequestReviewComment, func() Function { return InitCreatePullRequestReviewCommentFunction(repoService) }},
+		{FuncGetRepositoryContent, func() Function { return InitGetRepositoryContentFunction(repoService) }},
+		{FuncRequestReviewers, func() Function { return InitRequestReviewersFunction(repoService) }},
+		{FuncRunCommand, func() Function { return InitRunCommandFunction(setting.RunCommand) }},
+	}
+
+	for _, i := range initializers {
+		if allowFunction(allowFunctions, i.name) {
+			Register(i.init())
+		}
 	}
 }
 
+// Setting is the setting of functions given by the configuration.
+type Setting struct {
+	Walker     WalkerSetting
+	OpenFile   OpenFileSetting
+	RunCommand RunCommandSetting
+}
+
 // InitializeInvokeAgentFunction initializes the invoke agent function.
 // `invoke_agent` function requires the other functions. so it should be initialized after the other all functions.
 func InitializeInvokeAgentFunction(allowFunctions []string, agentCaller AgentInvokerIF) {
 	if allowFunction(allowFunctions, FuncInvokeAgent) {
-		InitInvokeAgentFunction(agentCaller)
+		Register(InitInvokeAgentFunction(agentCaller))
 	}
 }
 
@@ -88,26 +80,61 @@ func (f FuncName) String() string {
 	return string(f)
 }
 
+// Handler executes a function with the input unmarshalled from the arguments by LLM,
+// and returns the result passed to LLM.
+// The context is canceled when the run stops and carries the span of the function call.
+type Handler[I any] func(ctx context.Context, input I) (string, error)
+
 type Function struct {
 	Name        FuncName
 	Description string
-	Func        any
-	Parameters  map[string]any
+	Parameters  JSONSchema
+
+	// ReadOnly is true when the function has no side effects on the repository and the others,
+	// so that it can run concurrently with the other read-only functions.
+	ReadOnly bool
+
+	exec func(ctx context.Context, argsJson string) (string, error)
 }
 
-var functionsMap = map[string]Function{}
+// NewFunction creates a function executed by the handler.
+// The parameters are generated from the input struct I. See SchemaOf for the struct tags.
+func NewFunction[I any](name FuncName, description string, handler Handler[I]) Function {
+	return Function{
+		Name:        name,
+		Description: description,
+		Parameters:  SchemaOf[I](),
+		exec: func(ctx context.Context, argsJson string) (string, error) {
+			var input I
+			if err := json.Unmarshal([]byte(argsJson), &input); err != nil {
+				return "", fmt.Errorf("failed to unmarshal args: %w", err)
+			}
+			return handler(ctx, input)
+		},
+	}
+}
 
-func register(f Function) {
-	functionsMap[f.Name.String()] = f
+// NewReadOnlyFunction creates a function without side effects. See Function.ReadOnly.
+func NewReadOnlyFunction[I any](name FuncName, description string, handler Handler[I]) Function {
+	f := NewFunction(name, description, handler)
+	f.ReadOnly = true
+	return f
 }
 
-// TODO: no dependent on openai-go
-func (f Function) ToFunctionCalling() openai.FunctionDefinitionParam {
-	return openai.FunctionDefinitionParam{
-		Name:        f.Name.String(),
-		Description: openai.String(f.Name.String()),
-		Parameters:  openai.FunctionParameters(f.Parameters),
+// Exec executes the function with the JSON arguments by LLM.
+func (f Function) Exec(ctx context.Context, argsJson string) (string, error) {
+	if f.exec == nil {
+		return "", fmt.Errorf("%s has no handler", f.Name)
 	}
+	return f.exec(ctx, argsJson)
+}
+
+var functionsMap = map[string]Function{}
+
+// Register registers the function to be used by agents.
+// A function with the same name is replaced.
+func Register(f Function) {
+	functionsMap[f.Name.String()] = f
 }
 
 func FunctionByName(name string) (Function, error) {
@@ -135,222 +162,21 @@ func FunctionsMap() map[string]Function {
 	return functionsMap
 }
 
-func marshalFuncArgs(args string, input any) error {
-	return json.Unmarshal([]byte(args), &input)
-}
-
 const defaultSuccessReturning = "tool use succeeded."
 
-func ExecFunction(l logger.Logger, funcName FuncName, argsJson string) (string, error) {
-	// TODO: make large switch statement smaller
-	l.Info("functions: do %s\n", funcName)
-	switch funcName {
-	case FuncOpenFile:
-		input := OpenFileInput{}
-		if err := marshalFuncArgs(argsJson, &input); err != nil {
-			return "", fmt.Errorf("failed to unmarshal args: %w", err)
-		}
-		file, err := OpenFile(input)
-		if err != nil {
-			return "", err
-		}
-		return file.Content, nil
-
-	case FuncListFiles:
-		input := ListFilesInput{}
-		if err := marshalFuncArgs(argsJson, &input); err != nil {
-			return "", fmt.Errorf("failed to unmarshal args: %w", err)
-		}
-		files, err := ListFiles(input)
-		if err != nil {
-			return "", err
-		}
-		return strings.Join(files, "\n"), nil
+func ExecFunction(ctx context.Context, l logger.Logger, funcName FuncName, argsJson string) (result string, err error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "execute_tool "+funcName.String(),
+		trace.WithAttributes(telemetry.AttrToolName.String(funcName.String())))
+	defer func() {
+		span.SetAttributes(telemetry.AttrToolResultBytes.Int(len(result)))
+		telemetry.End(span, err)
+	}()
 
-	case FuncPutFile:
-		input := PutFileInput{}
-		if err := marshalFuncArgs(argsJson, &input); err != nil {
-			return "", fmt.Errorf("failed to unmarshal args: %w", err)
-		}
-		_, err := PutFile(input)
-		if err != nil {
-			return "", err
-		}
-		return defaultSuccessReturning, nil
-
-	case FuncModifyFile:
-		input := ModifyFileInput{}
-		if err := marshalFuncArgs(argsJson, &input); err != nil {
-			return "", fmt.Errorf("failed to unmarshal args: %w", err)
-		}
-		_, err := ModifyFile(input)
-		if err != nil {
-			return "", err
-		}
-		return defaultSuccessReturning, nil
-
-	case FuncSubmitFiles:
-		input := SubmitFilesInput{}
-		if err := marshalFuncArgs(argsJson, &input); err != nil {
-			return "", fmt.Errorf("failed to unmarshal args: %w", err)
-		}
-		out, err := functionsMap[FuncSubmitFiles].Func.(SubmitFilesType)(input)
-		if err != nil {
-			return "", err
-		}
-
-		return fmt.Sprintf("%s\n%s\n",
-			defaultSuccessReturning, out.Message), nil
-
-	case FuncGetWebSearchResult:
-		input := GetWebSearchResultInput{}
-		if err := marshalFuncArgs(argsJson, &input); err != nil {
-			return "", fmt.Errorf("failed to unmarshal args: %w", err)
-		}
-
-		r, err := GetWebSearchResult(input)
-		if err != nil {
-			return "", err
-		}
-		return r, nil
-
-	case FuncGetWebPageFromURL:
-		input := GetWebPageFromURLInput{}
-		if err := marshalFuncArgs(argsJson, &input); err != nil {
-			return "", fmt.Errorf("failed to unmarshal args: %w", err)
-		}
-
-		r, err := GetWebPageFromURL(input)
-		if err != nil {
-			return "", err
-		}
-		return r, nil
-
-	case FuncGetPullRequest:
-		input := GetPullRequestInput{}
-		if err := marshalFuncArgs(argsJson, &input); err != nil {
-			return "", fmt.Errorf("failed to unmarshal args: %w", err)
-		}
-		fn, ok := functionsMap[FuncGetPullRequest].Func.(GetPullRequestType)
-		if !ok {
-			return "", fmt.Errorf("cat not call %s function", FuncGetPullRequest)
-		}
-		r, err := fn(input)
-		if err != nil {
-			return "", err
-		}
-		return r.ToLLMString(), nil
-
-	case FuncSearchFiles:
-		input := SearchFilesInput{}
-		if err := marshalFuncArgs(argsJson, &input); err != nil {
-			return "", fmt.Errorf("failed to unmarshal args: %w", err)
-		}
-		r, err := SearchFiles(input)
-		if err != nil {
-			return "", err
-		}
-		return strings.Join(r, "\n"), nil
-
-	case FuncRemoveFile:
-		input := RemoveFileInput{}
-		if err := marshalFuncArgs(argsJson, &input); err != nil {
-			return "", fmt.Errorf("failed to unmarshal args: %w", err)
-		}
-		err := RemoveFile(input)
-		if err != nil {
-			return "", err
-		}
-		return defaultSuccessReturning, nil
-
-	case FuncSwitchBranch:
-		input := SwitchBranchInput{}
-		if err := marshalFuncArgs(argsJson, &input); err != nil {
-			return "", fmt.Errorf("failed to unmarshal args: %w", err)
-		}
-		r, err := SwitchBranch(input)
-		if err != nil {
-			return "", err
-		}
-		return fmt.Sprintf("%s\n%s\n", defaultSuccessReturning, r), nil
-
-	case FuncSubmitRevision:
-		input := SubmitRevisionInput{}
-		if err := marshalFuncArgs(argsJson, &input); err != nil {
-			return "", fmt.Errorf("failed to unmarshal args: %w", err)
-		}
-		out, err := functionsMap[FuncSubmitRevision].Func.(SubmitRevisionType)(input)
-		if err != nil {
-			return "", err
-		}
-		return out.Message, nil
-
-	case FuncGetIssue:
-		input := GetIssueInput{}
-		if err := marshalFuncArgs(argsJson, &input); err != nil {
-			return "", fmt.Errorf("failed to unmarshal args: %w", err)
-		}
-		out, err := functionsMap[FuncGetIssue].Func.(GetIssueType)(input)
-		if err != nil {
-			return "", err
-		}
-		return out.ToLLMString(), nil
-
-	case FuncCreatePullRequestComment:
-		input := CreatePullRequestCommentInput{}
-		if err := marshalFuncArgs(argsJson, &input); err != nil {
-			return "", fmt.Errorf("failed to unmarshal args: %w", err)
-		}
-		out, err := functionsMap[FuncCreatePullRequestComment].Func.(CreatePullRequestCommentType)(input)
-		if err != nil {
-			return "", err
-		}
-		return out.ToLLMString(), nil
-
-	case FuncCreatePullRequestReviewComment:
-		input := CreatePullRequestReviewCommentInput{}
-		if err := marshalFuncArgs(argsJson, &input); err != nil {
-			return "", fmt.Errorf("failed to unmarshal args: %w", err)
-		}
-		out, err := functionsMap[FuncCreatePullRequestReviewComment].Func.(CreatePullRequestReviewCommentType)(input)
-		if err != nil {
-			return "", err
-		}
-		return out.ToLLMString(), nil
-
-	case FuncGetRepositoryContent:
-		input := GetRepositoryContentInput{}
-		if err := marshalFuncArgs(argsJson, &input); err != nil {
-			return "", fmt.Errorf("failed to unmarshal args: %w", err)
-		}
-		out, err := functionsMap[FuncGetRepositoryContent].Func.(GetRepositoryContentType)(input)
-		if err != nil {
-			return "", err
-		}
-		return out.ToLLMString(), nil
-
-	case FuncInvokeAgent:
-		input := InvokeAgentInput{}
-		if err := marshalFuncArgs(argsJson, &input); err != nil {
-			return "", fmt.Errorf("failed to unmarshal args: %w", err)
-		}
-		out, err := functionsMap[FuncInvokeAgent].Func.(InvokeAgentType)(input)
-		if err != nil {
-			return "", err
-		}
-		return out.ToLLMString(), nil
-
-	case FuncRequestReviewers:
-		input := RequestReviewersInput{}
-		if err := marshalFuncArgs(argsJson, &input); err != nil {
-			return "", fmt.Errorf("failed to unmarshal args: %w", err)
-		}
-		out, err := functionsMap[FuncRequestReviewers].Func.(RequestReviewersType)(input)
-		if err != nil {
-			return "", err
-		}
-		return out.ToLLMString(), nil
+	l.Info("functions: do %s\n", funcName)
+	f, ok := functionsMap[funcName.String()]
+	if !ok {
+		return "", fmt.Errorf("function not found %s", funcName)
 	}
 
-	return "", fmt.Errorf("function not found %s", funcName)
+	return f.Exec(ctx, argsJson)
 }
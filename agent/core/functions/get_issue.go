@@ -1,41 +1,31 @@
 package functions
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 const FuncGetIssue = "get_issue"
 
-type GetIssueType func(input GetIssueInput) (GetIssueOutput, error)
+type GetIssueType func(ctx context.Context, input GetIssueInput) (GetIssueOutput, error)
 
 func InitGetIssueFunction(service GitHubService) Function {
-	f := Function{
-		Name:        FuncGetIssue,
-		Description: "Get a GitHub issue from organization(owner) passed as CLI input.",
-		Func:        GetIssueCaller(service),
-		Parameters: map[string]any{
-			"type": "object",
-			"properties": map[string]any{
-				"repository_name": map[string]any{
-					"type":        "string",
-					"description": "GitHub repository name to get the issue from. The `repo` part of the `owner/repo` format.",
-				},
-				"issue_number": map[string]any{
-					"type":        "string",
-					"description": "GitHub Issue Number to get",
-				},
-			},
-			"required":             []string{"repository_name", "issue_number"},
-			"additionalProperties": false,
+	return NewReadOnlyFunction(
+		FuncGetIssue,
+		"Get a GitHub issue from organization(owner) passed as CLI input.",
+		func(ctx context.Context, input GetIssueInput) (string, error) {
+			out, err := GetIssueCaller(service)(ctx, input)
+			if err != nil {
+				return "", err
+			}
+			return out.ToLLMString(), nil
 		},
-	}
-
-	register(f)
-
-	return f
+	)
 }
 
 type GetIssueInput struct {
-	RepositoryName string `json:"repository_name"`
-	IssueNumber    string `json:"issue_number"`
+	RepositoryName string `json:"repository_name" jsonschema:"required" description:"GitHub repository name to get the issue from. The 'repo' part of the 'owner/repo' format."`
+	IssueNumber    string `json:"issue_number" jsonschema:"required" description:"GitHub Issue Number to get"`
 }
 
 type GetIssueOutput struct {
@@ -52,7 +42,7 @@ func (g GetIssueOutput) ToLLMString() string {
 }
 
 func GetIssueCaller(service GitHubService) GetIssueType {
-	return func(input GetIssueInput) (GetIssueOutput, error) {
-		return service.GetIssue(input.RepositoryName, input.IssueNumber)
+	return func(ctx context.Context, input GetIssueInput) (GetIssueOutput, error) {
+		return service.GetIssue(ctx, input.RepositoryName, input.IssueNumber)
 	}
 }
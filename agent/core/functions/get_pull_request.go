@@ -2,39 +2,31 @@ package functions
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"text/template"
 )
 
 const FuncGetPullRequest = "get_pull_request"
 
-type GetPullRequestType func(input GetPullRequestInput) (GetPullRequestOutput, error)
+type GetPullRequestType func(ctx context.Context, input GetPullRequestInput) (GetPullRequestOutput, error)
 
 func InitGetPullRequestFunction(service GitHubService) Function {
-	f := Function{
-		Name:        FuncGetPullRequest,
-		Description: "Get a GitHub Pull Request",
-		Func:        GetPullRequestCaller(service),
-		Parameters: map[string]any{
-			"type": "object",
-			"properties": map[string]any{
-				"pr_number": map[string]any{
-					"type":        "string",
-					"description": "Pull Request Number to get",
-				},
-			},
-			"required":             []string{"pr_number"},
-			"additionalProperties": false,
+	return NewReadOnlyFunction(
+		FuncGetPullRequest,
+		"Get a GitHub Pull Request",
+		func(ctx context.Context, input GetPullRequestInput) (string, error) {
+			out, err := GetPullRequestCaller(service)(ctx, input)
+			if err != nil {
+				return "", err
+			}
+			return out.ToLLMString(), nil
 		},
-	}
-
-	register(f)
-
-	return f
+	)
 }
 
 type GetPullRequestInput struct {
-	PRNumber string `json:"pr_number"`
+	PRNumber string `json:"pr_number" jsonschema:"required" description:"Pull Request Number to get"`
 }
 
 type GetPullRequestOutput struct {
@@ -124,7 +116,7 @@ func (g GetPullRequestOutput) ToLLMString() string {
 }
 
 func GetPullRequestCaller(service GitHubService) GetPullRequestType {
-	return func(input GetPullRequestInput) (GetPullRequestOutput, error) {
-		return service.GetPullRequest(input.PRNumber)
+	return func(ctx context.Context, input GetPullRequestInput) (GetPullRequestOutput, error) {
+		return service.GetPullRequest(ctx, input.PRNumber)
 	}
 }
@@ -1,39 +1,28 @@
 package functions
 
+import "context"
+
 const FuncGetRepositoryContent = "get_repository_content"
 
-type GetRepositoryContentType func(input GetRepositoryContentInput) (GetRepositoryContentOutput, error)
+type GetRepositoryContentType func(ctx context.Context, input GetRepositoryContentInput) (GetRepositoryContentOutput, error)
 
 func InitGetRepositoryContentFunction(service GitHubService) Function {
-	f := Function{
-		Name:        FuncGetRepositoryContent,
-		Description: "Get contents of a file or directory in a GitHub repository.",
-		Func:        GetRepositoryContentCaller(service),
-		Parameters: map[string]any{
-			"type": "object",
-			"properties": map[string]any{
-				"repository_name": map[string]any{
-					"type":        "string",
-					"description": "GitHub repository name to get the content. This is `repo` part of the `owner/repo` format.",
-				},
-				"path": map[string]any{
-					"type":        "string",
-					"description": "File path from repository root.",
-				},
-			},
-			"required":             []string{"repository_name", "path"},
-			"additionalProperties": false,
+	return NewReadOnlyFunction(
+		FuncGetRepositoryContent,
+		"Get contents of a file or directory in a GitHub repository.",
+		func(ctx context.Context, input GetRepositoryContentInput) (string, error) {
+			out, err := GetRepositoryContentCaller(service)(ctx, input)
+			if err != nil {
+				return "", err
+			}
+			return out.ToLLMString(), nil
 		},
-	}
-
-	register(f)
-
-	return f
+	)
 }
 
 type GetRepositoryContentInput struct {
-	RepositoryName string `json:"repository_name"`
-	Path           string `json:"path"`
+	RepositoryName string `json:"repository_name" jsonschema:"required" description:"GitHub repository name to get the content. This is 'repo' part of the 'owner/repo' format."`
+	Path           string `json:"path" jsonschema:"required" description:"File path from repository root."`
 }
 
 type GetRepositoryContentOutput struct {
@@ -45,7 +34,7 @@ func (g GetRepositoryContentOutput) ToLLMString() string {
 }
 
 func GetRepositoryContentCaller(service GitHubService) GetRepositoryContentType {
-	return func(input GetRepositoryContentInput) (GetRepositoryContentOutput, error) {
-		return service.GetRepositoryContent(input)
+	return func(ctx context.Context, input GetRepositoryContentInput) (GetRepositoryContentOutput, error) {
+		return service.GetRepositoryContent(ctx, input)
 	}
 }
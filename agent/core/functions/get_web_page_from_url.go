@@ -1,6 +1,7 @@
 package functions
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -12,39 +13,28 @@ import (
 const FuncGetWebPageFromURL = "get_web_page_from_url"
 
 func InitFuncGetWebPageFromURLFunction() Function {
-	f := Function{
-		Name:        FuncGetWebPageFromURL,
-		Description: "Get the web page from the URL",
-		Func:        GetWebPageFromURL,
-		Parameters: map[string]any{
-			"type": "object",
-			"properties": map[string]any{
-				"url": map[string]any{
-					"type":        "string",
-					"description": fmt.Sprintf("The URL to get the Web page. More than %d characters are cut off", maxTextLength),
-				},
-			},
-			"required":             []string{"url"},
-			"additionalProperties": false,
-		},
-	}
-
-	register(f)
+	f := NewReadOnlyFunction(
+		FuncGetWebPageFromURL,
+		"Get the web page from the URL",
+		GetWebPageFromURL,
+	)
+	f.Parameters.SetPropertyDescription("url",
+		fmt.Sprintf("The URL to get the Web page. More than %d characters are cut off", maxTextLength))
 
 	return f
 }
 
 type GetWebPageFromURLInput struct {
-	URL string
+	URL string `json:"url" jsonschema:"required"`
 }
 
-func GetWebPageFromURL(input GetWebPageFromURLInput) (string, error) {
+func GetWebPageFromURL(ctx context.Context, input GetWebPageFromURLInput) (string, error) {
 	u, err := url.Parse(input.URL)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse URL: %w", err)
 	}
 
-	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
@@ -2,6 +2,7 @@ package functions
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -15,34 +16,19 @@ const FuncGetWebSearchResult = "get_web_search_result"
 const ddgBaseURL = "https://html.duckduckgo.com/html"
 
 func InitGetWebSearchResult() Function {
-	f := Function{
-		Name: FuncGetWebSearchResult,
-		Description: strings.ReplaceAll(`Get a list of results from an Internet search conducted with keywords.
+	return NewReadOnlyFunction(
+		FuncGetWebSearchResult,
+		strings.ReplaceAll(`Get a list of results from an Internet search conducted with keywords.
  You should get the page information from the url of the result next.`, "\n", ""),
-		Func: GetWebSearchResult,
-		Parameters: map[string]any{
-			"type": "object",
-			"properties": map[string]any{
-				"keyword": map[string]any{
-					"type":        "string",
-					"description": "Keyword to search for on the Internet",
-				},
-			},
-			"required":             []string{"keyword"},
-			"additionalProperties": false,
-		},
-	}
-
-	register(f)
-
-	return f
+		GetWebSearchResult,
+	)
 }
 
 type GetWebSearchResultInput struct {
-	Keyword string
+	Keyword string `json:"keyword" jsonschema:"required" description:"Keyword to search for on the Internet"`
 }
 
-func GetWebSearchResult(input GetWebSearchResultInput) (_ string, err error) {
+func GetWebSearchResult(ctx context.Context, input GetWebSearchResultInput) (_ string, err error) {
 	u, err := url.Parse(ddgBaseURL)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse URL: %w", err)
@@ -51,7 +37,7 @@ func GetWebSearchResult(input GetWebSearchResultInput) (_ string, err error) {
 	param := url.Values{}
 	param.Set("q", input.Keyword)
 	payload := bytes.NewBufferString(param.Encode())
-	req, err := http.NewRequest(http.MethodPost, u.String(), payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), payload)
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
@@ -1,11 +1,13 @@
 package functions
 
+import "context"
+
 type GitHubService interface {
-	GetIssue(repository string, prNumber string) (GetIssueOutput, error)
-	GetPullRequest(prNumber string) (GetPullRequestOutput, error)
-	GetRepositoryContent(input GetRepositoryContentInput) (GetRepositoryContentOutput, error)
+	GetIssue(ctx context.Context, repository string, prNumber string) (GetIssueOutput, error)
+	GetPullRequest(ctx context.Context, prNumber string) (GetPullRequestOutput, error)
+	GetRepositoryContent(ctx context.Context, input GetRepositoryContentInput) (GetRepositoryContentOutput, error)
 
-	CreateIssueComment(issueNumber string, comment string) (CreateIssueCommentOutput, error)
-	CreateReviewCommentOne(input CreatePullRequestReviewCommentInput) (CreatePullRequestReviewCommentOutput, error)
-	RequestReviewers(prNumber int, reviewers []string, teamReviewers []string) (RequestReviewersOutput, error)
+	CreateIssueComment(ctx context.Context, issueNumber string, comment string) (CreateIssueCommentOutput, error)
+	CreateReviewCommentOne(ctx context.Context, input CreatePullRequestReviewCommentInput) (CreatePullRequestReviewCommentOutput, error)
+	RequestReviewers(ctx context.Context, prNumber int, reviewers []string, teamReviewers []string) (RequestReviewersOutput, error)
 }
@@ -0,0 +1,119 @@
+package functions
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+const (
+	gitignoreFile   = ".gitignore"
+	infoExcludeFile = ".git/info/exclude"
+)
+
+// ignoreMatcher matches paths with the ignore patterns of the repository.
+// Patterns are relative to the base directory, which is the repository root.
+// .gitignore files in subdirectories are loaded while walking into them.
+type ignoreMatcher struct {
+	base     string
+	patterns []gitignore.Pattern
+	loaded   map[string]bool
+}
+
+// newIgnoreMatcher creates the matcher with .git/info/exclude and the extra patterns in the base directory.
+// The extra patterns have the lowest priority as well as .git/info/exclude.
+func newIgnoreMatcher(base string, extraPatterns []string) (*ignoreMatcher, error) {
+	m := &ignoreMatcher{
+		base:   base,
+		loaded: map[string]bool{},
+	}
+
+	for _, p := range extraPatterns {
+		m.patterns = append(m.patterns, gitignore.ParsePattern(p, nil))
+	}
+
+	patterns, err := readIgnorePatterns(filepath.Join(base, infoExcludeFile), nil)
+	if err != nil {
+		return nil, err
+	}
+	m.patterns = append(m.patterns, patterns...)
+
+	return m, nil
+}
+
+// loadParents loads .gitignore files from the base directory to the directory.
+func (m *ignoreMatcher) loadParents(dir string) error {
+	parts := m.split(dir)
+	for i := 0; i <= len(parts); i++ {
+		if err := m.loadDir(filepath.Join(append([]string{m.base}, parts[:i]...)...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadDir loads the .gitignore file in the directory.
+func (m *ignoreMatcher) loadDir(dir string) error {
+	dir = filepath.Clean(dir)
+	if m.loaded[dir] {
+		return nil
+	}
+	m.loaded[dir] = true
+
+	patterns, err := readIgnorePatterns(filepath.Join(dir, gitignoreFile), m.split(dir))
+	if err != nil {
+		return err
+	}
+	m.patterns = append(m.patterns, patterns...)
+
+	return nil
+}
+
+func (m *ignoreMatcher) match(path string, isDir bool) bool {
+	parts := m.split(path)
+	if len(parts) == 0 {
+		return false
+	}
+	return gitignore.NewMatcher(m.patterns).Match(parts, isDir)
+}
+
+// split splits the path relative to the base directory into the elements.
+func (m *ignoreMatcher) split(path string) []string {
+	rel, err := filepath.Rel(m.base, path)
+	if err != nil {
+		return nil
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == "." || strings.HasPrefix(rel, "../") {
+		return nil
+	}
+	return strings.Split(rel, "/")
+}
+
+// readIgnorePatterns reads the patterns in the ignore file.
+// The domain is the directory the patterns are relative to.
+func readIgnorePatterns(path string, domain []string) ([]gitignore.Pattern, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []gitignore.Pattern
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
+			continue
+		}
+		patterns = append(patterns, gitignore.ParsePattern(line, domain))
+	}
+
+	return patterns, scanner.Err()
+}
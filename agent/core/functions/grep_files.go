@@ -0,0 +1,301 @@
+package functions
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const FuncGrepFiles = "grep_files"
+
+// keep in sync with the jsonschema tags of GrepFilesInput
+const (
+	defaultGrepMaxResults = 100
+	maxGrepMaxResults     = 500
+	maxGrepContextLines   = 10
+)
+
+type GrepFilesType func(input GrepFilesInput) (GrepFilesOutput, error)
+
+func InitGrepFilesFunction(walker Walker) Function {
+	return NewReadOnlyFunction(
+		FuncGrepFiles,
+		"Search file contents with a regular expression recursively like grep command. "+
+			"Returns matched lines with the file path and line number, and the lines around them. "+
+			"Files ignored by .gitignore are not searched.",
+		func(_ context.Context, input GrepFilesInput) (string, error) {
+			out, err := GrepFilesCaller(walker)(input)
+			if err != nil {
+				return "", err
+			}
+			return out.ToLLMString(), nil
+		},
+	)
+}
+
+type GrepFilesInput struct {
+	Pattern         string   `json:"pattern" jsonschema:"required" description:"The regular expression to search for. The syntax is RE2 like Go regexp package."`
+	Path            string   `json:"path" description:"The directory or file path to search. Default is the repository root."`
+	Include         []string `json:"include" description:"Glob patterns of files to search, such as '*.go' or 'src/**/*.ts'. Default is all files."`
+	Exclude         []string `json:"exclude" description:"Glob patterns of files or directories not to search, such as '*_test.go' or 'testdata'."`
+	CaseInsensitive bool     `json:"case_insensitive" jsonschema:"default=false" description:"If true, search case-insensitively."`
+	ContextLines    int      `json:"context_lines" jsonschema:"default=0,minimum=0,maximum=10" description:"The number of lines to show before and after each matched line."`
+	MaxResults      int      `json:"max_results" jsonschema:"default=100,minimum=1,maximum=500" description:"The maximum number of matched lines to return."`
+}
+
+type GrepLine struct {
+	Number  int
+	Text    string
+	Matched bool
+}
+
+// GrepMatch is a group of lines in a file containing matched lines and their context.
+type GrepMatch struct {
+	Path  string
+	Lines []GrepLine
+}
+
+type GrepFilesOutput struct {
+	Matches    []GrepMatch
+	Count      int
+	MaxResults int
+	Limited    bool
+}
+
+// ToLLMString formats like grep command.
+// Matched lines are `path:number:text` and context lines are `path-number-text`.
+func (g GrepFilesOutput) ToLLMString() string {
+	if g.Count == 0 {
+		return "no match found"
+	}
+
+	var groups []string
+	for _, m := range g.Matches {
+		var b strings.Builder
+		for _, l := range m.Lines {
+			sep := "-"
+			if l.Matched {
+				sep = ":"
+			}
+			b.WriteString(fmt.Sprintf("%s%s%d%s%s\n", m.Path, sep, l.Number, sep, l.Text))
+		}
+		groups = append(groups, b.String())
+	}
+
+	s := strings.Join(groups, "--\n")
+	if g.Limited {
+		s += fmt.Sprintf("(results are limited to %d matched lines. narrow down the search with path, include or pattern)\n", g.MaxResults)
+	}
+	return s
+}
+
+var errGrepLimited = errors.New("grep results limited")
+
+func GrepFilesCaller(walker Walker) GrepFilesType {
+	return func(input GrepFilesInput) (GrepFilesOutput, error) {
+		return GrepFiles(walker, input)
+	}
+}
+
+func GrepFiles(walker Walker, input GrepFilesInput) (GrepFilesOutput, error) {
+	if input.Path == "" {
+		input.Path = "."
+	}
+	if err := guardPath(input.Path); err != nil {
+		return GrepFilesOutput{}, err
+	}
+
+	pattern := input.Pattern
+	if input.CaseInsensitive {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return GrepFilesOutput{}, fmt.Errorf("invalid pattern %s: %w", input.Pattern, err)
+	}
+
+	includes, err := compileGlobs(input.Include)
+	if err != nil {
+		return GrepFilesOutput{}, err
+	}
+	excludes, err := compileGlobs(input.Exclude)
+	if err != nil {
+		return GrepFilesOutput{}, err
+	}
+
+	out := GrepFilesOutput{MaxResults: input.MaxResults}
+	if out.MaxResults <= 0 {
+		out.MaxResults = defaultGrepMaxResults
+	}
+	out.MaxResults = min(out.MaxResults, maxGrepMaxResults)
+	contextLines := min(max(input.ContextLines, 0), maxGrepContextLines)
+
+	root := filepath.Clean(input.Path)
+	err = walker.Walk(root, func(path string, d fs.DirEntry) error {
+		if d.IsDir() {
+			if path != root && matchGlobs(excludes, path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !d.Type().IsRegular() || matchGlobs(excludes, path) {
+			return nil
+		}
+		if len(includes) > 0 && !matchGlobs(includes, path) {
+			return nil
+		}
+
+		binary, err := IsBinaryFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to open file: %w", err)
+		}
+		if binary {
+			return nil
+		}
+
+		matches, count, err := grepFile(path, re, contextLines, out.MaxResults-out.Count)
+		if err != nil {
+			return err
+		}
+		out.Matches = append(out.Matches, matches...)
+		out.Count += count
+		if out.Count >= out.MaxResults {
+			return errGrepLimited
+		}
+
+		return nil
+	})
+	if errors.Is(err, errGrepLimited) {
+		out.Limited = true
+		err = nil
+	}
+	if err != nil {
+		return GrepFilesOutput{}, fmt.Errorf("failed to walk directory: %w", err)
+	}
+
+	return out, nil
+}
+
+// grepFile searches the file and groups the matched lines overlapping with their context.
+func grepFile(path string, re *regexp.Regexp, contextLines int, limit int) ([]GrepMatch, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		// too long lines are in minified or generated files, which are not worth searching
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, 0, nil
+		}
+		return nil, 0, fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+
+	var matched []int
+	for i, line := range lines {
+		if len(matched) >= limit {
+			break
+		}
+		if re.MatchString(line) {
+			matched = append(matched, i)
+		}
+	}
+
+	var groups []GrepMatch
+	last := -1
+	for _, i := range matched {
+		start := max(i-contextLines, last+1)
+		end := min(i+contextLines, len(lines)-1)
+		if len(groups) == 0 || start > last+1 {
+			groups = append(groups, GrepMatch{Path: filepath.Clean(path)})
+		}
+		g := &groups[len(groups)-1]
+		for n := start; n <= end; n++ {
+			g.Lines = append(g.Lines, GrepLine{Number: n + 1, Text: lines[n], Matched: re.MatchString(lines[n])})
+		}
+		last = end
+	}
+
+	return groups, len(matched), nil
+}
+
+func compileGlobs(globs []string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	for _, g := range globs {
+		re, err := globToRegexp(g)
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob %s: %w", g, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// matchGlobs matches the path with the globs.
+// A glob without '/' matches the base name, otherwise it matches the path from the repository root.
+func matchGlobs(globs []*regexp.Regexp, path string) bool {
+	slashed := filepath.ToSlash(filepath.Clean(path))
+	base := filepath.Base(path)
+	for _, g := range globs {
+		if g.MatchString(slashed) || g.MatchString(base) {
+			return true
+		}
+	}
+	return false
+}
+
+// globToRegexp converts the glob to the regular expression.
+// `**` matches any directories, `*` and `?` do not match '/'.
+func globToRegexp(glob string) (*regexp.Regexp, error) {
+	glob = strings.TrimPrefix(filepath.ToSlash(glob), "./")
+
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case c == '*' && i+1 < len(glob) && glob[i+1] == '*':
+			i++
+			if i+1 < len(glob) && glob[i+1] == '/' {
+				i++
+				b.WriteString("(?:.*/)?")
+			} else {
+				b.WriteString(".*")
+			}
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed '['")
+			}
+			class := glob[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+
+	return regexp.Compile(b.String())
+}
@@ -0,0 +1,90 @@
+package functions_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/clover0/issue-agent/core/functions"
+	"github.com/clover0/issue-agent/test/assert"
+)
+
+func TestGrepFiles(t *testing.T) {
+	t.Parallel()
+
+	files := map[string]string{
+		".gitignore":       "ignored/\n*.log\n",
+		"main.go":          "package main\n\nfunc main() {\n\tHello()\n}\n",
+		"hello.go":         "package main\n\nfunc Hello() {\n\tprintln(\"hello\")\n}\n",
+		"hello_test.go":    "package main\n\nfunc TestHello() {\n\tHello()\n}\n",
+		"sub/.gitignore":   "generated.go\n",
+		"sub/generated.go": "package sub\n\nfunc Hello() {}\n",
+		"sub/sub.go":       "package sub\n\n// hello from sub\n",
+		"ignored/a.go":     "func Hello() {}\n",
+		"debug.log":        "Hello\n",
+	}
+
+	tests := map[string]struct {
+		input functions.GrepFilesInput
+		want  string
+	}{
+		"regex with gitignore": {
+			input: functions.GrepFilesInput{Pattern: `func \w+\(`},
+			want: "{dir}/hello.go:3:func Hello() {\n--\n" +
+				"{dir}/hello_test.go:3:func TestHello() {\n--\n" +
+				"{dir}/main.go:3:func main() {\n",
+		},
+		"include and exclude globs": {
+			input: functions.GrepFilesInput{Pattern: `Hello\(\)`, Include: []string{"*.go"}, Exclude: []string{"*_test.go"}},
+			want: "{dir}/hello.go:3:func Hello() {\n--\n" +
+				"{dir}/main.go:4:\tHello()\n",
+		},
+		"include glob with directory": {
+			input: functions.GrepFilesInput{Pattern: `hello`, Include: []string{"**/sub/*.go"}},
+			want:  "{dir}/sub/sub.go:3:// hello from sub\n",
+		},
+		"case insensitive": {
+			input: functions.GrepFilesInput{Pattern: `HELLO\(`, CaseInsensitive: true, Include: []string{"hello.go"}},
+			want:  "{dir}/hello.go:3:func Hello() {\n",
+		},
+		"context lines": {
+			input: functions.GrepFilesInput{Pattern: `println`, ContextLines: 1},
+			want:  "{dir}/hello.go-3-func Hello() {\n{dir}/hello.go:4:\tprintln(\"hello\")\n{dir}/hello.go-5-}\n",
+		},
+		"max results": {
+			input: functions.GrepFilesInput{Pattern: `^package`, MaxResults: 2},
+			want: "{dir}/hello.go:1:package main\n--\n{dir}/hello_test.go:1:package main\n" +
+				"(results are limited to 2 matched lines. narrow down the search with path, include or pattern)\n",
+		},
+		"no match": {
+			input: functions.GrepFilesInput{Pattern: `not_found_keyword`},
+			want:  "no match found",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			for name, content := range files {
+				path := filepath.Join(dir, name)
+				assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+				assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+			}
+
+			tt.input.Path = dir
+			got, err := functions.GrepFiles(functions.NewWalker(functions.WalkerSetting{}), tt.input)
+			assert.NoError(t, err)
+			assert.Equal(t, got.ToLLMString(), strings.ReplaceAll(tt.want, "{dir}", dir))
+		})
+	}
+}
+
+func TestGrepFiles_InvalidPattern(t *testing.T) {
+	t.Parallel()
+
+	_, err := functions.GrepFiles(functions.NewWalker(functions.WalkerSetting{}), functions.GrepFilesInput{Pattern: `(`})
+	assert.HasError(t, err)
+}
@@ -1,6 +1,7 @@
 package functions
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,43 +10,35 @@ import (
 
 const FuncListFiles = "list_files"
 
-func InitListFilesFunction() Function {
-	f := Function{
-		Name: FuncListFiles,
-		Description: strings.ReplaceAll(`List the files within the direc tory like Unix ls command.
+type ListFilesType func(input ListFilesInput) ([]string, error)
+
+func InitListFilesFunction(walker Walker) Function {
+	return NewReadOnlyFunction(
+		FuncListFiles,
+		strings.ReplaceAll(`List the files within the direc tory like Unix ls command.
 Each line contains the file mode, byte size, and name. If you want to list subdirectories recursively, use the depth option.`, "\n", ""),
-		Func: ListFiles,
-		Parameters: map[string]any{
-			"type": "object",
-			"properties": map[string]any{
-				"path": map[string]any{
-					"type":        "string",
-					"description": "The valid path to list within its directory",
-				},
-				"depth": map[string]any{
-					"type":        "number",
-					"description": "The depth of the directory to list subdirectory recursively. Default is 2",
-					"minimum":     1,
-					"default":     2,
-					"maximum":     3,
-				},
-			},
-			"required":             []string{"path"},
-			"additionalProperties": false,
+		func(_ context.Context, input ListFilesInput) (string, error) {
+			files, err := ListFilesCaller(walker)(input)
+			if err != nil {
+				return "", err
+			}
+			return strings.Join(files, "\n"), nil
 		},
-	}
-
-	register(f)
-
-	return f
+	)
 }
 
 type ListFilesInput struct {
-	Path  string
-	Depth int
+	Path  string `json:"path" jsonschema:"required" description:"The valid path to list within its directory"`
+	Depth int    `json:"depth" jsonschema:"minimum=1,default=2,maximum=3" description:"The depth of the directory to list subdirectory recursively. Default is 2"`
 }
 
-func ListFiles(input ListFilesInput) ([]string, error) {
+func ListFilesCaller(walker Walker) ListFilesType {
+	return func(input ListFilesInput) ([]string, error) {
+		return ListFiles(walker, input)
+	}
+}
+
+func ListFiles(walker Walker, input ListFilesInput) ([]string, error) {
 	if err := guardPath(input.Path); err != nil {
 		return nil, err
 	}
@@ -62,43 +55,36 @@ func ListFiles(input ListFilesInput) ([]string, error) {
 		input.Depth = 3
 	}
 
-	return listFilesRecursive(input.Path, 1, input.Depth, "")
-}
-
-func listFilesRecursive(path string, currentDepth, maxDepth int, prefix string) ([]string, error) {
-	entries, err := os.ReadDir(path)
-	if err != nil {
-		return nil, fmt.Errorf("can't read directory at %s: %w", path, err)
-	}
-
+	root := filepath.Clean(input.Path)
 	var files []string
-	for _, entry := range entries {
+	err := walker.Walk(root, func(path string, entry os.DirEntry) error {
+		if path == root {
+			return nil
+		}
+
 		info, err := entry.Info()
 		if err != nil {
-			return nil, fmt.Errorf("get file info error: %w", err)
+			return fmt.Errorf("get file info error: %w", err)
 		}
 
-		fullName := entry.Name()
-		if prefix != "" {
-			fullName = prefix + "/" + entry.Name()
+		fullName, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
 		}
+		fullName = filepath.ToSlash(fullName)
 
 		files = append(files,
 			fmt.Sprintf("%s %d %s", info.Mode(), info.Size(), fullName),
 		)
 
-		if entry.IsDir() && currentDepth < maxDepth {
-			subFiles, err := listFilesRecursive(
-				filepath.Join(path, entry.Name()),
-				currentDepth+1,
-				maxDepth,
-				fullName,
-			)
-			if err != nil {
-				return nil, err
-			}
-			files = append(files, subFiles...)
+		if entry.IsDir() && strings.Count(fullName, "/")+1 >= input.Depth {
+			return filepath.SkipDir
 		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't read directory at %s: %w", input.Path, err)
 	}
 
 	return files, nil
@@ -1,6 +1,7 @@
 package functions
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
@@ -11,36 +12,22 @@ import (
 const FuncModifyFile = "modify_file"
 
 func InitModifyFileFunction() Function {
-	f := Function{
-		Name: FuncModifyFile,
-		Description: strings.ReplaceAll(`Modify the file at path with the contents of content_text.
+	return NewFunction(
+		FuncModifyFile,
+		strings.ReplaceAll(`Modify the file at path with the contents of content_text.
  Modified file must be full file content including modified content`, "\n", ""),
-		Func: ModifyFile,
-		Parameters: map[string]any{
-			"type": "object",
-			"properties": map[string]any{
-				"path": map[string]any{
-					"type":        "string",
-					"description": "Path of the file to be modified",
-				},
-				"content_text": map[string]any{
-					"type":        "string",
-					"description": "The new content of the file",
-				},
-			},
-			"required":             []string{"path", "content_text"},
-			"additionalProperties": false,
+		func(_ context.Context, input ModifyFileInput) (string, error) {
+			if _, err := ModifyFile(input); err != nil {
+				return "", err
+			}
+			return defaultSuccessReturning, nil
 		},
-	}
-
-	register(f)
-
-	return f
+	)
 }
 
 type ModifyFileInput struct {
-	Path        string `json:"path"`
-	ContentText string `json:"content_text"`
+	Path        string `json:"path" jsonschema:"required" description:"Path of the file to be modified"`
+	ContentText string `json:"content_text" jsonschema:"required" description:"The new content of the file"`
 }
 
 func ModifyFile(input ModifyFileInput) (store.File, error) {
@@ -1,59 +1,292 @@
 package functions
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"os"
+	"strings"
+	"unicode/utf8"
 
+	"github.com/clover0/issue-agent/config"
 	"github.com/clover0/issue-agent/core/store"
 )
 
 const FuncOpenFile = "open_file"
 
-func InitOpenFileFunction() Function {
-	f := Function{
-		Name:        FuncOpenFile,
-		Description: "Open the file full content",
-		Func:        OpenFile,
-		Parameters: map[string]any{
-			"type": "object",
-			"properties": map[string]any{
-				"path": map[string]any{
-					"type":        "string",
-					"description": "The path of the file to open",
-				},
-			},
-			"required":             []string{"path"},
-			"additionalProperties": false,
+type OpenFileType func(input OpenFileInput) (OpenFileOutput, error)
+
+// OpenFileSetting limits the size of the content returned to the agent.
+type OpenFileSetting struct {
+	MaxBytes int
+}
+
+func InitOpenFileFunction(setting OpenFileSetting) Function {
+	return NewReadOnlyFunction(
+		FuncOpenFile,
+		"Open the file content. "+
+			"When the file is too large, the number of lines is returned instead, so open the file in ranges of lines.",
+		func(_ context.Context, input OpenFileInput) (string, error) {
+			out, err := OpenFileCaller(setting)(input)
+			if err != nil {
+				return "", err
+			}
+			return out.ToLLMString(), nil
 		},
+	)
+}
+
+type OpenFileInput struct {
+	Path            string `json:"path" jsonschema:"required" description:"The path of the file to open"`
+	StartLine       int    `json:"start_line" description:"The first line number to open, starting from 1. Default is the first line."`
+	EndLine         int    `json:"end_line" description:"The last line number to open, inclusive. Default is the last line."`
+	WithLineNumbers bool   `json:"with_line_numbers" jsonschema:"default=false" description:"If true, each line is prefixed with its line number. The prefixes are not part of the content."`
+}
+
+type OpenFileOutput struct {
+	File            store.File
+	Size            int64
+	TotalLines      int
+	StartLine       int
+	EndLine         int
+	MaxBytes        int
+	TooLarge        bool
+	LineTruncated   bool
+	Ranged          bool
+	WithLineNumbers bool
+}
+
+func (o OpenFileOutput) ToLLMString() string {
+	if o.TooLarge {
+		return fmt.Sprintf("The file %s is too large to open at once: %d bytes, %d lines. The limit is %d bytes.\n"+
+			"Open the file in ranges of lines with start_line and end_line.\n",
+			o.File.Path, o.Size, o.TotalLines, o.MaxBytes)
 	}
 
-	register(f)
+	content := o.File.Content
+	if o.WithLineNumbers {
+		content = withLineNumbers(content, o.StartLine)
+	}
+	if o.LineTruncated {
+		content += fmt.Sprintf("(line %d is cut off at %d bytes)\n", o.EndLine, o.MaxBytes)
+	}
+	if !o.Ranged && !o.LineTruncated {
+		return content
+	}
 
-	return f
+	s := fmt.Sprintf("# %s (lines %d-%d of %d lines)\n", o.File.Path, o.StartLine, o.EndLine, o.TotalLines)
+	if o.EndLine < o.TotalLines {
+		s += fmt.Sprintf("(continue from start_line %d to read the rest)\n", o.EndLine+1)
+	}
+	return s + content
 }
 
-type OpenFileInput struct {
-	Path string
+func OpenFileCaller(setting OpenFileSetting) OpenFileType {
+	return func(input OpenFileInput) (OpenFileOutput, error) {
+		return OpenFileWithLimit(setting, input)
+	}
 }
 
-func OpenFile(input OpenFileInput) (store.File, error) {
+// OpenFileWithLimit opens the file within the size limit.
+// A whole file over the limit is not read, and the range is shortened to the lines within the limit.
+func OpenFileWithLimit(setting OpenFileSetting, input OpenFileInput) (OpenFileOutput, error) {
 	if err := guardPath(input.Path); err != nil {
-		return store.File{}, err
+		return OpenFileOutput{}, err
+	}
+
+	maxBytes := setting.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = config.DefaultOpenFileMaxBytes
+	}
+
+	info, err := os.Stat(input.Path)
+	if err != nil {
+		return OpenFileOutput{}, err
+	}
+
+	ranged := input.StartLine > 0 || input.EndLine > 0
+	if !ranged && info.Size() > int64(maxBytes) {
+		lines, err := countLines(input.Path)
+		if err != nil {
+			return OpenFileOutput{}, err
+		}
+		return OpenFileOutput{
+			File:       store.File{Path: input.Path},
+			Size:       info.Size(),
+			TotalLines: lines,
+			MaxBytes:   maxBytes,
+			TooLarge:   true,
+		}, nil
 	}
 
-	file, err := os.Open(input.Path)
+	r, err := readLines(input.Path, input.StartLine, input.EndLine, maxBytes)
 	if err != nil {
+		return OpenFileOutput{}, err
+	}
+
+	return OpenFileOutput{
+		File:            store.File{Path: input.Path, Content: r.content},
+		Size:            info.Size(),
+		TotalLines:      r.totalLines,
+		StartLine:       r.startLine,
+		EndLine:         r.endLine,
+		MaxBytes:        maxBytes,
+		LineTruncated:   r.lineTruncated,
+		Ranged:          ranged,
+		WithLineNumbers: input.WithLineNumbers,
+	}, nil
+}
+
+// OpenFile opens the file content in the range of lines without the size limit.
+func OpenFile(input OpenFileInput) (store.File, error) {
+	if err := guardPath(input.Path); err != nil {
 		return store.File{}, err
 	}
-	defer file.Close()
 
-	data, err := io.ReadAll(file)
+	if input.StartLine <= 0 && input.EndLine <= 0 {
+		file, err := os.Open(input.Path)
+		if err != nil {
+			return store.File{}, err
+		}
+		defer file.Close()
+
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return store.File{}, err
+		}
+
+		return store.File{
+			Path:    input.Path,
+			Content: string(data),
+		}, nil
+	}
+
+	r, err := readLines(input.Path, input.StartLine, input.EndLine, 0)
 	if err != nil {
 		return store.File{}, err
 	}
 
 	return store.File{
 		Path:    input.Path,
-		Content: string(data),
+		Content: r.content,
 	}, nil
 }
+
+type linesResult struct {
+	content       string
+	startLine     int
+	endLine       int
+	totalLines    int
+	lineTruncated bool
+}
+
+// readLines reads the lines from startLine to endLine.
+// When maxBytes is positive, the lines over maxBytes are not included.
+func readLines(path string, startLine int, endLine int, maxBytes int) (linesResult, error) {
+	if startLine <= 0 {
+		startLine = 1
+	}
+	if endLine > 0 && endLine < startLine {
+		return linesResult{}, fmt.Errorf("end_line %d is before start_line %d", endLine, startLine)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return linesResult{}, err
+	}
+	defer file.Close()
+
+	result := linesResult{startLine: startLine}
+	var content strings.Builder
+	full := false
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			result.totalLines++
+			n := result.totalLines
+			inRange := n >= startLine && (endLine <= 0 || n <= endLine)
+			if inRange && !full {
+				switch {
+				case maxBytes <= 0 || content.Len()+len(line) <= maxBytes:
+					content.WriteString(line)
+					result.endLine = n
+				case content.Len() == 0:
+					// a single line over the limit, such as minified code
+					// it is cut at the start of a rune not to break a multi-byte character
+					cut := maxBytes
+					for cut > 0 && !utf8.RuneStart(line[cut]) {
+						cut--
+					}
+					content.WriteString(line[:cut] + "\n")
+					result.endLine = n
+					result.lineTruncated = true
+					full = true
+				default:
+					full = true
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return linesResult{}, err
+		}
+	}
+
+	if startLine > max(result.totalLines, 1) {
+		return linesResult{}, fmt.Errorf("start_line %d is over the total lines %d of %s", startLine, result.totalLines, path)
+	}
+
+	result.content = content.String()
+	return result, nil
+}
+
+func countLines(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	lines := 0
+	endsWithNewline := true
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := file.Read(buf)
+		if n > 0 {
+			lines += bytes.Count(buf[:n], []byte("\n"))
+			endsWithNewline = buf[n-1] == '\n'
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	if !endsWithNewline {
+		lines++
+	}
+
+	return lines, nil
+}
+
+func withLineNumbers(content string, startLine int) string {
+	if content == "" {
+		return ""
+	}
+	lines := strings.SplitAfter(content, "\n")
+	var b strings.Builder
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+		b.WriteString(fmt.Sprintf("%d: %s", startLine+i, line))
+	}
+	return b.String()
+}
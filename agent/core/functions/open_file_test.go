@@ -1,9 +1,12 @@
 package functions_test
 
 import (
+	"fmt"
 	"os"
 	"path"
+	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/clover0/issue-agent/core/functions"
 	"github.com/clover0/issue-agent/core/store"
@@ -90,3 +93,98 @@ func TestOpenFile(t *testing.T) {
 		})
 	}
 }
+
+func TestOpenFileWithLimit(t *testing.T) {
+	t.Parallel()
+
+	content := "line1\nline2\nline3\nline4\nline5\n"
+
+	tests := map[string]struct {
+		input    functions.OpenFileInput
+		maxBytes int
+		want     string
+		wantErr  bool
+	}{
+		"whole file": {
+			input:    functions.OpenFileInput{},
+			maxBytes: 100,
+			want:     content,
+		},
+		"whole file with line numbers": {
+			input:    functions.OpenFileInput{WithLineNumbers: true},
+			maxBytes: 100,
+			want:     "1: line1\n2: line2\n3: line3\n4: line4\n5: line5\n",
+		},
+		"too large file": {
+			input:    functions.OpenFileInput{},
+			maxBytes: 10,
+			want: "The file %s is too large to open at once: 30 bytes, 5 lines. The limit is 10 bytes.\n" +
+				"Open the file in ranges of lines with start_line and end_line.\n",
+		},
+		"line range": {
+			input:    functions.OpenFileInput{StartLine: 2, EndLine: 3, WithLineNumbers: true},
+			maxBytes: 100,
+			want:     "# %s (lines 2-3 of 5 lines)\n(continue from start_line 4 to read the rest)\n2: line2\n3: line3\n",
+		},
+		"line range to the end": {
+			input:    functions.OpenFileInput{StartLine: 4},
+			maxBytes: 100,
+			want:     "# %s (lines 4-5 of 5 lines)\nline4\nline5\n",
+		},
+		"line range shortened by the limit": {
+			input:    functions.OpenFileInput{StartLine: 1, EndLine: 5},
+			maxBytes: 13,
+			want:     "# %s (lines 1-2 of 5 lines)\n(continue from start_line 3 to read the rest)\nline1\nline2\n",
+		},
+		"start line over the total lines": {
+			input:    functions.OpenFileInput{StartLine: 6},
+			maxBytes: 100,
+			wantErr:  true,
+		},
+		"end line before start line": {
+			input:    functions.OpenFileInput{StartLine: 3, EndLine: 2},
+			maxBytes: 100,
+			wantErr:  true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			filePath := path.Join(t.TempDir(), "test.txt")
+			assert.NoError(t, os.WriteFile(filePath, []byte(content), 0644))
+
+			tt.input.Path = filePath
+			got, err := functions.OpenFileWithLimit(functions.OpenFileSetting{MaxBytes: tt.maxBytes}, tt.input)
+			if tt.wantErr {
+				assert.HasError(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			want := tt.want
+			if strings.Contains(want, "%s") {
+				want = fmt.Sprintf(want, filePath)
+			}
+			assert.Equal(t, got.ToLLMString(), want)
+		})
+	}
+}
+
+func TestOpenFileWithLimit_MultiByteLine(t *testing.T) {
+	t.Parallel()
+
+	filePath := path.Join(t.TempDir(), "test.txt")
+	assert.NoError(t, os.WriteFile(filePath, []byte(strings.Repeat("日本語", 100)+"\n"), 0644))
+
+	// 3 bytes per character, so the limit is in the middle of a character
+	got, err := functions.OpenFileWithLimit(functions.OpenFileSetting{MaxBytes: 10}, functions.OpenFileInput{
+		Path:      filePath,
+		StartLine: 1,
+	})
+	assert.NoError(t, err)
+	assert.Contains(t, got.ToLLMString(), "日本語\n")
+	assert.Equal(t, utf8.ValidString(got.ToLLMString()), true)
+	assert.Equal(t, strings.Contains(got.ToLLMString(), "日本語日"), false)
+}
@@ -1,6 +1,7 @@
 package functions
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -11,35 +12,21 @@ import (
 const FuncPutFile = "put_file"
 
 func InitPutFileFunction() Function {
-	f := Function{
-		Name:        FuncPutFile,
-		Description: "Put new file content to path",
-		Func:        PutFile,
-		Parameters: map[string]any{
-			"type": "object",
-			"properties": map[string]any{
-				"path": map[string]any{
-					"type":        "string",
-					"description": "Path of the file to be changed to the new content",
-				},
-				"content_text": map[string]any{
-					"type":        "string",
-					"description": "The new content of the file",
-				},
-			},
-			"required":             []string{"path", "content_text"},
-			"additionalProperties": false,
+	return NewFunction(
+		FuncPutFile,
+		"Put new file content to path",
+		func(_ context.Context, input PutFileInput) (string, error) {
+			if _, err := PutFile(input); err != nil {
+				return "", err
+			}
+			return defaultSuccessReturning, nil
 		},
-	}
-
-	register(f)
-
-	return f
+	)
 }
 
 type PutFileInput struct {
-	Path        string `json:"path"`
-	ContentText string `json:"content_text"`
+	Path        string `json:"path" jsonschema:"required" description:"Path of the file to be changed to the new content"`
+	ContentText string `json:"content_text" jsonschema:"required" description:"The new content of the file"`
 }
 
 func PutFile(input PutFileInput) (store.File, error) {
@@ -1,6 +1,7 @@
 package functions
 
 import (
+	"context"
 	"fmt"
 	"os"
 )
@@ -8,30 +9,20 @@ import (
 const FuncRemoveFile = "remove_file"
 
 func InitRemoveFileFunction() Function {
-	f := Function{
-		Name:        FuncRemoveFile,
-		Description: "Remove a file specified by the path",
-		Func:        RemoveFile,
-		Parameters: map[string]any{
-			"type": "object",
-			"properties": map[string]any{
-				"path": map[string]any{
-					"type":        "string",
-					"description": "Path of the file to be removed",
-				},
-			},
-			"required":             []string{"path"},
-			"additionalProperties": false,
+	return NewFunction(
+		FuncRemoveFile,
+		"Remove a file specified by the path",
+		func(_ context.Context, input RemoveFileInput) (string, error) {
+			if err := RemoveFile(input); err != nil {
+				return "", err
+			}
+			return defaultSuccessReturning, nil
 		},
-	}
-
-	register(f)
-
-	return f
+	)
 }
 
 type RemoveFileInput struct {
-	Path string `json:"path"`
+	Path string `json:"path" jsonschema:"required" description:"Path of the file to be removed"`
 }
 
 func RemoveFile(input RemoveFileInput) error {
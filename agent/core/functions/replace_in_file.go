@@ -0,0 +1,171 @@
+package functions
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/clover0/issue-agent/core/store"
+)
+
+const FuncReplaceInFile = "replace_in_file"
+
+func InitReplaceInFileFunction() Function {
+	return NewFunction(
+		FuncReplaceInFile,
+		"Edit part of the file by replacing exact search text with replacement text. "+
+			"Use this instead of modify_file to change a part of the file. "+
+			"Each search text must match exactly one place in the file including indentation. "+
+			"When any search text does not match, no replacement is applied.",
+		func(_ context.Context, input ReplaceInFileInput) (string, error) {
+			if _, err := ReplaceInFile(input); err != nil {
+				return "", err
+			}
+			return defaultSuccessReturning, nil
+		},
+	)
+}
+
+type ReplaceInFileInput struct {
+	Path         string        `json:"path" jsonschema:"required" description:"Path of the file to be edited"`
+	Replacements []Replacement `json:"replacements" jsonschema:"required" description:"Replacements applied in order. Later search texts are matched against the content after the former replacements."`
+}
+
+type Replacement struct {
+	Search  string `json:"search" jsonschema:"required" description:"The exact text to be replaced. Include a few surrounding lines to make it unique in the file."`
+	Replace string `json:"replace" jsonschema:"required" description:"The new text replacing the search text. Empty to delete the search text."`
+}
+
+// ReplaceInFile applies all replacements to the file or none of them.
+// The file is written atomically not to leave a half-written file.
+func ReplaceInFile(input ReplaceInFileInput) (store.File, error) {
+	if err := guardPath(input.Path); err != nil {
+		return store.File{}, err
+	}
+
+	if len(input.Replacements) == 0 {
+		return store.File{}, fmt.Errorf("replacements are empty")
+	}
+
+	info, err := os.Stat(input.Path)
+	if err != nil {
+		return store.File{}, fmt.Errorf("replace in %s: %w", input.Path, err)
+	}
+
+	data, err := os.ReadFile(input.Path)
+	if err != nil {
+		return store.File{}, fmt.Errorf("replace in %s: %w", input.Path, err)
+	}
+
+	content := string(data)
+	for i, r := range input.Replacements {
+		content, err = replaceOnce(content, r)
+		if err != nil {
+			return store.File{}, fmt.Errorf("replacement %d in %s: %w. no replacement is applied", i+1, input.Path, err)
+		}
+	}
+
+	if err := writeFileAtomic(input.Path, []byte(content), info.Mode().Perm()); err != nil {
+		return store.File{}, fmt.Errorf("replace in %s: %w", input.Path, err)
+	}
+
+	return store.File{
+		Path:    input.Path,
+		Content: content,
+	}, nil
+}
+
+func replaceOnce(content string, r Replacement) (string, error) {
+	if r.Search == "" {
+		return "", fmt.Errorf("search text is empty")
+	}
+
+	switch n := strings.Count(content, r.Search); n {
+	case 1:
+		return strings.Replace(content, r.Search, r.Replace, 1), nil
+	case 0:
+		return "", fmt.Errorf("search text is not found. %s", describeMismatch(content, r.Search))
+	default:
+		return "", fmt.Errorf("search text is found %d times at lines %s. include more surrounding lines to make it unique",
+			n, strings.Join(matchedLines(content, r.Search), ", "))
+	}
+}
+
+func matchedLines(content string, search string) []string {
+	var lines []string
+	offset := 0
+	for {
+		i := strings.Index(content[offset:], search)
+		if i < 0 {
+			return lines
+		}
+		lines = append(lines, fmt.Sprint(strings.Count(content[:offset+i], "\n")+1))
+		offset += i + len(search)
+	}
+}
+
+// describeMismatch finds the place most similar to the search text
+// and reports the first line that differs, so that the model can fix the search text.
+func describeMismatch(content string, search string) string {
+	fileLines := strings.Split(content, "\n")
+	searchLines := strings.Split(strings.TrimSuffix(search, "\n"), "\n")
+
+	bestStart, bestMatched := -1, 0
+	for start := range fileLines {
+		matched := 0
+		for matched < len(searchLines) && start+matched < len(fileLines) &&
+			strings.TrimSpace(fileLines[start+matched]) == strings.TrimSpace(searchLines[matched]) {
+			matched++
+		}
+		if matched > bestMatched {
+			bestStart, bestMatched = start, matched
+		}
+	}
+
+	if bestStart < 0 {
+		return fmt.Sprintf("no line in the file matches the first line of the search text `%s`. open the file and check the current content",
+			searchLines[0])
+	}
+
+	// the lines match ignoring whitespace, so find the first line differing in whitespace
+	for i := 0; i < bestMatched; i++ {
+		if fileLines[bestStart+i] != searchLines[i] {
+			return fmt.Sprintf("line %d of the file differs only in whitespace or indentation. file: `%s`, search text: `%s`",
+				bestStart+i+1, fileLines[bestStart+i], searchLines[i])
+		}
+	}
+
+	if bestStart+bestMatched >= len(fileLines) {
+		return fmt.Sprintf("the search text matches from line %d to the end of the file, but the search text has more lines",
+			bestStart+1)
+	}
+
+	return fmt.Sprintf("the search text matches from line %d to line %d, but line %d of the file is `%s` while the search text expects `%s`",
+		bestStart+1, bestStart+bestMatched, bestStart+bestMatched+1,
+		fileLines[bestStart+bestMatched], searchLines[bestMatched])
+}
+
+// writeFileAtomic writes the data to a temporary file and renames it to the path.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,108 @@
+package functions_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/clover0/issue-agent/core/functions"
+	"github.com/clover0/issue-agent/test/assert"
+)
+
+func TestReplaceInFile(t *testing.T) {
+	t.Parallel()
+
+	original := `package main
+
+func main() {
+	println("hello")
+	println("hello")
+}
+`
+
+	tests := map[string]struct {
+		replacements []functions.Replacement
+		want         string
+		wantErr      string
+	}{
+		"single replacement": {
+			replacements: []functions.Replacement{
+				{Search: "func main() {\n\tprintln(\"hello\")\n", Replace: "func main() {\n\tprintln(\"world\")\n"},
+			},
+			want: `package main
+
+func main() {
+	println("world")
+	println("hello")
+}
+`,
+		},
+		"replacements applied in order": {
+			replacements: []functions.Replacement{
+				{Search: "package main", Replace: "package app"},
+				{Search: "package app\n\nfunc main", Replace: "package app\n\nfunc run"},
+			},
+			want: `package app
+
+func run() {
+	println("hello")
+	println("hello")
+}
+`,
+		},
+		"search text found multiple times": {
+			replacements: []functions.Replacement{
+				{Search: `println("hello")`, Replace: `println("world")`},
+			},
+			wantErr: "replacement 1 in %s: search text is found 2 times at lines 4, 5. include more surrounding lines to make it unique. no replacement is applied",
+		},
+		"search text differs in indentation": {
+			replacements: []functions.Replacement{
+				{Search: "func main() {\n    println(\"hello\")", Replace: ""},
+			},
+			wantErr: "replacement 1 in %s: search text is not found. line 4 of the file differs only in whitespace or indentation. " +
+				"file: `\tprintln(\"hello\")`, search text: `    println(\"hello\")`. no replacement is applied",
+		},
+		"search text differs in a line": {
+			replacements: []functions.Replacement{
+				{Search: "func main() {\n\tprintln(\"bye\")", Replace: ""},
+			},
+			wantErr: "replacement 1 in %s: search text is not found. the search text matches from line 3 to line 3, " +
+				"but line 4 of the file is `\tprintln(\"hello\")` while the search text expects `\tprintln(\"bye\")`. no replacement is applied",
+		},
+		"no replacement applied when later one fails": {
+			replacements: []functions.Replacement{
+				{Search: "package main", Replace: "package app"},
+				{Search: "func unknown() {", Replace: ""},
+			},
+			wantErr: "replacement 2 in %s: search text is not found. no line in the file matches the first line of the search text `func unknown() {`. " +
+				"open the file and check the current content. no replacement is applied",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "main.go")
+			assert.NoError(t, os.WriteFile(path, []byte(original), 0644))
+
+			_, err := functions.ReplaceInFile(functions.ReplaceInFileInput{
+				Path:         path,
+				Replacements: tt.replacements,
+			})
+
+			got, readErr := os.ReadFile(path)
+			assert.NoError(t, readErr)
+			if tt.wantErr != "" {
+				assert.HasError(t, err)
+				assert.Equal(t, err.Error(), fmt.Sprintf(tt.wantErr, path))
+				assert.Equal(t, string(got), original)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, string(got), tt.want)
+		})
+	}
+}
@@ -1,50 +1,29 @@
 package functions
 
+import "context"
+
 const FuncRequestReviewers = "request_reviewers"
 
-type RequestReviewersType func(input RequestReviewersInput) (RequestReviewersOutput, error)
+type RequestReviewersType func(ctx context.Context, input RequestReviewersInput) (RequestReviewersOutput, error)
 
 func InitRequestReviewersFunction(service GitHubService) Function {
-	f := Function{
-		Name:        FuncRequestReviewers,
-		Description: "Request reviewers for a GitHub pull request.",
-		Func:        RequestReviewersCaller(service),
-		Parameters: map[string]any{
-			"type": "object",
-			"properties": map[string]any{
-				"pr_number": map[string]any{
-					"type":        "number",
-					"description": "GitHub Pull Request Number to request reviewers for.",
-				},
-				"member_reviewers": map[string]any{
-					"type": "array",
-					"items": map[string]string{
-						"type": "string",
-					},
-					"description": "List of member `login`s to request on the pull request.",
-				},
-				"team_reviewers": map[string]any{
-					"type": "array",
-					"items": map[string]string{
-						"type": "string",
-					},
-					"description": "List of team `slug`s to request on the pull request.",
-				},
-			},
-			"required":             []string{"pr_number"},
-			"additionalProperties": false,
+	return NewFunction(
+		FuncRequestReviewers,
+		"Request reviewers for a GitHub pull request.",
+		func(ctx context.Context, input RequestReviewersInput) (string, error) {
+			out, err := RequestReviewersCaller(service)(ctx, input)
+			if err != nil {
+				return "", err
+			}
+			return out.ToLLMString(), nil
 		},
-	}
-
-	register(f)
-
-	return f
+	)
 }
 
 type RequestReviewersInput struct {
-	PRNumber        int      `json:"pr_number"`
-	MemberReviewers []string `json:"member_reviewers"`
-	TeamReviewers   []string `json:"team_reviewers"`
+	PRNumber        int      `json:"pr_number" jsonschema:"required" description:"GitHub Pull Request Number to request reviewers for."`
+	MemberReviewers []string `json:"member_reviewers" description:"List of member 'login's to request on the pull request."`
+	TeamReviewers   []string `json:"team_reviewers" description:"List of team 'slug's to request on the pull request."`
 }
 
 type RequestReviewersOutput struct{}
@@ -54,7 +33,7 @@ func (g RequestReviewersOutput) ToLLMString() string {
 }
 
 func RequestReviewersCaller(service GitHubService) RequestReviewersType {
-	return func(input RequestReviewersInput) (RequestReviewersOutput, error) {
-		return service.RequestReviewers(input.PRNumber, input.MemberReviewers, input.TeamReviewers)
+	return func(ctx context.Context, input RequestReviewersInput) (RequestReviewersOutput, error) {
+		return service.RequestReviewers(ctx, input.PRNumber, input.MemberReviewers, input.TeamReviewers)
 	}
 }
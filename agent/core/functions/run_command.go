@@ -0,0 +1,219 @@
+package functions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/clover0/issue-agent/config"
+)
+
+const FuncRunCommand = "run_command"
+
+type RunCommandType func(input RunCommandInput) (RunCommandOutput, error)
+
+// RunCommandSetting is the allowlist of commands that run_command can run.
+type RunCommandSetting struct {
+	Commands       []AllowedCommand
+	MaxOutputBytes int
+}
+
+type AllowedCommand struct {
+	Command string
+	Timeout time.Duration
+}
+
+func InitRunCommandFunction(setting RunCommandSetting) Function {
+	f := NewFunction(
+		FuncRunCommand,
+		"Run a command such as tests or linters in the repository and get the exit code and the output. "+
+			"Only the allowed commands can be run, and the shell is not available.",
+		func(_ context.Context, input RunCommandInput) (string, error) {
+			out, err := RunCommandCaller(setting)(input)
+			if err != nil {
+				return "", err
+			}
+			return out.ToLLMString(), nil
+		},
+	)
+	f.Parameters.SetPropertyDescription("command",
+		"The command to run. It must be exactly one of the allowed commands: "+
+			strings.Join(allowedCommandNames(setting.Commands), ", "))
+
+	return f
+}
+
+type RunCommandInput struct {
+	Command string `json:"command" jsonschema:"required"`
+	Dir     string `json:"dir" description:"The directory to run the command in, relative to the repository root. Default is the repository root."`
+}
+
+type RunCommandOutput struct {
+	Command   string
+	ExitCode  int
+	TimedOut  bool
+	Truncated bool
+	Output    string
+}
+
+func (r RunCommandOutput) ToLLMString() string {
+	s := fmt.Sprintf("# Command\n%s\n\n", r.Command)
+	if r.TimedOut {
+		s += "# Exit Code\ntimed out\n\n"
+	} else {
+		s += fmt.Sprintf("# Exit Code\n%d\n\n", r.ExitCode)
+	}
+	s += "# Output\n"
+	if r.Truncated {
+		s += "(the output is truncated)\n"
+	}
+	s += r.Output
+	return s
+}
+
+func RunCommandCaller(setting RunCommandSetting) RunCommandType {
+	return func(input RunCommandInput) (RunCommandOutput, error) {
+		return RunCommand(setting, input)
+	}
+}
+
+// RunCommand runs the allowed command without a shell.
+// A command exiting with non-zero code is not an error, because the agent needs the output to fix the code.
+func RunCommand(setting RunCommandSetting, input RunCommandInput) (RunCommandOutput, error) {
+	allowed, ok := findAllowedCommand(setting.Commands, input.Command)
+	if !ok {
+		return RunCommandOutput{}, fmt.Errorf("command `%s` is not allowed. allowed commands: %s",
+			input.Command, strings.Join(allowedCommandNames(setting.Commands), ", "))
+	}
+
+	dir := input.Dir
+	if dir == "" {
+		dir = "."
+	}
+	if err := guardPath(dir); err != nil {
+		return RunCommandOutput{}, err
+	}
+
+	timeout := allowed.Timeout
+	if timeout <= 0 {
+		timeout = config.DefaultRunCommandTimeout
+	}
+	maxBytes := setting.MaxOutputBytes
+	if maxBytes <= 0 {
+		maxBytes = config.DefaultRunCommandMaxOutputBytes
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	args := strings.Fields(allowed.Command)
+	out := newTruncatingWriter(maxBytes)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.Env = commandEnv(os.Environ())
+	cmd.Stdout = out
+	cmd.Stderr = out
+	// child processes keeping the output open must not block after the timeout
+	cmd.WaitDelay = time.Second
+
+	result := RunCommandOutput{Command: allowed.Command}
+	err := cmd.Run()
+	result.Output = out.String()
+	result.Truncated = out.truncated
+
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result.TimedOut = true
+		result.ExitCode = -1
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	case err != nil:
+		return RunCommandOutput{}, fmt.Errorf("failed to run command %s: %w", allowed.Command, err)
+	}
+
+	return result, nil
+}
+
+func findAllowedCommand(commands []AllowedCommand, command string) (AllowedCommand, bool) {
+	fields := strings.Fields(command)
+	for _, c := range commands {
+		allowed := strings.Fields(c.Command)
+		if len(allowed) > 0 && strings.Join(allowed, " ") == strings.Join(fields, " ") {
+			return c, true
+		}
+	}
+
+	return AllowedCommand{}, false
+}
+
+func allowedCommandNames(commands []AllowedCommand) []string {
+	names := make([]string, 0, len(commands))
+	for _, c := range commands {
+		names = append(names, c.Command)
+	}
+	return names
+}
+
+// commandEnv removes credentials from the environment,
+// because the commands run code in the repository that the agent can change.
+func commandEnv(environ []string) []string {
+	var env []string
+	for _, e := range environ {
+		name, _, _ := strings.Cut(e, "=")
+		upper := strings.ToUpper(name)
+		if strings.HasPrefix(upper, "AWS_") ||
+			strings.Contains(upper, "TOKEN") ||
+			strings.Contains(upper, "SECRET") ||
+			strings.Contains(upper, "API_KEY") ||
+			strings.Contains(upper, "PASSWORD") ||
+			strings.Contains(upper, "CREDENTIAL") {
+			continue
+		}
+		env = append(env, e)
+	}
+	return env
+}
+
+// truncatingWriter keeps the head and the tail of the output within the limit.
+// Test and linter results often have the summary at the end.
+type truncatingWriter struct {
+	limit     int
+	head      []byte
+	tail      []byte
+	truncated bool
+}
+
+func newTruncatingWriter(limit int) *truncatingWriter {
+	return &truncatingWriter{limit: limit}
+}
+
+func (w *truncatingWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	headLimit := w.limit / 2
+	if len(w.head) < headLimit {
+		size := min(headLimit-len(w.head), len(p))
+		w.head = append(w.head, p[:size]...)
+		p = p[size:]
+	}
+
+	tailLimit := w.limit - headLimit
+	w.tail = append(w.tail, p...)
+	if len(w.tail) > tailLimit {
+		w.truncated = true
+		w.tail = append(w.tail[:0], w.tail[len(w.tail)-tailLimit:]...)
+	}
+
+	return n, nil
+}
+
+func (w *truncatingWriter) String() string {
+	if w.truncated {
+		return string(w.head) + "\n...\n" + string(w.tail)
+	}
+	return string(w.head) + string(w.tail)
+}
@@ -0,0 +1,85 @@
+package functions_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clover0/issue-agent/core/functions"
+	"github.com/clover0/issue-agent/test/assert"
+)
+
+func TestRunCommand(t *testing.T) {
+	t.Parallel()
+
+	setting := functions.RunCommandSetting{
+		Commands: []functions.AllowedCommand{
+			{Command: "echo hello", Timeout: time.Second},
+			{Command: "false", Timeout: time.Second},
+			{Command: "sleep 5", Timeout: 100 * time.Millisecond},
+			{Command: "seq 1 1000", Timeout: time.Second},
+		},
+		MaxOutputBytes: 100,
+	}
+
+	tests := map[string]struct {
+		input         functions.RunCommandInput
+		wantExitCode  int
+		wantOutput    string
+		wantTimedOut  bool
+		wantTruncated bool
+		wantErr       bool
+	}{
+		"allowed command": {
+			input:      functions.RunCommandInput{Command: "echo hello"},
+			wantOutput: "hello\n",
+		},
+		"allowed command with extra spaces": {
+			input:      functions.RunCommandInput{Command: " echo   hello "},
+			wantOutput: "hello\n",
+		},
+		"non-zero exit code": {
+			input:        functions.RunCommandInput{Command: "false"},
+			wantExitCode: 1,
+		},
+		"timeout": {
+			input:        functions.RunCommandInput{Command: "sleep 5"},
+			wantExitCode: -1,
+			wantTimedOut: true,
+		},
+		"truncated output": {
+			input:         functions.RunCommandInput{Command: "seq 1 1000"},
+			wantTruncated: true,
+		},
+		"not allowed command": {
+			input:   functions.RunCommandInput{Command: "rm -rf ."},
+			wantErr: true,
+		},
+		"not allowed arguments": {
+			input:   functions.RunCommandInput{Command: "echo hello world"},
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := functions.RunCommand(setting, tt.input)
+			if tt.wantErr {
+				assert.HasError(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, got.ExitCode, tt.wantExitCode)
+			assert.Equal(t, got.TimedOut, tt.wantTimedOut)
+			assert.Equal(t, got.Truncated, tt.wantTruncated)
+			if tt.wantTruncated {
+				assert.Equal(t, strings.HasPrefix(got.Output, "1\n2\n"), true)
+				assert.Equal(t, strings.HasSuffix(got.Output, "999\n1000\n"), true)
+				return
+			}
+			assert.Equal(t, got.Output, tt.wantOutput)
+		})
+	}
+}
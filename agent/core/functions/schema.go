@@ -0,0 +1,139 @@
+package functions
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// SchemaOf generates the JSON schema of the input struct of a function.
+// The schema is built from the struct tags of the fields:
+//
+//   - json: the property name. Fields without the json tag or tagged with "-" are skipped.
+//   - description: the description of the property.
+//   - jsonschema: comma-separated options: required, minimum=N, maximum=N, default=V, minLength=N, maxLength=N.
+//
+// It panics when the input is not a struct or the tags are invalid,
+// because the schemas are fixed at compile time.
+func SchemaOf[I any]() JSONSchema {
+	t := reflect.TypeFor[I]()
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("function input must be a struct: %s", t))
+	}
+	return objectSchema(t)
+}
+
+func objectSchema(t reflect.Type) JSONSchema {
+	properties := map[string]any{}
+	required := []string{}
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || name == "" || name == "-" {
+			continue
+		}
+
+		prop := typeSchema(field.Type)
+		if d := field.Tag.Get("description"); d != "" {
+			prop["description"] = d
+		}
+		if applySchemaOptions(prop, field) {
+			required = append(required, name)
+		}
+		properties[name] = prop
+	}
+
+	return JSONSchema{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+func typeSchema(t reflect.Type) JSONSchema {
+	switch t.Kind() {
+	case reflect.String:
+		return JSONSchema{"type": "string"}
+	case reflect.Bool:
+		return JSONSchema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return JSONSchema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return JSONSchema{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return JSONSchema{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Struct:
+		return objectSchema(t)
+	case reflect.Pointer:
+		return typeSchema(t.Elem())
+	default:
+		panic(fmt.Sprintf("unsupported type of function input: %s", t))
+	}
+}
+
+// applySchemaOptions sets the jsonschema tag options to the property and reports whether the field is required.
+func applySchemaOptions(prop JSONSchema, field reflect.StructField) bool {
+	tag := field.Tag.Get("jsonschema")
+	if tag == "" {
+		return false
+	}
+
+	required := false
+	for _, opt := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(opt, "=")
+		switch key {
+		case "required":
+			required = true
+		case "minimum", "maximum", "minLength", "maxLength":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				panic(fmt.Sprintf("invalid %s of %s: %s", key, field.Name, value))
+			}
+			prop[key] = n
+		case "default":
+			prop[key] = defaultValue(field, value)
+		default:
+			panic(fmt.Sprintf("unknown jsonschema option of %s: %s", field.Name, opt))
+		}
+	}
+
+	return required
+}
+
+func defaultValue(field reflect.StructField, value string) any {
+	var (
+		v   any
+		err error
+	)
+	switch prop := typeSchema(field.Type); prop["type"] {
+	case "boolean":
+		v, err = strconv.ParseBool(value)
+	case "integer":
+		v, err = strconv.Atoi(value)
+	case "number":
+		v, err = strconv.ParseFloat(value, 64)
+	case "string":
+		v = value
+	default:
+		err = fmt.Errorf("default is not supported for %s", prop["type"])
+	}
+	if err != nil {
+		panic(fmt.Sprintf("invalid default of %s: %s: %v", field.Name, value, err))
+	}
+	return v
+}
+
+// SetPropertyDescription sets the description of the property of the object schema.
+// Use this for the description decided at runtime, such as by the setting.
+func (s JSONSchema) SetPropertyDescription(name string, description string) {
+	properties, ok := s["properties"].(map[string]any)
+	if !ok {
+		return
+	}
+	if prop, ok := properties[name].(JSONSchema); ok {
+		prop["description"] = description
+	}
+}
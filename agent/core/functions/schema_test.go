@@ -0,0 +1,79 @@
+package functions_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/clover0/issue-agent/core/functions"
+	"github.com/clover0/issue-agent/test/assert"
+)
+
+type schemaTestItem struct {
+	Key string `json:"key" jsonschema:"required"`
+}
+
+type schemaTestInput struct {
+	Path    string           `json:"path" jsonschema:"required" description:"the path"`
+	Depth   int              `json:"depth" jsonschema:"minimum=1,maximum=3,default=2"`
+	Verbose bool             `json:"verbose" jsonschema:"default=false"`
+	Ratio   float64          `json:"ratio"`
+	Names   []string         `json:"names" jsonschema:"minLength=1"`
+	Items   []schemaTestItem `json:"items"`
+	Skipped string           `json:"-"`
+	NoTag   string
+}
+
+func TestSchemaOf(t *testing.T) {
+	t.Parallel()
+
+	got, err := json.Marshal(functions.SchemaOf[schemaTestInput]())
+	assert.Nil(t, err)
+
+	want := `{"additionalProperties":false,"properties":{` +
+		`"depth":{"default":2,"maximum":3,"minimum":1,"type":"integer"},` +
+		`"items":{"items":{"additionalProperties":false,"properties":{"key":{"type":"string"}},"required":["key"],"type":"object"},"type":"array"},` +
+		`"names":{"items":{"type":"string"},"minLength":1,"type":"array"},` +
+		`"path":{"description":"the path","type":"string"},` +
+		`"ratio":{"type":"number"},` +
+		`"verbose":{"default":false,"type":"boolean"}` +
+		`},"required":["path"],"type":"object"}`
+	assert.Equal(t, string(got), want)
+}
+
+func TestNewFunction_Exec(t *testing.T) {
+	t.Parallel()
+
+	f := functions.NewFunction("echo", "echo the path", func(_ context.Context, input schemaTestInput) (string, error) {
+		return input.Path, nil
+	})
+
+	tests := map[string]struct {
+		args    string
+		want    string
+		wantErr bool
+	}{
+		"unmarshal the arguments to the input": {
+			args: `{"path": "a/b.go", "depth": 1}`,
+			want: "a/b.go",
+		},
+		"invalid arguments": {
+			args:    `{"path": 1}`,
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := f.Exec(t.Context(), tt.args)
+			if tt.wantErr {
+				assert.HasError(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, got, tt.want)
+		})
+	}
+}
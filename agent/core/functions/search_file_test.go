@@ -102,7 +102,7 @@ func TestSearchFiles(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
-			result, err := functions.SearchFiles(tt.input)
+			result, err := functions.SearchFiles(functions.NewWalker(functions.WalkerSetting{}), tt.input)
 
 			if tt.wantErr {
 				assert.HasError(t, err)
@@ -2,49 +2,44 @@ package functions
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
-	"slices"
 	"strings"
 )
 
 const FuncSearchFiles = "search_files"
 
-func InitSearchFilesFunction() Function {
-	f := Function{
-		Name: FuncSearchFiles,
-		Description: strings.ReplaceAll(`Search for files containing specific keyword (e.g., "xxx")
+type SearchFilesType func(input SearchFilesInput) ([]string, error)
+
+func InitSearchFilesFunction(walker Walker) Function {
+	return NewReadOnlyFunction(
+		FuncSearchFiles,
+		strings.ReplaceAll(`Search for files containing specific keyword (e.g., "xxx")
  within a directory path recursively`, "\n", ""),
-		Func: SearchFiles,
-		Parameters: map[string]any{
-			"type": "object",
-			"properties": map[string]any{
-				"keyword": map[string]any{
-					"type":        "string",
-					"description": "The keyword to search for.",
-				},
-				"path": map[string]any{
-					"type":        "string",
-					"description": "The path to search within its directory",
-				},
-			},
-			"required":             []string{"keyword", "path"},
-			"additionalProperties": false,
+		func(_ context.Context, input SearchFilesInput) (string, error) {
+			files, err := SearchFilesCaller(walker)(input)
+			if err != nil {
+				return "", err
+			}
+			return strings.Join(files, "\n"), nil
 		},
-	}
-
-	register(f)
-
-	return f
+	)
 }
 
 type SearchFilesInput struct {
-	Keyword string
-	Path    string
+	Keyword string `json:"keyword" jsonschema:"required" description:"The keyword to search for."`
+	Path    string `json:"path" jsonschema:"required" description:"The path to search within its directory"`
 }
 
-func SearchFiles(input SearchFilesInput) ([]string, error) {
+func SearchFilesCaller(walker Walker) SearchFilesType {
+	return func(input SearchFilesInput) ([]string, error) {
+		return SearchFiles(walker, input)
+	}
+}
+
+func SearchFiles(walker Walker, input SearchFilesInput) ([]string, error) {
 	if err := guardPath(input.Path); err != nil {
 		return nil, err
 	}
@@ -53,39 +48,34 @@ func SearchFiles(input SearchFilesInput) ([]string, error) {
 		return nil, fmt.Errorf("%s does not exist: %w", input.Path, err)
 	}
 
-	currentDirs := []string{".", "./"}
 	fileNames := make([]string, 0)
-	err := filepath.WalkDir(input.Path, func(path string, d os.DirEntry, err error) error {
+	err := walker.Walk(input.Path, func(path string, d os.DirEntry) error {
 		if d.IsDir() {
 			// Skip hidden directories
-			if !slices.Contains(currentDirs, d.Name()) && strings.HasPrefix(d.Name(), ".") {
+			if strings.HasPrefix(d.Name(), ".") {
 				return filepath.SkipDir
 			}
 			return nil
 		}
 
-		fileInfo, statErr := os.Lstat(path)
-		if statErr != nil {
-			return fmt.Errorf("failed to get file info: %w", err)
+		if !d.Type().IsRegular() {
+			return nil
 		}
 
-		if !fileInfo.Mode().IsRegular() {
+		binary, err := IsBinaryFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to open file: %w", err)
+		}
+		if binary {
 			return nil
 		}
 
-		f, fileErr := os.Open(path)
-		if fileErr != nil {
-			return fmt.Errorf("failed to open file: %w", fileErr)
+		found, err := containsKeyword(path, input.Keyword)
+		if err != nil {
+			return err
 		}
-		defer f.Close()
-
-		scanner := bufio.NewScanner(f)
-		for scanner.Scan() {
-			line := scanner.Text()
-			if strings.Contains(line, input.Keyword) {
-				fileNames = append(fileNames, filepath.Clean(path))
-				break
-			}
+		if found {
+			fileNames = append(fileNames, filepath.Clean(path))
 		}
 
 		return nil
@@ -97,3 +87,20 @@ func SearchFiles(input SearchFilesInput) ([]string, error) {
 
 	return fileNames, nil
 }
+
+func containsKeyword(path string, keyword string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if strings.Contains(scanner.Text(), keyword) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -1,5 +1,7 @@
 package functions
 
+import "context"
+
 type SubmitFilesServiceInput struct {
 	GitHubOwner string
 	Repository  string
@@ -9,7 +11,7 @@ type SubmitFilesServiceInput struct {
 	PRLabels    []string
 }
 
-type SubmitFilesType func(input SubmitFilesInput) (SubmitFilesOutput, error)
+type SubmitFilesType func(ctx context.Context, input SubmitFilesInput) (SubmitFilesOutput, error)
 
 type SubmitFilesOutput struct {
 	Message           string
@@ -18,5 +20,5 @@ type SubmitFilesOutput struct {
 }
 
 type SubmitFilesService interface {
-	SubmitFiles(callerInput SubmitFilesInput) (SubmitFilesOutput, error)
+	SubmitFiles(ctx context.Context, callerInput SubmitFilesInput) (SubmitFilesOutput, error)
 }
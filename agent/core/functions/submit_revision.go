@@ -1,41 +1,30 @@
 package functions
 
+import "context"
+
 const FuncSubmitRevision = "submit_revision"
 
 func InitSubmitRevisionFunction(service SubmitRevisionService) Function {
-	f := Function{
-		Name:        FuncSubmitRevision,
-		Description: "Submit revision commits changed files using git add and git commit, finally git push on working branch.",
-		Func:        SubmitRevisionCaller(service),
-		Parameters: map[string]any{
-			"type": "object",
-			"properties": map[string]any{
-				"commit_message_short": map[string]any{
-					"type":        "string",
-					"description": "Short commit message indicating purpose to resubmit",
-				},
-				"commit_message_detail": map[string]any{
-					"type":        "string",
-					"description": "Detail commit message indicating resubmitting content",
-				},
-			},
-			"required":             []string{"commit_message_short"},
-			"additionalProperties": false,
+	return NewFunction(
+		FuncSubmitRevision,
+		"Submit revision commits changed files using git add and git commit, finally git push on working branch.",
+		func(ctx context.Context, input SubmitRevisionInput) (string, error) {
+			out, err := SubmitRevisionCaller(service)(ctx, input)
+			if err != nil {
+				return "", err
+			}
+			return out.Message, nil
 		},
-	}
-
-	register(f)
-
-	return f
+	)
 }
 
 type SubmitRevisionInput struct {
-	CommitMessageShort  string `json:"commit_message_short"`
-	CommitMessageDetail string `json:"commit_message_detail"`
+	CommitMessageShort  string `json:"commit_message_short" jsonschema:"required" description:"Short commit message indicating purpose to resubmit"`
+	CommitMessageDetail string `json:"commit_message_detail" description:"Detail commit message indicating resubmitting content"`
 }
 
 func SubmitRevisionCaller(service SubmitRevisionService) SubmitRevisionType {
-	return func(input SubmitRevisionInput) (SubmitRevisionOutput, error) {
-		return service.SubmitRevision(input)
+	return func(ctx context.Context, input SubmitRevisionInput) (SubmitRevisionOutput, error) {
+		return service.SubmitRevision(ctx, input)
 	}
 }
@@ -1,5 +1,7 @@
 package functions
 
+import "context"
+
 type SubmitRevisionServiceInput struct {
 	GitHubOwner string
 	Repository  string
@@ -9,12 +11,12 @@ type SubmitRevisionServiceInput struct {
 	GitName     string
 }
 
-type SubmitRevisionType func(input SubmitRevisionInput) (SubmitRevisionOutput, error)
+type SubmitRevisionType func(ctx context.Context, input SubmitRevisionInput) (SubmitRevisionOutput, error)
 
 type SubmitRevisionOutput struct {
 	Message string
 }
 
 type SubmitRevisionService interface {
-	SubmitRevision(callerInput SubmitRevisionInput) (SubmitRevisionOutput, error)
+	SubmitRevision(ctx context.Context, callerInput SubmitRevisionInput) (SubmitRevisionOutput, error)
 }
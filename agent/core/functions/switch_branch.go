@@ -1,6 +1,7 @@
 package functions
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -11,36 +12,22 @@ import (
 const FuncSwitchBranch = "switch_branch"
 
 func InitSwitchBranchFunction() Function {
-	f := Function{
-		Name:        FuncSwitchBranch,
-		Description: "Switch the branch. Like git checkout, git switch command.",
-		Func:        SwitchBranch,
-		Parameters: map[string]any{
-			"type": "object",
-			"properties": map[string]any{
-				"branch": map[string]any{
-					"type":        "string",
-					"description": "The branch name you want to switch.",
-				},
-				"create_branch": map[string]any{
-					"type":        "boolean",
-					"description": "If you create a new branch, set this to true.The name of the branch to be created is generated by the system.",
-					"default":     false,
-				},
-			},
-			"required":             []string{"create_branch"},
-			"additionalProperties": false,
+	return NewFunction(
+		FuncSwitchBranch,
+		"Switch the branch. Like git checkout, git switch command.",
+		func(_ context.Context, input SwitchBranchInput) (string, error) {
+			r, err := SwitchBranch(input)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%s\n%s\n", defaultSuccessReturning, r), nil
 		},
-	}
-
-	register(f)
-
-	return f
+	)
 }
 
 type SwitchBranchInput struct {
-	Branch       string
-	CreateBranch bool `json:"create_branch"`
+	Branch       string `json:"branch" description:"The branch name you want to switch."`
+	CreateBranch bool   `json:"create_branch" jsonschema:"required,default=false" description:"If you create a new branch, set this to true.The name of the branch to be created is generated by the system."`
 }
 
 func makeBranchName() string {
@@ -0,0 +1,28 @@
+package functions
+
+// JSONSchema is a JSON schema object describing the parameters of a function.
+type JSONSchema map[string]any
+
+// ToolDefinition is a provider-neutral definition of a function passed to LLMs as a tool.
+// Each LLM forwarder converts it to the provider's tool format.
+type ToolDefinition struct {
+	Name        string
+	Description string
+	Parameters  JSONSchema
+}
+
+func (f Function) ToolDefinition() ToolDefinition {
+	return ToolDefinition{
+		Name:        f.Name.String(),
+		Description: f.Description,
+		Parameters:  f.Parameters,
+	}
+}
+
+func ToolDefinitions(fns []Function) []ToolDefinition {
+	defs := make([]ToolDefinition, len(fns))
+	for i, f := range fns {
+		defs[i] = f.ToolDefinition()
+	}
+	return defs
+}
@@ -0,0 +1,103 @@
+package functions
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// binaryCheckBytes is the size of the head of a file to check if it is binary, same as git.
+const binaryCheckBytes = 8000
+
+// WalkerSetting is the setting of the repository walker.
+type WalkerSetting struct {
+	// IgnorePatterns are ignored in addition to .gitignore. The syntax is the same as .gitignore.
+	IgnorePatterns []string
+}
+
+// Walker walks the repository files like git does.
+// .git directory and the files ignored by .gitignore, .git/info/exclude and the setting are skipped.
+// Functions walking the repository should use Walker to see the same files.
+type Walker struct {
+	setting WalkerSetting
+
+	// base is the repository root. Functions run in the repository root.
+	base string
+}
+
+func NewWalker(setting WalkerSetting) Walker {
+	return Walker{setting: setting, base: "."}
+}
+
+// WalkFunc is called for each file and directory except the root directory.
+// Returning filepath.SkipDir skips the directory like fs.WalkDirFunc.
+type WalkFunc func(path string, d fs.DirEntry) error
+
+// Walk walks the file tree of the root.
+// When the root is a relative path, it is relative to the repository root,
+// otherwise the root is treated as the repository root.
+func (w Walker) Walk(root string, fn WalkFunc) error {
+	base := w.base
+	if filepath.IsAbs(root) {
+		base = root
+	} else {
+		root = filepath.Join(w.base, root)
+	}
+
+	ignore, err := newIgnoreMatcher(base, w.setting.IgnorePatterns)
+	if err != nil {
+		return fmt.Errorf("failed to read ignore patterns: %w", err)
+	}
+	if err := ignore.loadParents(filepath.Dir(filepath.Clean(root))); err != nil {
+		return fmt.Errorf("failed to read .gitignore: %w", err)
+	}
+
+	root = filepath.Clean(root)
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if path == root {
+			if d.IsDir() {
+				return ignore.loadDir(path)
+			}
+			return fn(path, d)
+		}
+
+		if d.IsDir() {
+			if d.Name() == ".git" || ignore.match(path, true) {
+				return filepath.SkipDir
+			}
+			if err := fn(path, d); err != nil {
+				return err
+			}
+			return ignore.loadDir(path)
+		}
+
+		if ignore.match(path, false) {
+			return nil
+		}
+		return fn(path, d)
+	})
+}
+
+// IsBinaryFile checks if the file is binary by NUL bytes in the head of the file like git.
+func IsBinaryFile(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, binaryCheckBytes)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+
+	return bytes.IndexByte(buf[:n], 0) >= 0, nil
+}
@@ -0,0 +1,115 @@
+package functions_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/clover0/issue-agent/core/functions"
+	"github.com/clover0/issue-agent/test/assert"
+)
+
+func TestWalker_Walk(t *testing.T) {
+	t.Parallel()
+
+	files := map[string]string{
+		".gitignore":              "node_modules/\n*.log\n!keep.log\n",
+		".git/info/exclude":       "local/\n",
+		".git/HEAD":               "ref: refs/heads/main\n",
+		"main.go":                 "package main\n",
+		"debug.log":               "log\n",
+		"keep.log":                "log\n",
+		"node_modules/a/index.js": "module.exports = {}\n",
+		"local/note.txt":          "note\n",
+		"vendor/lib/lib.go":       "package lib\n",
+		"src/.gitignore":          "/build\n",
+		"src/app.go":              "package src\n",
+		"src/build/out.js":        "out\n",
+	}
+
+	tests := map[string]struct {
+		setting functions.WalkerSetting
+		root    string
+		want    []string
+	}{
+		"ignore by gitignore and info exclude": {
+			root: ".",
+			want: []string{
+				".gitignore", "keep.log", "main.go",
+				"src", "src/.gitignore", "src/app.go",
+				"vendor", "vendor/lib", "vendor/lib/lib.go",
+			},
+		},
+		"extra ignore patterns": {
+			setting: functions.WalkerSetting{IgnorePatterns: []string{"vendor/"}},
+			root:    ".",
+			want: []string{
+				".gitignore", "keep.log", "main.go",
+				"src", "src/.gitignore", "src/app.go",
+			},
+		},
+		"subdirectory loads gitignore of parents": {
+			root: "src",
+			want: []string{"src/.gitignore", "src/app.go"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			for name, content := range files {
+				path := filepath.Join(dir, name)
+				assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+				assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+			}
+
+			var got []string
+			err := functions.NewWalkerInDir(tt.setting, dir).Walk(tt.root, func(path string, d fs.DirEntry) error {
+				rel, err := filepath.Rel(dir, path)
+				assert.NoError(t, err)
+				got = append(got, filepath.ToSlash(rel))
+				return nil
+			})
+			assert.NoError(t, err)
+			assert.EqualStringSlices(t, got, tt.want)
+		})
+	}
+}
+
+func TestIsBinaryFile(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		content []byte
+		want    bool
+	}{
+		"text file": {
+			content: []byte("package main\n"),
+			want:    false,
+		},
+		"empty file": {
+			content: []byte{},
+			want:    false,
+		},
+		"binary file": {
+			content: []byte{0x7f, 'E', 'L', 'F', 0x00, 0x01},
+			want:    true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "file")
+			assert.NoError(t, os.WriteFile(path, tt.content, 0644))
+
+			got, err := functions.IsBinaryFile(path)
+			assert.NoError(t, err)
+			assert.Equal(t, got, tt.want)
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	// maxLengthOverContinuations is the maximum number of the consecutive responses cut off by the max output tokens
+	maxLengthOverContinuations = 3
+
+	lengthOverContinuePrompt = "Your previous response was cut off because it reached the maximum output tokens. " +
+		"Continue the response exactly from where it was cut off, without repeating it."
+
+	lengthOverToolCallResult = "This function was not executed, because the arguments were cut off by the maximum output tokens. " +
+		"Call the function again with smaller arguments, for example, change a large file in several calls."
+)
+
+// NewLengthOverStep returns the step to continue the response cut off by the max output tokens.
+// The tool calls of the response are never executed, and their results tell LLM to call them again with smaller arguments.
+// Otherwise, LLM is asked to continue the response.
+// The cut off arguments are replaced with an empty object in the history in place,
+// and the raw message of the provider holding them is dropped,
+// so that every provider rebuilds the request from the fixed history.
+func NewLengthOverStep(history []LLMMessage) Step {
+	if lengthOverCount(history) > maxLengthOverContinuations {
+		return NewUnrecoverableStep(fmt.Errorf("responses were cut off by the max output tokens %d times in a row", maxLengthOverContinuations+1))
+	}
+
+	lastMsg := history[len(history)-1]
+	if len(lastMsg.ReturnedToolCalls) == 0 {
+		return NewReturnToLLMStep([]ReturnToLLMInput{{Content: lengthOverContinuePrompt}})
+	}
+
+	var input []ReturnToLLMInput
+	for i, v := range lastMsg.ReturnedToolCalls {
+		if !json.Valid([]byte(v.Argument)) {
+			lastMsg.ReturnedToolCalls[i].Argument = "{}"
+			history[len(history)-1].RawMessageStruct = nil
+		}
+		input = append(input, ReturnToLLMInput{
+			ToolCallerID: v.ToolCallerID,
+			ToolName:     v.ToolName,
+			Content:      lengthOverToolCallResult,
+		})
+	}
+	return NewReturnToLLMStep(input)
+}
+
+// lengthOverCount returns the number of the consecutive responses cut off by the max output tokens at the end of the history.
+func lengthOverCount(history []LLMMessage) int {
+	count := 0
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Role != LLMAssistant {
+			continue
+		}
+		if history[i].FinishReason != FinishLengthOver {
+			break
+		}
+		count++
+	}
+	return count
+}
+
+// continuedOutput joins the texts of the responses continued after being cut off by the max output tokens,
+// so that the output of the agent is not only the last part.
+func continuedOutput(history []LLMMessage, output string) string {
+	parts := []string{output}
+	for i := len(history) - 2; i >= 1; i -= 2 {
+		prompt, cutOff := history[i], history[i-1]
+		if prompt.Role != LLMUser || prompt.RawContent != lengthOverContinuePrompt ||
+			cutOff.Role != LLMAssistant || cutOff.FinishReason != FinishLengthOver {
+			break
+		}
+		parts = append([]string{cutOff.RawContent}, parts...)
+	}
+	return strings.Join(parts, "")
+}
@@ -0,0 +1,97 @@
+package core_test
+
+import (
+	"testing"
+
+	"github.com/clover0/issue-agent/core"
+	"github.com/clover0/issue-agent/test/assert"
+)
+
+func TestNewLengthOverStep(t *testing.T) {
+	t.Parallel()
+
+	cutOff := func(toolCalls ...core.ToolCall) core.LLMMessage {
+		return core.LLMMessage{
+			Role:              core.LLMAssistant,
+			RawContent:        "the plan is",
+			FinishReason:      core.FinishLengthOver,
+			ReturnedToolCalls: toolCalls,
+		}
+	}
+	user := core.LLMMessage{Role: core.LLMUser, RawContent: "fix the issue"}
+
+	t.Run("text is continued", func(t *testing.T) {
+		t.Parallel()
+
+		step := core.NewLengthOverStep([]core.LLMMessage{user, cutOff()})
+
+		assert.Equal(t, step.Do, core.ReturnToLLM)
+		assert.Equal(t, len(step.ReturnToLLMContexts), 1)
+		assert.Equal(t, step.ReturnToLLMContexts[0].ToolCallerID, "")
+	})
+
+	t.Run("tool call is not executed", func(t *testing.T) {
+		t.Parallel()
+
+		history := []core.LLMMessage{user, cutOff(core.ToolCall{
+			ToolCallerID: "call_1",
+			ToolName:     "put_file",
+			Argument:     `{"path":"main.go","content_text":"package ma`,
+		})}
+		history[1].RawMessageStruct = "raw message with the cut off argument"
+
+		step := core.NewLengthOverStep(history)
+
+		assert.Equal(t, step.Do, core.ReturnToLLM)
+		assert.Equal(t, len(step.ReturnToLLMContexts), 1)
+		assert.Equal(t, step.ReturnToLLMContexts[0].ToolCallerID, "call_1")
+		assert.Equal(t, step.ReturnToLLMContexts[0].ToolName, "put_file")
+		// the cut off argument is replaced to rebuild the request
+		assert.Equal(t, history[1].ReturnedToolCalls[0].Argument, "{}")
+		assert.Nil(t, history[1].RawMessageStruct)
+	})
+
+	t.Run("too many continuations", func(t *testing.T) {
+		t.Parallel()
+
+		continued := core.LLMMessage{Role: core.LLMUser, RawContent: "continue"}
+		history := []core.LLMMessage{user, cutOff(), continued, cutOff(), continued, cutOff(), continued, cutOff()}
+
+		step := core.NewLengthOverStep(history)
+
+		assert.Equal(t, step.Do, core.Unrecoverable)
+	})
+}
+
+func TestContinuedOutput(t *testing.T) {
+	t.Parallel()
+
+	user := core.LLMMessage{Role: core.LLMUser, RawContent: "fix the issue"}
+	first := core.LLMMessage{Role: core.LLMAssistant, RawContent: "1. open ", FinishReason: core.FinishLengthOver}
+	last := core.LLMMessage{Role: core.LLMAssistant, RawContent: "main.go", FinishReason: core.FinishStop}
+
+	step := core.NewLengthOverStep([]core.LLMMessage{user, first})
+	continuePrompt := core.LLMMessage{Role: core.LLMUser, RawContent: step.ReturnToLLMContexts[0].Content}
+
+	tests := map[string]struct {
+		history []core.LLMMessage
+		want    string
+	}{
+		"not continued": {
+			history: []core.LLMMessage{user, last},
+			want:    "main.go",
+		},
+		"continued": {
+			history: []core.LLMMessage{user, first, continuePrompt, last},
+			want:    "1. open main.go",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, core.ContinuedOutput(tt.history, "main.go"), tt.want)
+		})
+	}
+}
@@ -13,7 +13,7 @@ type StartCompletionInput struct {
 	Model           string
 	SystemPrompt    string
 	StartUserPrompt string
-	Functions       []functions.Function
+	Tools           []functions.ToolDefinition
 }
 
 type LLMForwarder interface {
@@ -27,26 +27,34 @@ type LLMForwarder interface {
 	ForwardStep(ctx context.Context, history []LLMMessage) Step
 }
 
-type SelectForwarder = func(lo logger.Logger, model string) (LLMForwarder, error)
+// SelectForwarder selects the forwarder of the model. The provider is empty when it is not specified.
+type SelectForwarder = func(lo logger.Logger, provider string, model string) (LLMForwarder, error)
 
+// LLMMessage is a provider-neutral message.
+// Forwarders must be able to rebuild the provider's request from these fields only,
+// because the message is serialized to a checkpoint without RawMessageStruct.
 type LLMMessage struct {
-	Role         MessageRole
-	RawContent   string
-	FinishReason MessageFinishReason
+	Role         MessageRole         `json:"role"`
+	RawContent   string              `json:"raw_content"`
+	FinishReason MessageFinishReason `json:"finish_reason,omitempty"`
 
 	// user to llm
-	RespondToolCall ToolCall
+	RespondToolCall ToolCall `json:"respond_tool_call"`
 
 	// llm to user
-	ReturnedToolCalls []ToolCall
+	ReturnedToolCalls []ToolCall `json:"returned_tool_calls,omitempty"`
 
 	// returned raw message struct from LLM API
-	RawMessageStruct any
+	// This is not serialized.
+	RawMessageStruct any `json:"-"`
 
 	// Usage saves LLM usage information
 	// Only the usage response from LLM response message,
 	// so Usage is stored in Message with Role = LLMAssistant or LLMTool.
-	Usage LLMUsage
+	Usage LLMUsage `json:"usage"`
+
+	// Model is the model returning the message when it is not the model of the agent, such as a fallback model.
+	Model string `json:"model,omitempty"`
 }
 
 func (l LLMMessage) ShowAssistantMessage(out logger.Logger) {
@@ -95,9 +103,9 @@ func TotalOutputTokens(messages []LLMMessage) int64 {
 }
 
 type ToolCall struct {
-	ToolCallerID string
-	ToolName     string
-	Argument     string
+	ToolCallerID string `json:"tool_caller_id"`
+	ToolName     string `json:"tool_name"`
+	Argument     string `json:"argument"`
 }
 
 type MessageRole string
@@ -118,10 +126,10 @@ const (
 )
 
 type LLMUsage struct {
-	InputToken       int64
-	OutputToken      int64
-	CacheCreateToken int64
-	CacheReadToken   int64
+	InputToken       int64 `json:"input_token"`
+	OutputToken      int64 `json:"output_token"`
+	CacheCreateToken int64 `json:"cache_create_token"`
+	CacheReadToken   int64 `json:"cache_read_token"`
 }
 
 func (l LLMUsage) TotalInputToken() int64 {
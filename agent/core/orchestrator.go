@@ -4,15 +4,14 @@ import (
 	"context"
 	"fmt"
 	"slices"
+	"strconv"
 	"strings"
 
-	"github.com/google/go-github/v73/github"
-
-	"github.com/clover0/issue-agent/agithub"
 	"github.com/clover0/issue-agent/config"
 	"github.com/clover0/issue-agent/core/functions"
 	coreprompt "github.com/clover0/issue-agent/core/prompt"
 	"github.com/clover0/issue-agent/logger"
+	"github.com/clover0/issue-agent/platform"
 	"github.com/clover0/issue-agent/util"
 )
 
@@ -21,30 +20,27 @@ import (
 // TODO: refactor many arguments
 // TODO: no dependent on issue command
 func OrchestrateAgentsByIssue(
-	_ context.Context,
+	ctx context.Context,
 	lo logger.Logger,
 	conf config.Config,
 	baseBranch string,
 	workRepository string,
-	gh *github.Client,
+	service platform.Service,
 	issueNumber string,
 	selectForward SelectForwarder,
+	priceOf PriceOf,
+	checkpoint CheckpointStore,
+	report *RunReport,
 ) error {
-	llmForwarder, err := selectForward(lo, conf.Agent.Model)
-	if err != nil {
-		return fmt.Errorf("select forwarder: %w", err)
-	}
-
 	// check if the base branch exists
-	ghService := agithub.NewGitHubService(conf.Agent.GitHub.Owner, workRepository, gh, lo)
-	if _, err = ghService.GetBranch(baseBranch); err != nil {
+	if _, err := service.GetBranch(ctx, baseBranch); err != nil {
 		return err
 	}
 
-	submitService, err := agithub.NewSubmitFileGitHubService(
-		lo, gh,
+	submitService, err := platform.NewSubmitFileService(
+		lo, service,
 		functions.SubmitFilesServiceInput{
-			GitHubOwner: conf.Agent.GitHub.Owner,
+			GitHubOwner: conf.Agent.Owner(),
 			Repository:  workRepository,
 			BaseBranch:  baseBranch,
 			GitEmail:    conf.Agent.Git.UserEmail,
@@ -55,28 +51,110 @@ func OrchestrateAgentsByIssue(
 		return fmt.Errorf("create submit file service: %w", err)
 	}
 
-	submitRevisionService := agithub.NopSubmitRevisionService{}
+	issue, err := service.GetIssue(ctx, workRepository, issueNumber)
+	if err != nil {
+		return fmt.Errorf("get issue: %w", err)
+	}
+
+	lo.Info("agents make a pull request to %s/%s\n", conf.Agent.Owner(), workRepository)
+
+	return orchestrateAgentsByIssue(ctx, lo, conf, baseBranch, issue, service, submitService,
+		conf.Agent.AllowFunctions, selectForward, priceOf, checkpoint, report)
+}
+
+// localUnsupportedFunctions are the functions calling the GitHub API, which are not available in the local run.
+var localUnsupportedFunctions = []string{
+	functions.FuncGetPullRequest,
+	functions.FuncGetIssue,
+	functions.FuncCreatePullRequestComment,
+	functions.FuncCreatePullRequestReviewComment,
+	functions.FuncGetRepositoryContent,
+	functions.FuncRequestReviewers,
+	functions.FuncSubmitRevision,
+}
 
-	parameter := Parameter{
-		MaxSteps: conf.Agent.MaxSteps,
-		Model:    conf.Agent.Model,
+// OrchestrateAgentsByLocalIssue orchestrates the planning and developer agents for the issue
+// in the local repository without GitHub.
+// The repository service and the submit files service work on the local repository.
+func OrchestrateAgentsByLocalIssue(
+	ctx context.Context,
+	lo logger.Logger,
+	conf config.Config,
+	baseBranch string,
+	issue functions.GetIssueOutput,
+	repoService functions.GitHubService,
+	submitFilesService functions.SubmitFilesService,
+	selectForward SelectForwarder,
+	priceOf PriceOf,
+	checkpoint CheckpointStore,
+	report *RunReport,
+) error {
+	allowFunctions := slices.DeleteFunc(slices.Clone(conf.Agent.AllowFunctions), func(name string) bool {
+		return slices.Contains(localUnsupportedFunctions, name)
+	})
+
+	lo.Info("agents commit to a local branch based on %s\n", baseBranch)
+
+	return orchestrateAgentsByIssue(ctx, lo, conf, baseBranch, issue, repoService, submitFilesService,
+		allowFunctions, selectForward, priceOf, checkpoint, report)
+}
+
+// orchestrateAgentsByIssue runs the planning agent and the developer agent for the issue.
+func orchestrateAgentsByIssue(
+	ctx context.Context,
+	lo logger.Logger,
+	conf config.Config,
+	baseBranch string,
+	issue functions.GetIssueOutput,
+	repoService functions.GitHubService,
+	submitFilesService functions.SubmitFilesService,
+	allowFunctions []string,
+	selectForward SelectForwarder,
+	priceOf PriceOf,
+	checkpoint CheckpointStore,
+	report *RunReport,
+) error {
+	planningForwarder, planningParameter, err := selectAgentForwarder(lo, selectForward, conf.Agents.Planning.WithDefaults(conf.Agent), conf.Agent)
+	if err != nil {
+		return err
+	}
+	developerForwarder, developerParameter, err := selectAgentForwarder(lo, selectForward, conf.Agents.Developer.WithDefaults(conf.Agent), conf.Agent)
+	if err != nil {
+		return err
+	}
+	subAgentForwarder, subAgentParameter, err := selectAgentForwarder(lo, selectForward, conf.Agents.SubAgent.WithDefaults(conf.Agent), conf.Agent)
+	if err != nil {
+		return err
 	}
 
+	meter := NewUsageMeterByConfig(lo, conf, priceOf)
+	defer func() {
+		report.SetUsage(meter.Total())
+		lo.Info("usage of agents:\n%s", meter.Summary())
+	}()
+
+	submitService := reportingSubmitFilesService{SubmitFilesService: submitFilesService, report: report}
+
+	submitRevisionService := platform.NopSubmitRevisionService{}
+
 	functions.InitializeFunctions(
-		ghService,
+		repoService,
 		submitService,
 		submitRevisionService,
-		conf.Agent.AllowFunctions,
+		allowFunctions,
+		FunctionSetting(conf),
 	)
 
 	tools := functions.AllFunctions()
 	functions.InitializeInvokeAgentFunction(
-		conf.Agent.AllowFunctions,
+		allowFunctions,
 		NewAgentInvoker(
-			parameter,
+			subAgentParameter,
 			lo,
-			llmForwarder,
+			subAgentForwarder,
 			tools,
+			meter,
+			report,
 		))
 
 	tools = functions.AllFunctions()
@@ -84,12 +162,6 @@ func OrchestrateAgentsByIssue(
 		tools,
 		func(e functions.Function) string { return e.Name.String() },
 	), ","))
-	lo.Info("agents make a pull request to %s/%s\n", conf.Agent.GitHub.Owner, workRepository)
-
-	issue, err := ghService.GetIssue(workRepository, issueNumber)
-	if err != nil {
-		return fmt.Errorf("get issue: %w", err)
-	}
 
 	prompt, err := coreprompt.Planning{
 		Language:     conf.Language,
@@ -101,8 +173,8 @@ func OrchestrateAgentsByIssue(
 	if err != nil {
 		return fmt.Errorf("orchestrator builds planning prompt: %w", err)
 	}
-	planningAgent, err := RunAgent("planningAgent",
-		prompt, parameter, lo, llmForwarder, PlanTools())
+	planningAgent, err := RunAgent(ctx, "planningAgent",
+		prompt, planningParameter, lo, planningForwarder, PlanTools(), checkpoint, meter, report)
 	if err != nil {
 		return err
 	}
@@ -115,12 +187,13 @@ func OrchestrateAgentsByIssue(
 		IssueContent: issue.Content,
 		IssueNumber:  issue.Path,
 		Instruction:  instruction,
+		Commands:     allowedCommands(conf),
 	}.Build()
 	if err != nil {
 		return fmt.Errorf("orchestrator builds developer prompt: %w", err)
 	}
 
-	if _, err := RunAgent("developerAgent", prompt, parameter, lo, llmForwarder, tools); err != nil {
+	if _, err := RunAgent(ctx, "developerAgent", prompt, developerParameter, lo, developerForwarder, tools, checkpoint, meter, report); err != nil {
 		return fmt.Errorf("orchestrator developer agent: %w", err)
 	}
 
@@ -130,25 +203,41 @@ func OrchestrateAgentsByIssue(
 }
 
 func OrchestrateAgentsByComment(
+	ctx context.Context,
 	lo logger.Logger,
 	conf config.Config,
 	workRepository string,
-	gh *github.Client,
+	service platform.Service,
 	selectForward SelectForwarder,
+	priceOf PriceOf,
 	comment functions.GetCommentOutput,
 	pr functions.GetPullRequestOutput,
+	checkpoint CheckpointStore,
+	report *RunReport,
 ) error {
-	llmForwarder, err := selectForward(lo, conf.Agent.Model)
+	reactorForwarder, reactorParameter, err := selectAgentForwarder(lo, selectForward, conf.Agents.CommentReactor.WithDefaults(conf.Agent), conf.Agent)
+	if err != nil {
+		return err
+	}
+	subAgentForwarder, subAgentParameter, err := selectAgentForwarder(lo, selectForward, conf.Agents.SubAgent.WithDefaults(conf.Agent), conf.Agent)
 	if err != nil {
-		return fmt.Errorf("select forwarder: %w", err)
+		return err
 	}
 
-	ghService := agithub.NewGitHubService(conf.Agent.GitHub.Owner, workRepository, gh, lo)
+	meter := NewUsageMeterByConfig(lo, conf, priceOf)
+	defer func() {
+		report.SetUsage(meter.Total())
+		lo.Info("usage of agents:\n%s", meter.Summary())
+	}()
 
-	submitFilesService := agithub.NopSubmitFileService{}
-	submitRevisionService, err := agithub.NewSubmitRevisionGitHubService(lo, gh,
+	if number, err := strconv.Atoi(pr.PRNumber); err == nil {
+		report.SetPullRequest(number, pr.Head)
+	}
+
+	submitFilesService := platform.NopSubmitFileService{}
+	submitRevisionService, err := platform.NewSubmitRevisionService(lo, service,
 		functions.SubmitRevisionServiceInput{
-			GitHubOwner: conf.Agent.GitHub.Owner,
+			GitHubOwner: conf.Agent.Owner(),
 			Repository:  workRepository,
 			BaseBranch:  pr.Base,
 			WorkBranch:  pr.Head,
@@ -159,25 +248,23 @@ func OrchestrateAgentsByComment(
 		return fmt.Errorf("create submit revision service: %w", err)
 	}
 
-	parameter := Parameter{
-		MaxSteps: conf.Agent.MaxSteps,
-		Model:    conf.Agent.Model,
-	}
-
 	functions.InitializeFunctions(
-		ghService,
+		service,
 		submitFilesService,
 		submitRevisionService,
 		conf.Agent.AllowFunctions,
+		FunctionSetting(conf),
 	)
 
 	functions.InitializeInvokeAgentFunction(
 		conf.Agent.AllowFunctions,
 		NewAgentInvoker(
-			parameter,
+			subAgentParameter,
 			lo,
-			llmForwarder,
+			subAgentForwarder,
 			ReactTools(),
+			meter,
+			report,
 		))
 
 	tools := slices.Concat(ReactTools(), InvokeAgentTools())
@@ -186,7 +273,7 @@ func OrchestrateAgentsByComment(
 		tools,
 		func(e functions.Function) string { return e.Name.String() },
 	), ","))
-	lo.Info("agents will push to %s/%s branch %s\n", conf.Agent.GitHub.Owner, workRepository, pr.Head)
+	lo.Info("agents will push to %s/%s branch %s\n", conf.Agent.Owner(), workRepository, pr.Head)
 
 	prompt, err := coreprompt.CommentReactor{
 		Language:      conf.Language,
@@ -194,15 +281,16 @@ func OrchestrateAgentsByComment(
 		PRNumber:      pr.PRNumber,
 		Comment:       comment.Content,
 		PRLLMString:   pr.ToLLMString(),
+		Commands:      allowedCommands(conf),
 	}.Build()
 	if err != nil {
 		lo.Error("orchestrator builds comment reactor prompt: %s\n", err)
 		return err
 	}
 
-	_, err = RunAgent("commentReactorAgent",
-		prompt, parameter, lo, llmForwarder,
-		tools,
+	_, err = RunAgent(ctx, "commentReactorAgent",
+		prompt, reactorParameter, lo, reactorForwarder,
+		tools, checkpoint, meter, report,
 	)
 	if err != nil {
 		return fmt.Errorf("orchestrator comment reactor agent: %w", err)
@@ -212,13 +300,53 @@ func OrchestrateAgentsByComment(
 	return nil
 }
 
+// selectAgentForwarder selects the forwarder and creates the parameter of an agent by the setting.
+// The forwarder falls back to the fallback models in order when the model fails,
+// and compacts the history when the context exceeds the threshold.
+func selectAgentForwarder(
+	lo logger.Logger,
+	selectForward SelectForwarder,
+	setting config.AgentSetting,
+	agent config.Agent,
+) (LLMForwarder, Parameter, error) {
+	forwarder, err := selectForward(lo, setting.Provider, setting.Model)
+	if err != nil {
+		return nil, Parameter{}, fmt.Errorf("select forwarder of %s: %w", setting.Model, err)
+	}
+
+	if len(agent.Fallbacks) > 0 {
+		forwarders := []ModelForwarder{{Model: setting.Model, Forwarder: forwarder}}
+		for _, fb := range agent.Fallbacks {
+			f, err := selectForward(lo, fb.Provider, fb.Model)
+			if err != nil {
+				return nil, Parameter{}, fmt.Errorf("select forwarder of fallback %s: %w", fb.Model, err)
+			}
+			forwarders = append(forwarders, ModelForwarder{Model: fb.Model, Forwarder: f})
+		}
+		forwarder = NewFallbackForwarder(lo, forwarders)
+	}
+
+	if agent.ContextWindow.CompactThresholdTokens > 0 {
+		forwarder = NewContextWindowForwarder(lo, forwarder, agent.ContextWindow)
+	}
+
+	return forwarder, Parameter{
+		MaxSteps: setting.MaxSteps,
+		Model:    setting.Model,
+	}, nil
+}
+
 func RunAgent(
+	ctx context.Context,
 	name string,
 	prompt coreprompt.Prompt,
 	parameter Parameter,
 	lo logger.Logger,
 	llmForwarder LLMForwarder,
 	tools []functions.Function,
+	checkpoint CheckpointStore,
+	meter *UsageMeter,
+	report *RunReport,
 ) (AgentLike, error) {
 	ag := NewAgent(
 		parameter,
@@ -227,11 +355,105 @@ func RunAgent(
 		prompt,
 		llmForwarder,
 		tools,
+		checkpoint,
+		meter,
+		report,
 	)
 
-	if _, err := ag.Work(); err != nil {
+	if _, err := ag.Work(ctx); err != nil {
 		return &Agent{}, fmt.Errorf("agent %s failed: %w", name, err)
 	}
 
 	return ag, nil
 }
+
+// reportingSubmitFilesService reports the pull request created by the agent.
+type reportingSubmitFilesService struct {
+	functions.SubmitFilesService
+	report *RunReport
+}
+
+func (s reportingSubmitFilesService) SubmitFiles(ctx context.Context, input functions.SubmitFilesInput) (functions.SubmitFilesOutput, error) {
+	out, err := s.SubmitFilesService.SubmitFiles(ctx, input)
+	if err == nil {
+		s.report.SetPullRequest(out.PullRequestNumber, out.PushedBranch)
+	}
+	return out, err
+}
+
+// FunctionSetting converts the configuration to the setting of functions.
+func FunctionSetting(conf config.Config) functions.Setting {
+	var commands []functions.AllowedCommand
+	for _, c := range conf.Agent.Functions.RunCommand.Commands {
+		commands = append(commands, functions.AllowedCommand{
+			Command: c.Command,
+			Timeout: c.Timeout,
+		})
+	}
+
+	return functions.Setting{
+		Walker: functions.WalkerSetting{
+			IgnorePatterns: conf.Agent.Functions.IgnorePatterns,
+		},
+		OpenFile: functions.OpenFileSetting{
+			MaxBytes: conf.Agent.Functions.OpenFile.MaxBytes,
+		},
+		RunCommand: functions.RunCommandSetting{
+			Commands:       commands,
+			MaxOutputBytes: conf.Agent.Functions.RunCommand.MaxOutputBytes,
+		},
+	}
+}
+
+// NewUsageMeterByConfig creates the meter with the budgets in the configuration.
+func NewUsageMeterByConfig(lo logger.Logger, conf config.Config, priceOf PriceOf) *UsageMeter {
+	toBudget := func(l config.Limit) Budget {
+		return Budget{
+			MaxInputTokens:  l.MaxInputTokens,
+			MaxOutputTokens: l.MaxOutputTokens,
+			MaxCostUSD:      l.MaxCostUSD,
+		}
+	}
+	budget := conf.Agent.Budget
+
+	if budget.Agent.MaxCostUSD > 0 || budget.Run.MaxCostUSD > 0 {
+		for _, model := range agentModels(conf) {
+			if _, ok := priceOf(model); !ok {
+				lo.Error("the price of %s is unknown, so the cost budget is not enforced\n", model)
+			}
+		}
+	}
+
+	return NewUsageMeter(priceOf, toBudget(budget.Agent), toBudget(budget.Run))
+}
+
+// agentModels returns the models used by the agent roles and the fallbacks without duplicates.
+func agentModels(conf config.Config) []string {
+	var models []string
+	for _, s := range []config.AgentSetting{
+		conf.Agents.Planning,
+		conf.Agents.Developer,
+		conf.Agents.SubAgent,
+		conf.Agents.CommentReactor,
+	} {
+		if model := s.WithDefaults(conf.Agent).Model; !slices.Contains(models, model) {
+			models = append(models, model)
+		}
+	}
+	for _, fb := range conf.Agent.Fallbacks {
+		if !slices.Contains(models, fb.Model) {
+			models = append(models, fb.Model)
+		}
+	}
+	return models
+}
+
+// allowedCommands returns the commands that agents can run with run_command function.
+func allowedCommands(conf config.Config) []string {
+	if !slices.Contains(conf.Agent.AllowFunctions, functions.FuncRunCommand) {
+		return nil
+	}
+
+	return util.Map(conf.Agent.Functions.RunCommand.Commands,
+		func(c config.AllowedCommand) string { return c.Command })
+}
@@ -6,6 +6,7 @@ type CommentReactor struct {
 	PRNumber      string
 	Comment       string
 	PRLLMString   string
+	Commands      []string
 }
 
 func (p CommentReactor) SystemPromptTemplate() string {
@@ -22,8 +23,15 @@ User instructs you to accomplish the task with GitHub comment on a Pull Request.
 
 <constraints>
 * Communicate entirely in {{.Language}}.
+{{- if .Commands}}
+* You cannot run the shell. Only the following commands can be run with run_command tool.
+{{- range .Commands}}
+  * {{.}}
+{{- end}}
+{{- else}}
 * You are in an environment where you cannot execute arbitrary commands, so you cannot run the shell. Only tool use can be used.
-* Handling files with huge sizes is inefficient, so you can only open files that are less than 15,000 bytes.
+{{- end}}
+* Handling files with huge sizes is inefficient, so open large files in ranges of lines with start_line and end_line of open_file.
 * You can't write new comments in the code. However, you can preserve existing comments.
 </constraints>
 
@@ -36,7 +44,11 @@ User instructs you to accomplish the task with GitHub comment on a Pull Request.
 * Always consider the context of the code you are editing. The code to which you make changes must be consistent with the existing codebase.
 * Use only the standard library of the programming language or use only libraries used in the repository.
 * When creating a new implementation, check carefully if it exists in any other directories.
+{{- if .Commands}}
+* Run the commands with run_command tool to check the code you have changed, and fix it until the commands succeed.
+{{- else}}
 * Plan and run a check to see how the code you have changed works correctly without linting or compile, and fix it.
+{{- end}}
 </important-rules>
 `
 }
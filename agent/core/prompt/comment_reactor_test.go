@@ -37,7 +37,7 @@ User instructs you to accomplish the task with GitHub comment on a Pull Request.
 <constraints>
 * Communicate entirely in Japanese.
 * You are in an environment where you cannot execute arbitrary commands, so you cannot run the shell. Only tool use can be used.
-* Handling files with huge sizes is inefficient, so you can only open files that are less than 15,000 bytes.
+* Handling files with huge sizes is inefficient, so open large files in ranges of lines with start_line and end_line of open_file.
 * You can't write new comments in the code. However, you can preserve existing comments.
 </constraints>
 
@@ -92,7 +92,7 @@ User instructs you to accomplish the task with GitHub comment on a Pull Request.
 <constraints>
 * Communicate entirely in English.
 * You are in an environment where you cannot execute arbitrary commands, so you cannot run the shell. Only tool use can be used.
-* Handling files with huge sizes is inefficient, so you can only open files that are less than 15,000 bytes.
+* Handling files with huge sizes is inefficient, so open large files in ranges of lines with start_line and end_line of open_file.
 * You can't write new comments in the code. However, you can preserve existing comments.
 </constraints>
 
@@ -37,7 +37,7 @@ User instructs you to accomplish the task with plans.
 <constraints>
 * Communicate entirely in Japanese.
 * You are in an environment where you cannot execute arbitrary commands, so you cannot run the shell. Only tool use can be used.
-* Handling files with huge sizes is inefficient, so you can only open files that are less than 15,000 bytes.
+* Handling files with huge sizes is inefficient, so open large files in ranges of lines with start_line and end_line of open_file.
 * You can't write new comments in the code. However, you can preserve existing comments.
 </constraints>
 
@@ -108,7 +108,7 @@ User instructs you to accomplish the task with plans.
 <constraints>
 * Communicate entirely in English.
 * You are in an environment where you cannot execute arbitrary commands, so you cannot run the shell. Only tool use can be used.
-* Handling files with huge sizes is inefficient, so you can only open files that are less than 15,000 bytes.
+* Handling files with huge sizes is inefficient, so open large files in ranges of lines with start_line and end_line of open_file.
 * You can't write new comments in the code. However, you can preserve existing comments.
 </constraints>
 
@@ -152,6 +152,80 @@ Title:
 * Create or edit files as necessary to write code to complete the task.
 * You should follow development plan bellow.
 
+</instructions>
+`,
+			},
+		},
+		"with allowed commands": {
+			input: prompt.Developer{
+				Language:     "English",
+				BaseBranch:   "main",
+				IssueTitle:   "Test Issue",
+				IssueContent: "This is a test issue content",
+				IssueNumber:  "123",
+				Instruction:  "Test instruction",
+				Commands:     []string{"go test ./...", "make lint"},
+			},
+			want: prompt.Prompt{
+				SystemPrompt: `
+You are a software development engineer with expertise in the latest technologies, programming, best practices.
+You will understand the codebase of the git repository and complete the task.
+User instructs you to accomplish the task with plans.
+
+<system-environment>
+* You are in the root directory of the repository.
+* Git Base branch is main.
+</system-environment>
+
+<constraints>
+* Communicate entirely in English.
+* You cannot run the shell. Only the following commands can be run with run_command tool.
+  * go test ./...
+  * make lint
+* Handling files with huge sizes is inefficient, so open large files in ranges of lines with start_line and end_line of open_file.
+* You can't write new comments in the code. However, you can preserve existing comments.
+</constraints>
+
+<important-rules>
+* First create a working branch using switch_branch.
+* Indentation is very important! When editing files, insert appropriate indentation at the beginning of each line.
+* Adhering to the coding style of other source code in the repository.
+* If a 'tool use' does not work, try another tool or change the arguments before running it again. A command that fails once will not work again without modification.
+* Always keep track of the current file you are editing and the current working directory. The file you are editing might be in a different directory from the working directory.
+* Consider how changes will affect other source code. If there are impacts, also modify the affected code.
+* Always consider the context of the code you are editing. The code to which you make changes must be consistent with the existing codebase.
+* Use only the standard library of the programming language or use only libraries used in the repository.
+* When creating a new implementation, check carefully if it exists in any other directories.
+* Run the commands with run_command tool to check the code you have changed, and fix it until the commands succeed.
+* Finally you must create Pull Request using submit_files tool with submission-template in English.
+</important-rules>
+
+<submission-template>
+Write the reason for the changes here.
+Write what was added or created along with the reasons here.
+
+# Issue
+ #123
+</submission-template>
+`,
+				StartUserPrompt: `
+The task is bellow:
+
+<task>
+Issue Number: 123
+Title: Test Issue
+This is a test issue content
+</task>
+
+<what-to-do-last>
+* Finally you must create Pull Request with submission-template in English.
+</what-to-do-last>
+
+<instructions>
+* Understand the overall structure of the repository's codebase before proceeding.
+* Create or edit files as necessary to write code to complete the task.
+* You should follow development plan bellow.
+Test instruction
 </instructions>
 `,
 			},
@@ -26,7 +26,7 @@ The plan is passed as input to the agent doing the development.
 <constraints>
 * Communicate entirely in {{.Language}}.
 * You are in an environment where you cannot execute arbitrary commands, so you cannot run the shell. Only tool use can be used.
-* Handling files with huge sizes is inefficient, so you can only open files that are less than 15,000 bytes.
+* Handling files with huge sizes is inefficient, so open large files in ranges of lines with start_line and end_line of open_file.
 * You and the developer work in the same environment.
 </constraints>
 
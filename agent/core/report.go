@@ -0,0 +1,211 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type ExitReason string
+
+const (
+	ExitCompleted      ExitReason = "completed"
+	ExitMaxSteps       ExitReason = "max_steps"
+	ExitBudgetExceeded ExitReason = "budget_exceeded"
+	ExitError          ExitReason = "error"
+)
+
+func exitReasonOf(err error) ExitReason {
+	switch {
+	case err == nil:
+		return ExitCompleted
+	case errors.Is(err, ErrBudgetExceeded):
+		return ExitBudgetExceeded
+	default:
+		return ExitError
+	}
+}
+
+// RunReport is the machine-readable report of a run of the command.
+// It is written to a JSON file to be archived by CI.
+type RunReport struct {
+	mu sync.Mutex
+
+	Command     string             `json:"command"`
+	StartedAt   time.Time          `json:"started_at"`
+	FinishedAt  time.Time          `json:"finished_at"`
+	ExitReason  ExitReason         `json:"exit_reason"`
+	Error       string             `json:"error,omitempty"`
+	PullRequest *PullRequestReport `json:"pull_request,omitempty"`
+	Usage       Usage              `json:"usage"`
+	Agents      []*AgentReport     `json:"agents"`
+}
+
+type PullRequestReport struct {
+	Number int    `json:"number"`
+	Branch string `json:"branch"`
+}
+
+// AgentReport is the report of an agent. Sub agents invoked by invoke_agent are reported as well.
+type AgentReport struct {
+	Name       string       `json:"name"`
+	Model      string       `json:"model"`
+	Resumed    bool         `json:"resumed,omitempty"`
+	StartedAt  time.Time    `json:"started_at"`
+	FinishedAt time.Time    `json:"finished_at"`
+	ExitReason ExitReason   `json:"exit_reason"`
+	Error      string       `json:"error,omitempty"`
+	Usage      Usage        `json:"usage"`
+	Steps      []StepReport `json:"steps"`
+}
+
+type StepReport struct {
+	Step       int            `json:"step"`
+	Do         DoType         `json:"do"`
+	StartedAt  time.Time      `json:"started_at"`
+	DurationMS int64          `json:"duration_ms"`
+	Message    *MessageReport `json:"message,omitempty"`
+	Tools      []ToolReport   `json:"tools,omitempty"`
+}
+
+// MessageReport is the message returned from LLM.
+type MessageReport struct {
+	FinishReason MessageFinishReason `json:"finish_reason"`
+	ToolCalls    []string            `json:"tool_calls,omitempty"`
+	Usage        LLMUsage            `json:"usage"`
+}
+
+type ToolReport struct {
+	ToolCallerID string `json:"tool_caller_id"`
+	Name         string `json:"name"`
+	Args         string `json:"args"`
+	ResultBytes  int    `json:"result_bytes"`
+	DurationMS   int64  `json:"duration_ms"`
+	Error        string `json:"error,omitempty"`
+}
+
+func NewRunReport(command string) *RunReport {
+	return &RunReport{
+		Command:   command,
+		StartedAt: time.Now(),
+		Agents:    []*AgentReport{},
+	}
+}
+
+// StartAgent adds the report of the agent starting work.
+func (r *RunReport) StartAgent(name string, model string) *AgentReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	a := &AgentReport{
+		Name:      name,
+		Model:     model,
+		StartedAt: time.Now(),
+		Steps:     []StepReport{},
+	}
+	r.Agents = append(r.Agents, a)
+
+	return a
+}
+
+func (r *RunReport) SetPullRequest(number int, branch string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.PullRequest = &PullRequestReport{Number: number, Branch: branch}
+}
+
+func (r *RunReport) SetUsage(usage Usage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Usage = usage
+}
+
+// Finish sets the exit reason by the error of the run.
+func (r *RunReport) Finish(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.FinishedAt = time.Now()
+	r.ExitReason = exitReasonOf(err)
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
+
+// Save writes the report to the JSON file.
+func (r *RunReport) Save(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal report: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create report directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+
+	return nil
+}
+
+func (a *AgentReport) addStep(step StepReport) {
+	a.Steps = append(a.Steps, step)
+}
+
+func (a *AgentReport) addUsage(u Usage) {
+	a.Usage = a.Usage.add(u)
+}
+
+// finish sets the exit reason by the error of the agent, unless the reason is already set.
+func (a *AgentReport) finish(err error) {
+	a.FinishedAt = time.Now()
+	if a.ExitReason == "" {
+		a.ExitReason = exitReasonOf(err)
+	}
+	if err != nil {
+		a.Error = err.Error()
+	}
+}
+
+func newMessageReport(msg LLMMessage) *MessageReport {
+	r := &MessageReport{
+		FinishReason: msg.FinishReason,
+		Usage:        msg.Usage,
+	}
+	for _, t := range msg.ReturnedToolCalls {
+		r.ToolCalls = append(r.ToolCalls, t.ToolName)
+	}
+	return r
+}
+
+func newExecStepReport(steps int, started time.Time, fnCtxs []FunctionContext, results []functionResult) StepReport {
+	step := StepReport{
+		Step:       steps,
+		Do:         Exec,
+		StartedAt:  started,
+		DurationMS: time.Since(started).Milliseconds(),
+	}
+	for i, r := range results {
+		tool := ToolReport{
+			ToolCallerID: r.ToolCallerID,
+			Name:         fnCtxs[i].Function.Name.String(),
+			Args:         fnCtxs[i].FunctionArgs.String(),
+			ResultBytes:  len(r.Content),
+			DurationMS:   r.Duration.Milliseconds(),
+		}
+		if r.Err != nil {
+			tool.Error = r.Err.Error()
+		}
+		step.Tools = append(step.Tools, tool)
+	}
+	return step
+}
@@ -0,0 +1,144 @@
+package core_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/clover0/issue-agent/core"
+	"github.com/clover0/issue-agent/core/functions"
+	"github.com/clover0/issue-agent/core/prompt"
+	"github.com/clover0/issue-agent/test/assert"
+	"github.com/clover0/issue-agent/test/loggertest"
+)
+
+// scriptedForwarder returns the steps in order for each response.
+type scriptedForwarder struct {
+	steps []core.Step
+	calls int
+}
+
+func (f *scriptedForwarder) StartForward(_ core.StartCompletionInput) ([]core.LLMMessage, error) {
+	return f.respond(nil), nil
+}
+
+func (f *scriptedForwarder) ForwardLLM(
+	_ context.Context, _ core.StartCompletionInput, _ []core.ReturnToLLMContext, history []core.LLMMessage,
+) ([]core.LLMMessage, error) {
+	return f.respond(history), nil
+}
+
+func (f *scriptedForwarder) respond(history []core.LLMMessage) []core.LLMMessage {
+	f.calls++
+	return append(history, core.LLMMessage{
+		Role:         core.LLMAssistant,
+		FinishReason: core.FinishToolCalls,
+		Usage:        core.LLMUsage{InputToken: 1000, OutputToken: 100},
+	})
+}
+
+func (f *scriptedForwarder) ForwardStep(_ context.Context, _ []core.LLMMessage) core.Step {
+	return f.steps[f.calls-1]
+}
+
+func TestRunReport(t *testing.T) {
+	t.Parallel()
+
+	functions.Register(functions.NewReadOnlyFunction("test_report_echo", "echo",
+		func(_ context.Context, input struct {
+			Text string `json:"text"`
+		}) (string, error) {
+			if input.Text == "" {
+				return "", errors.New("text is empty")
+			}
+			return input.Text, nil
+		}))
+
+	tests := map[string]struct {
+		steps          []core.Step
+		meter          *core.UsageMeter
+		wantErr        bool
+		wantExitReason core.ExitReason
+		wantSteps      int
+	}{
+		"completed": {
+			steps: []core.Step{
+				core.NewExecStep([]core.FunctionsInput{
+					{FuncName: "test_report_echo", FunctionArgs: `{"text": "hello"}`, ToolCallerID: "1"},
+					{FuncName: "test_report_echo", FunctionArgs: `{}`, ToolCallerID: "2"},
+				}),
+				core.NewWaitingInstructionStep("done"),
+			},
+			meter:          core.NewUsageMeter(nil, core.Budget{}, core.Budget{}),
+			wantExitReason: core.ExitCompleted,
+			// start, exec, return to llm
+			wantSteps: 3,
+		},
+		"budget exceeded": {
+			steps: []core.Step{
+				core.NewExecStep([]core.FunctionsInput{
+					{FuncName: "test_report_echo", FunctionArgs: `{"text": "hello"}`, ToolCallerID: "1"},
+				}),
+				core.NewWaitingInstructionStep("done"),
+			},
+			meter:          core.NewUsageMeter(nil, core.Budget{}, core.Budget{MaxInputTokens: 1500}),
+			wantErr:        true,
+			wantExitReason: core.ExitBudgetExceeded,
+			wantSteps:      3,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			report := core.NewRunReport("test")
+			agent := core.NewAgent(
+				core.Parameter{MaxSteps: 10, Model: "test-model"},
+				"testAgent",
+				loggertest.NewTestLogger(),
+				prompt.Prompt{},
+				&scriptedForwarder{steps: tt.steps},
+				nil,
+				core.NopCheckpointStore{},
+				tt.meter,
+				report,
+			)
+
+			_, err := agent.Work(t.Context())
+			if tt.wantErr {
+				assert.HasError(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+			report.SetUsage(tt.meter.Total())
+			report.Finish(err)
+
+			path := filepath.Join(t.TempDir(), "reports", "report.json")
+			assert.Nil(t, report.Save(path))
+			data, err := os.ReadFile(path)
+			assert.Nil(t, err)
+			var got core.RunReport
+			assert.Nil(t, json.Unmarshal(data, &got))
+
+			assert.Equal(t, got.ExitReason, tt.wantExitReason)
+			assert.Equal(t, len(got.Agents), 1)
+			ag := got.Agents[0]
+			assert.Equal(t, ag.Name, "testAgent")
+			assert.Equal(t, ag.ExitReason, tt.wantExitReason)
+			assert.Equal(t, len(ag.Steps), tt.wantSteps)
+			assert.Equal(t, ag.Usage.InputTokens, got.Usage.InputTokens)
+
+			exec := ag.Steps[1]
+			assert.Equal(t, exec.Do, core.Exec)
+			assert.Equal(t, exec.Tools[0].Name, "test_report_echo")
+			assert.Equal(t, exec.Tools[0].ResultBytes, len("hello"))
+			if len(exec.Tools) > 1 {
+				assert.Equal(t, exec.Tools[1].Error, "text is empty")
+			}
+		})
+	}
+}
@@ -35,21 +35,21 @@ type Step struct {
 }
 
 type ReturnToLLMContext struct {
-	ToolCallerID string // TODO: non OpenAI dependency
-	ToolName     string
-	Content      string
+	ToolCallerID string `json:"tool_caller_id"`
+	ToolName     string `json:"tool_name"`
+	Content      string `json:"content"`
 }
 
 type FunctionContext struct {
 	Function     functions.Function
 	FunctionArgs JSONString
-	ToolCallerID string // TODO: non OpenAI dependency
+	ToolCallerID string
 }
 
 type FunctionsInput struct {
-	FuncName     string
-	FunctionArgs string
-	ToolCallerID string // TODO: non OpenAI dependency
+	FuncName     string `json:"func_name"`
+	FunctionArgs string `json:"function_args"`
+	ToolCallerID string `json:"tool_caller_id"`
 }
 
 func NewExecStep(fnsInput []FunctionsInput) Step {
@@ -91,7 +91,7 @@ func NewUnrecoverableStep(err error) Step {
 }
 
 type ReturnToLLMInput struct {
-	ToolCallerID string // TODO: non OpenAI dependency
+	ToolCallerID string
 	ToolName     string
 	Content      string
 }
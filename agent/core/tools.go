@@ -7,20 +7,29 @@ import (
 func PlanTools() []functions.Function {
 	m := functions.FunctionsMap()
 
-	return []functions.Function{
-		m[functions.FuncOpenFile],
-		m[functions.FuncListFiles],
-		m[functions.FuncSearchFiles],
-		m[functions.FuncGetPullRequest],
-		m[functions.FuncGetIssue],
-		m[functions.FuncGetRepositoryContent],
+	var tools []functions.Function
+	// the functions not allowed in the configuration or not available in the run, such as the local run, are skipped
+	for _, name := range []string{
+		functions.FuncOpenFile,
+		functions.FuncListFiles,
+		functions.FuncSearchFiles,
+		functions.FuncGetPullRequest,
+		functions.FuncGetIssue,
+		functions.FuncGetRepositoryContent,
+		functions.FuncGrepFiles,
+	} {
+		if f, ok := m[name]; ok {
+			tools = append(tools, f)
+		}
 	}
+
+	return tools
 }
 
 func ReactTools() []functions.Function {
 	m := functions.FunctionsMap()
 
-	return []functions.Function{
+	tools := []functions.Function{
 		m[functions.FuncOpenFile],
 		m[functions.FuncPutFile],
 		m[functions.FuncListFiles],
@@ -33,6 +42,14 @@ func ReactTools() []functions.Function {
 		m[functions.FuncCreatePullRequestReviewComment],
 		m[functions.FuncGetRepositoryContent],
 	}
+	// optional functions are used only when they are allowed in the configuration
+	for _, name := range []string{functions.FuncReplaceInFile, functions.FuncGrepFiles, functions.FuncRunCommand} {
+		if f, ok := m[name]; ok {
+			tools = append(tools, f)
+		}
+	}
+
+	return tools
 }
 
 func InvokeAgentTools() []functions.Function {
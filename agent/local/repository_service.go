@@ -0,0 +1,62 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/clover0/issue-agent/core/functions"
+)
+
+var errNotAvailable = errors.New("not available in the local run without GitHub")
+
+// RepositoryService implements functions.GitHubService for the local run.
+// Only the issue given to the run is available, and the other operations return an error.
+type RepositoryService struct {
+	issue functions.GetIssueOutput
+}
+
+func NewRepositoryService(issue functions.GetIssueOutput) RepositoryService {
+	return RepositoryService{issue: issue}
+}
+
+func (s RepositoryService) GetIssue(_ context.Context, _ string, _ string) (functions.GetIssueOutput, error) {
+	return s.issue, nil
+}
+
+func (s RepositoryService) GetPullRequest(_ context.Context, _ string) (functions.GetPullRequestOutput, error) {
+	return functions.GetPullRequestOutput{}, fmt.Errorf("get pull request: %w", errNotAvailable)
+}
+
+func (s RepositoryService) GetRepositoryContent(_ context.Context, _ functions.GetRepositoryContentInput) (functions.GetRepositoryContentOutput, error) {
+	return functions.GetRepositoryContentOutput{}, fmt.Errorf("get repository content: %w", errNotAvailable)
+}
+
+func (s RepositoryService) CreateIssueComment(_ context.Context, _ string, _ string) (functions.CreateIssueCommentOutput, error) {
+	return functions.CreateIssueCommentOutput{}, fmt.Errorf("create issue comment: %w", errNotAvailable)
+}
+
+func (s RepositoryService) CreateReviewCommentOne(_ context.Context, _ functions.CreatePullRequestReviewCommentInput) (functions.CreatePullRequestReviewCommentOutput, error) {
+	return functions.CreatePullRequestReviewCommentOutput{}, fmt.Errorf("create review comment: %w", errNotAvailable)
+}
+
+func (s RepositoryService) RequestReviewers(_ context.Context, _ int, _ []string, _ []string) (functions.RequestReviewersOutput, error) {
+	return functions.RequestReviewersOutput{}, fmt.Errorf("request reviewers: %w", errNotAvailable)
+}
+
+// ParseIssue parses the issue text or markdown.
+// The title is the first non-empty line without the heading marks, and the content is the whole text.
+func ParseIssue(text string) (functions.GetIssueOutput, error) {
+	for _, line := range strings.Split(text, "\n") {
+		title := strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#"))
+		if title != "" {
+			return functions.GetIssueOutput{
+				Title:   title,
+				Content: strings.TrimSpace(text),
+			}, nil
+		}
+	}
+
+	return functions.GetIssueOutput{}, fmt.Errorf("issue is empty")
+}
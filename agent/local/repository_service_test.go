@@ -0,0 +1,52 @@
+package local_test
+
+import (
+	"testing"
+
+	"github.com/clover0/issue-agent/core/functions"
+	"github.com/clover0/issue-agent/local"
+	"github.com/clover0/issue-agent/test/assert"
+)
+
+func TestParseIssue(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		text    string
+		want    functions.GetIssueOutput
+		wantErr bool
+	}{
+		"markdown with heading": {
+			text: "# Add a flag\n\nAdd --verbose flag.\n",
+			want: functions.GetIssueOutput{
+				Title:   "Add a flag",
+				Content: "# Add a flag\n\nAdd --verbose flag.",
+			},
+		},
+		"text starting with empty lines": {
+			text: "\n\nFix the typo in README",
+			want: functions.GetIssueOutput{
+				Title:   "Fix the typo in README",
+				Content: "Fix the typo in README",
+			},
+		},
+		"empty": {
+			text:    " \n#\n",
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := local.ParseIssue(tt.text)
+			if tt.wantErr {
+				assert.HasError(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, got, tt.want)
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/clover0/issue-agent/core/functions"
+	"github.com/clover0/issue-agent/logger"
+	"github.com/clover0/issue-agent/platform"
+)
+
+// SubmitFileLocalService commits the files to the local branch,
+// and writes the pull request to the file instead of creating it on GitHub.
+// All the changes in the worktree are committed, so the worktree must be clean before the run. See CheckCleanWorktree.
+type SubmitFileLocalService struct {
+	logger          logger.Logger
+	callerInput     functions.SubmitFilesServiceInput
+	pullRequestPath string
+}
+
+func NewSubmitFileLocalService(
+	logger logger.Logger,
+	callerInput functions.SubmitFilesServiceInput,
+	pullRequestPath string,
+) (functions.SubmitFilesService, error) {
+	if callerInput.GitEmail == "" {
+		return SubmitFileLocalService{}, fmt.Errorf("git email is not set")
+	}
+	if callerInput.GitName == "" {
+		return SubmitFileLocalService{}, fmt.Errorf("git name is not set")
+	}
+
+	return SubmitFileLocalService{
+		logger:          logger,
+		callerInput:     callerInput,
+		pullRequestPath: pullRequestPath,
+	}, nil
+}
+
+func (s SubmitFileLocalService) SubmitFiles(_ context.Context, input functions.SubmitFilesInput) (functions.SubmitFilesOutput, error) {
+	errorf := func(format string, a ...any) error {
+		return fmt.Errorf("submit file local service: "+format, a...)
+	}
+
+	repo, err := git.PlainOpen(".")
+	if err != nil {
+		return functions.SubmitFilesOutput{}, errorf("failed to open repository: %w", err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return functions.SubmitFilesOutput{}, errorf("failed to get HEAD: %w", err)
+	}
+	currentBranch := head.Name().Short()
+	if currentBranch == s.callerInput.BaseBranch {
+		return functions.SubmitFilesOutput{}, errorf("cannot submit in the base branch. create and switch to a new branch")
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		return functions.SubmitFilesOutput{}, errorf("failed to get worktree: %w", err)
+	}
+
+	if _, err := wt.Add("./"); err != nil {
+		return functions.SubmitFilesOutput{}, errorf("failed to add files: %w", err)
+	}
+
+	if err := platform.ResetSymlink(s.logger, wt); err != nil {
+		return functions.SubmitFilesOutput{}, errorf("failed to reset symlink: %w", err)
+	}
+
+	// the git config of the local repository is kept, and only the author of the commit is the agent
+	if _, err := wt.Commit(
+		fmt.Sprintf("%s\n\n%s", input.CommitMessageShort, input.CommitMessageDetail),
+		&git.CommitOptions{
+			Author: &object.Signature{
+				Name:  s.callerInput.GitName,
+				Email: s.callerInput.GitEmail,
+				When:  time.Now(),
+			},
+		}); err != nil {
+		return functions.SubmitFilesOutput{}, errorf("failed to commit: %w", err)
+	}
+
+	if err := s.writePullRequest(currentBranch, input); err != nil {
+		return functions.SubmitFilesOutput{}, errorf("failed to write pull request: %w", err)
+	}
+	s.logger.Info("committed to branch %s, pull request is written to %s\n", currentBranch, s.pullRequestPath)
+
+	return functions.SubmitFilesOutput{
+		Message: fmt.Sprintf("success committing to the local branch.\nbranch: %s\npull request is written to %s.",
+			currentBranch, s.pullRequestPath),
+		PushedBranch: currentBranch,
+	}, nil
+}
+
+// writePullRequest writes the title and the body of the pull request as markdown.
+func (s SubmitFileLocalService) writePullRequest(branch string, input functions.SubmitFilesInput) error {
+	if err := os.MkdirAll(filepath.Dir(s.pullRequestPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	body := fmt.Sprintf("# %s\n\nbase: %s\nbranch: %s\n\n%s\n",
+		input.CommitMessageShort, s.callerInput.BaseBranch, branch, input.PullRequestContent)
+
+	return os.WriteFile(s.pullRequestPath, []byte(body), 0644)
+}
+
+// CheckCleanWorktree returns an error when the repository has uncommitted changes or untracked files,
+// so that the changes of the developer are not committed with the changes of the agent.
+func CheckCleanWorktree(repoPath string) error {
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	status, err := wt.Status()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree status: %w", err)
+	}
+	if !status.IsClean() {
+		return fmt.Errorf("the worktree has uncommitted changes, commit or stash them before running the agent:\n%s", status)
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package local_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/clover0/issue-agent/core/functions"
+	"github.com/clover0/issue-agent/local"
+	"github.com/clover0/issue-agent/test/assert"
+	"github.com/clover0/issue-agent/test/loggertest"
+)
+
+func TestSubmitFileLocalService_SubmitFiles(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	assert.NoError(t, err)
+	wt, err := repo.Worktree()
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("initial"), 0644))
+	_, err = wt.Add(".")
+	assert.NoError(t, err)
+	_, err = wt.Commit("initial", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	assert.NoError(t, err)
+	head, err := repo.Head()
+	assert.NoError(t, err)
+	baseBranch := head.Name().Short()
+
+	t.Chdir(dir)
+
+	pullRequestPath := filepath.Join(t.TempDir(), "pull_requests", "pr.md")
+	service, err := local.NewSubmitFileLocalService(loggertest.NewTestLogger(), functions.SubmitFilesServiceInput{
+		BaseBranch: baseBranch,
+		GitEmail:   "agent@example.com",
+		GitName:    "agent",
+	}, pullRequestPath)
+	assert.NoError(t, err)
+
+	input := functions.SubmitFilesInput{
+		CommitMessageShort:  "Update README",
+		CommitMessageDetail: "detail",
+		PullRequestContent:  "pull request body",
+	}
+
+	_, err = service.SubmitFiles(context.Background(), input)
+	assert.HasError(t, err)
+
+	assert.NoError(t, wt.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("agent-1"), Create: true}))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("changed"), 0644))
+
+	out, err := service.SubmitFiles(context.Background(), input)
+	assert.NoError(t, err)
+	assert.Equal(t, out.PushedBranch, "agent-1")
+
+	head, err = repo.Head()
+	assert.NoError(t, err)
+	commit, err := repo.CommitObject(head.Hash())
+	assert.NoError(t, err)
+	assert.Equal(t, commit.Message, "Update README\n\ndetail")
+	assert.Equal(t, commit.Author.Name, "agent")
+
+	body, err := os.ReadFile(pullRequestPath)
+	assert.NoError(t, err)
+	assert.Equal(t, string(body), "# Update README\n\nbase: "+baseBranch+"\nbranch: agent-1\n\npull request body\n")
+}
+
+func TestCheckCleanWorktree(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	assert.NoError(t, err)
+	wt, err := repo.Worktree()
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("initial"), 0644))
+	_, err = wt.Add(".")
+	assert.NoError(t, err)
+	_, err = wt.Commit("initial", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, local.CheckCleanWorktree(dir))
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, ".env"), []byte("SECRET=1"), 0644))
+	assert.HasError(t, local.CheckCleanWorktree(dir))
+}
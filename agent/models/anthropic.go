@@ -11,6 +11,8 @@ import (
 	"strings"
 	"time"
 
+	"golang.org/x/oauth2"
+
 	"github.com/clover0/issue-agent/logger"
 	"github.com/clover0/issue-agent/util"
 )
@@ -20,6 +22,9 @@ type AnthropicClient struct {
 	logger  logger.Logger
 	baseURL *url.URL
 
+	// vertex is set when the client calls Claude models on Vertex AI
+	vertex *anthropicVertex
+
 	// services
 	Messages *AnthropicMessageService
 }
@@ -49,6 +54,77 @@ func NewAnthropic(logger logger.Logger, apiKey string) AnthropicClient {
 	return c
 }
 
+type anthropicVertex struct {
+	projectID string
+	region    string
+}
+
+// NewAnthropicVertex creates the client calling Claude models on Google Cloud Vertex AI.
+// The access token is taken from the token source for each request.
+func NewAnthropicVertex(logger logger.Logger, projectID string, region string, tokenSource oauth2.TokenSource) AnthropicClient {
+	baseURL := fmt.Sprintf("https://%s-aiplatform.googleapis.com", region)
+	if region == "global" {
+		baseURL = "https://aiplatform.googleapis.com"
+	}
+
+	return newAnthropicVertex(logger, baseURL, projectID, region, tokenSource)
+}
+
+func newAnthropicVertex(logger logger.Logger, baseURL string, projectID string, region string, tokenSource oauth2.TokenSource) AnthropicClient {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		logger.Error("failed to parse base URL: %s", err)
+	}
+
+	client := &http.Client{
+		Transport: roundTripper(func(req *http.Request) (*http.Response, error) {
+			token, err := tokenSource.Token()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get access token of Google Cloud: %w", err)
+			}
+			req.Header.Set("content-type", "application/json")
+			token.SetAuthHeader(req)
+			return http.DefaultTransport.RoundTrip(req)
+		}),
+	}
+	c := AnthropicClient{
+		logger:  logger,
+		client:  client,
+		baseURL: u,
+		vertex: &anthropicVertex{
+			projectID: projectID,
+			region:    region,
+		},
+	}
+
+	c.Messages = &AnthropicMessageService{client: &c}
+
+	return c
+}
+
+// messagesRequest returns the path and the body of Messages API.
+// Vertex AI takes the model in the path and the API version in the body.
+func (c *AnthropicClient) messagesRequest(body J) (string, J) {
+	if c.vertex == nil {
+		return "v1/messages", body
+	}
+
+	vertexBody := J{"anthropic_version": "vertex-2023-10-16"}
+	for k, v := range body {
+		if k != "model" {
+			vertexBody[k] = v
+		}
+	}
+
+	method := "rawPredict"
+	if body["stream"] == true {
+		method = "streamRawPredict"
+	}
+
+	return fmt.Sprintf("v1/projects/%s/locations/%s/publishers/anthropic/models/%s:%s",
+		c.vertex.projectID, c.vertex.region, body["model"], method), vertexBody
+}
+
 type roundTripper func(r *http.Request) (*http.Response, error)
 
 func (r roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
@@ -80,8 +156,9 @@ type AnthropicMessageService struct {
 
 func (s *AnthropicMessageService) Create(ctx context.Context, body J) (*ResponseMessage, error) {
 	var message *ResponseMessage
+	path, body := s.client.messagesRequest(body)
 	err := util.Retry(3, func() error {
-		req, err := s.client.NewRequest("POST", "v1/messages", body)
+		req, err := s.client.NewRequest("POST", path, body)
 		if err != nil {
 			return fmt.Errorf("failed to create request: %w", err)
 		}
@@ -98,15 +175,7 @@ func (s *AnthropicMessageService) Create(ctx context.Context, body J) (*Response
 		}
 
 		if resp.StatusCode >= 400 {
-			respStr := string(b)
-			if resp.StatusCode == http.StatusServiceUnavailable || strings.Contains(respStr, "overloaded") {
-				return util.NewRetryableError(fmt.Errorf("service unavailable or overloaded"), 1*time.Second)
-			}
-			if resp.StatusCode == http.StatusTooManyRequests {
-				s.client.logger.Info(fmt.Sprintf("%s\nRate limited, retrying after 60 seconds...\n", respStr))
-				return util.NewRetryableError(fmt.Errorf("too many requests, retrying after 60 seconds"), 60*time.Second)
-			}
-			return fmt.Errorf("invalid request or server error %s", b)
+			return s.statusError(resp.StatusCode, b)
 		}
 
 		if err := json.Unmarshal(b, &message); err != nil {
@@ -121,6 +190,156 @@ func (s *AnthropicMessageService) Create(ctx context.Context, body J) (*Response
 	return message, nil
 }
 
+// CreateStream creates the message receiving the response as a stream, and returns the assembled message.
+// onDelta is called with the text and the tool input deltas.
+// The request is retried when no event arrives within the idle timeout.
+func (s *AnthropicMessageService) CreateStream(
+	ctx context.Context,
+	body J,
+	idleTimeout time.Duration,
+	onDelta func(delta string),
+) (*ResponseMessage, error) {
+	streamBody := J{"stream": true}
+	for k, v := range body {
+		streamBody[k] = v
+	}
+
+	var message *ResponseMessage
+	path, streamBody := s.client.messagesRequest(streamBody)
+	err := util.Retry(3, func() error {
+		req, err := s.client.NewRequest("POST", path, streamBody)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+
+		streamCtx, touch, isIdle, cancel := idleContext(ctx, idleTimeout)
+		defer cancel()
+
+		resp, err := s.client.client.Do(req.WithContext(streamCtx))
+		if err != nil {
+			if isIdle() {
+				return util.NewRetryableError(errStreamIdle, 1*time.Second)
+			}
+			return fmt.Errorf("failed to send request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode >= 400 {
+			b, err := io.ReadAll(resp.Body)
+			if err != nil {
+				return fmt.Errorf("failed to read response body: %w", err)
+			}
+			return s.statusError(resp.StatusCode, b)
+		}
+
+		message, err = readAnthropicStream(resp.Body, touch, onDelta)
+		if err != nil {
+			if isIdle() {
+				return util.NewRetryableError(errStreamIdle, 1*time.Second)
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// readAnthropicStream assembles the message from the events of the stream.
+func readAnthropicStream(r io.Reader, touch func(), onDelta func(delta string)) (*ResponseMessage, error) {
+	var message ResponseMessage
+	var inputs []strings.Builder
+
+	err := readSSE(r, touch, func(event string, data []byte) error {
+		var e struct {
+			Message      ResponseMessage `json:"message"`
+			Index        int             `json:"index"`
+			ContentBlock MessageContent  `json:"content_block"`
+			Delta        struct {
+				Type        string `json:"type"`
+				Text        string `json:"text"`
+				PartialJSON string `json:"partial_json"`
+				StopReason  string `json:"stop_reason"`
+			} `json:"delta"`
+			Usage struct {
+				OutputTokens int64 `json:"output_tokens"`
+			} `json:"usage"`
+			Error struct {
+				Type    string `json:"type"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(data, &e); err != nil {
+			return fmt.Errorf("failed to unmarshal stream event %s: %w", event, err)
+		}
+
+		switch event {
+		case "message_start":
+			message = e.Message
+		case "content_block_start":
+			for len(message.Content) <= e.Index {
+				message.Content = append(message.Content, MessageContent{})
+				inputs = append(inputs, strings.Builder{})
+			}
+			message.Content[e.Index] = e.ContentBlock
+		case "content_block_delta":
+			if e.Index >= len(message.Content) {
+				return fmt.Errorf("stream event of unknown content block %d", e.Index)
+			}
+			switch e.Delta.Type {
+			case "text_delta":
+				message.Content[e.Index].Text += e.Delta.Text
+				onDelta(e.Delta.Text)
+			case "input_json_delta":
+				inputs[e.Index].WriteString(e.Delta.PartialJSON)
+				onDelta(e.Delta.PartialJSON)
+			}
+		case "message_delta":
+			message.StopReason = e.Delta.StopReason
+			message.Usage.OutputTokens = e.Usage.OutputTokens
+		case "error":
+			if e.Error.Type == "overloaded_error" {
+				return util.NewRetryableError(fmt.Errorf("overloaded: %s", e.Error.Message), 1*time.Second)
+			}
+			return fmt.Errorf("stream error %s: %s", e.Error.Type, e.Error.Message)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the input of the tool use is sent as partial JSON strings
+	for i, input := range inputs {
+		if message.Content[i].Type != "tool_use" || input.Len() == 0 {
+			continue
+		}
+		if err := json.Unmarshal([]byte(input.String()), &message.Content[i].Input); err != nil {
+			// the input cut off by max_tokens is not a valid JSON, and the tool use is not executed.
+			if message.StopReason != "max_tokens" {
+				return nil, fmt.Errorf("failed to unmarshal tool input: %w", err)
+			}
+		}
+	}
+
+	return &message, nil
+}
+
+func (s *AnthropicMessageService) statusError(status int, b []byte) error {
+	respStr := string(b)
+	if status == http.StatusServiceUnavailable || strings.Contains(respStr, "overloaded") {
+		return util.NewRetryableError(fmt.Errorf("service unavailable or overloaded"), 1*time.Second)
+	}
+	if status == http.StatusTooManyRequests {
+		s.client.logger.Info(fmt.Sprintf("%s\nRate limited, retrying after 60 seconds...\n", respStr))
+		return util.NewRetryableError(fmt.Errorf("too many requests, retrying after 60 seconds"), 60*time.Second)
+	}
+	return &util.StatusError{StatusCode: status, Err: fmt.Errorf("invalid request or server error %s", b)}
+}
+
 type ResponseMessage struct {
 	ID           string           `json:"id"`
 	Type         string           `json:"type"`
@@ -5,6 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"time"
+
+	"golang.org/x/oauth2/google"
 
 	"github.com/clover0/issue-agent/core"
 	"github.com/clover0/issue-agent/logger"
@@ -14,6 +17,9 @@ type AnthropicLLMForwarder struct {
 	anthropic     AnthropicClient
 	forwardLogger logger.Logger
 	receiveLogger logger.Logger
+
+	// streamIdleTimeout is the idle timeout of the stream. Zero means no streaming.
+	streamIdleTimeout time.Duration
 }
 
 func NewAnthropicLLMForwarder(l logger.Logger) (core.LLMForwarder, error) {
@@ -22,14 +28,35 @@ func NewAnthropicLLMForwarder(l logger.Logger) (core.LLMForwarder, error) {
 		return nil, fmt.Errorf("ANTHROPIC_API_KEY is not set")
 	}
 
-	forwardLogger := l.AddPrefix("[AnthropicForwarder] ").SetColor(logger.Green)
-	receiveLogger := l.AddPrefix("[AnthropicReceive] ").SetColor(logger.Yellow)
+	return newAnthropicLLMForwarder(l, NewAnthropic(l, token)), nil
+}
+
+// NewAnthropicVertexLLMForwarder creates the forwarder of Claude models on Google Cloud Vertex AI.
+// The credentials are found by Application Default Credentials of Google Cloud.
+func NewAnthropicVertexLLMForwarder(l logger.Logger) (core.LLMForwarder, error) {
+	projectID, ok := os.LookupEnv("ANTHROPIC_VERTEX_PROJECT_ID")
+	if !ok {
+		return nil, fmt.Errorf("ANTHROPIC_VERTEX_PROJECT_ID is not set")
+	}
+	region, ok := os.LookupEnv("CLOUD_ML_REGION")
+	if !ok {
+		return nil, fmt.Errorf("CLOUD_ML_REGION is not set")
+	}
+
+	tokenSource, err := google.DefaultTokenSource(context.Background(), "https://www.googleapis.com/auth/cloud-platform")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find Google Cloud credentials: %w", err)
+	}
+
+	return newAnthropicLLMForwarder(l, NewAnthropicVertex(l, projectID, region, tokenSource)), nil
+}
 
+func newAnthropicLLMForwarder(l logger.Logger, client AnthropicClient) AnthropicLLMForwarder {
 	return AnthropicLLMForwarder{
-		anthropic:     NewAnthropic(l, token),
-		forwardLogger: forwardLogger,
-		receiveLogger: receiveLogger,
-	}, nil
+		anthropic:     client,
+		forwardLogger: l.AddPrefix("[AnthropicForwarder] ").SetColor(logger.Green),
+		receiveLogger: l.AddPrefix("[AnthropicReceive] ").SetColor(logger.Yellow),
+	}
 }
 
 func (a AnthropicLLMForwarder) StartForward(input core.StartCompletionInput) ([]core.LLMMessage, error) {
@@ -40,7 +67,7 @@ func (a AnthropicLLMForwarder) StartForward(input core.StartCompletionInput) ([]
 	a.forwardLogger.Info(fmt.Sprintf("model: %s, sending message\n", input.Model))
 	a.forwardLogger.Debug("system prompt:\n%s\n", input.SystemPrompt)
 	a.forwardLogger.Debug("user prompt:\n%s\n", input.StartUserPrompt)
-	resp, err := a.anthropic.Messages.Create(context.TODO(), params)
+	resp, err := a.create(context.TODO(), params)
 	if err != nil {
 		return nil, err
 	}
@@ -86,7 +113,7 @@ func (a AnthropicLLMForwarder) StartForward(input core.StartCompletionInput) ([]
 }
 
 func (a AnthropicLLMForwarder) ForwardLLM(
-	_ context.Context,
+	ctx context.Context,
 	input core.StartCompletionInput,
 	llmContexts []core.ReturnToLLMContext,
 	history []core.LLMMessage,
@@ -144,6 +171,9 @@ func (a AnthropicLLMForwarder) ForwardLLM(
 					},
 				},
 			})
+		case core.LLMSystem:
+			// the system prompt is sent separately. The history returned by OpenAI has the system message.
+			continue
 		default:
 			return nil, fmt.Errorf("unknown role: %s", h.Role)
 		}
@@ -206,7 +236,7 @@ func (a AnthropicLLMForwarder) ForwardLLM(
 	a.forwardLogger.Info(fmt.Sprintf("model: %s, sending message\n", input.Model))
 	a.forwardLogger.Debug("%s\n", newMsg.TruncatedRawContent("... truncated in debug output ..."))
 
-	resp, err := a.anthropic.Messages.Create(context.TODO(), params)
+	resp, err := a.create(ctx, params)
 	if err != nil {
 		return nil, err
 	}
@@ -253,6 +283,22 @@ func (a AnthropicLLMForwarder) ForwardLLM(
 	return history, nil
 }
 
+func (a AnthropicLLMForwarder) withStreaming(idleTimeout time.Duration) core.LLMForwarder {
+	a.streamIdleTimeout = idleTimeout
+	return a
+}
+
+// create creates the message, receiving the response as a stream when streaming is enabled.
+func (a AnthropicLLMForwarder) create(ctx context.Context, params J) (*ResponseMessage, error) {
+	if a.streamIdleTimeout == 0 {
+		return a.anthropic.Messages.Create(ctx, params)
+	}
+
+	deltas := newDeltaLogger(a.receiveLogger)
+	defer deltas.Flush()
+	return a.anthropic.Messages.CreateStream(ctx, params, a.streamIdleTimeout, deltas.Write)
+}
+
 // TODO: refactor with openai forwarder
 func (a AnthropicLLMForwarder) ForwardStep(_ context.Context, history []core.LLMMessage) core.Step {
 	lastMsg := history[len(history)-1]
@@ -271,22 +317,14 @@ func (a AnthropicLLMForwarder) ForwardStep(_ context.Context, history []core.LLM
 		}
 		return core.NewExecStep(input)
 	case core.FinishLengthOver:
-		return core.NewUnrecoverableStep(fmt.Errorf("chat completion length error"))
+		return core.NewLengthOverStep(history)
 	}
 
-	return core.NewUnknownStep()
+	return core.NewUnrecoverableStep(fmt.Errorf("generation stopped by %s", lastMsg.FinishReason))
 }
 
 func (a AnthropicLLMForwarder) createParams(input core.StartCompletionInput) (J, []core.LLMMessage) {
-	tools := make([]J, len(input.Functions))
-
-	for i, f := range input.Functions {
-		tools[i] = J{
-			"name":         f.Name,
-			"description":  f.Description,
-			"input_schema": f.Parameters,
-		}
-	}
+	tools := toAnthropicTools(input.Tools)
 
 	body := J{
 		"model": input.Model,
@@ -332,9 +370,10 @@ func convertAnthropicStopReasonToReason(reason string) core.MessageFinishReason
 		return core.FinishLengthOver
 	case "stop_sequence":
 		return core.FinishStop
-	case "too_use":
+	case "tool_use":
 		return core.FinishToolCalls
 	default:
-		return core.FinishToolCalls
+		// such as refusal, which stops the agent
+		return core.MessageFinishReason(reason)
 	}
 }
@@ -0,0 +1,125 @@
+package models_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/clover0/issue-agent/core"
+	"github.com/clover0/issue-agent/models"
+	"github.com/clover0/issue-agent/test/assert"
+	"github.com/clover0/issue-agent/test/loggertest"
+)
+
+func TestAnthropicVertexLLMForwarder(t *testing.T) {
+	t.Parallel()
+
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.URL.Path,
+			"/v1/projects/test-project/locations/us-east5/publishers/anthropic/models/claude-sonnet-4@20250514:rawPredict")
+		assert.Equal(t, r.Header.Get("Authorization"), "Bearer test-token")
+
+		b, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(b, &body)
+
+		_, _ = w.Write([]byte(`{
+  "id": "msg_1",
+  "type": "message",
+  "role": "assistant",
+  "content": [{"type": "text", "text": "done"}],
+  "stop_reason": "end_turn",
+  "usage": {"input_tokens": 10, "output_tokens": 2}
+}`))
+	}))
+	defer server.Close()
+
+	forwarder := models.NewAnthropicVertexLLMForwarderWithBaseURL(
+		loggertest.NewTestLogger(), server.URL, "test-project", "us-east5", "test-token")
+
+	history, err := forwarder.StartForward(core.StartCompletionInput{
+		Model:           "claude-sonnet-4@20250514",
+		SystemPrompt:    "system",
+		StartUserPrompt: "user",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, history[len(history)-1].RawContent, "done")
+
+	// Vertex AI takes the model in the path and the API version in the body
+	_, hasModel := body["model"]
+	assert.Equal(t, hasModel, false)
+	assert.Equal(t, body["anthropic_version"], any("vertex-2023-10-16"))
+}
+
+func TestAnthropicVertexLLMForwarder_Streaming(t *testing.T) {
+	t.Parallel()
+
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.URL.Path,
+			"/v1/projects/test-project/locations/us-east5/publishers/anthropic/models/claude-sonnet-4@20250514:streamRawPredict")
+
+		b, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(b, &body)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(`event: message_start
+data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[],"usage":{"input_tokens":10,"output_tokens":1}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"open "}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"the file"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: content_block_start
+data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"open_file","input":{}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"path\": "}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"README.md\"}"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":1}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":20}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`))
+	}))
+	defer server.Close()
+
+	forwarder := models.WithStreaming(models.NewAnthropicVertexLLMForwarderWithBaseURL(
+		loggertest.NewTestLogger(), server.URL, "test-project", "us-east5", "test-token"), time.Second)
+
+	history, err := forwarder.StartForward(core.StartCompletionInput{
+		Model:           "claude-sonnet-4@20250514",
+		SystemPrompt:    "system",
+		StartUserPrompt: "user",
+	})
+	assert.NoError(t, err)
+
+	last := history[len(history)-1]
+	assert.Equal(t, last.RawContent, "open the file")
+	assert.Equal(t, last.FinishReason, core.FinishToolCalls)
+	assert.Equal(t, len(last.ReturnedToolCalls), 1)
+	assert.Equal(t, last.ReturnedToolCalls[0].ToolName, "open_file")
+	assert.Equal(t, last.ReturnedToolCalls[0].Argument, `{"path":"README.md"}`)
+	assert.Equal(t, last.Usage.InputToken, int64(10))
+	assert.Equal(t, last.Usage.OutputToken, int64(20))
+	assert.Equal(t, body["stream"], any(true))
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -12,10 +13,12 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws/retry"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/document"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
 
 	"github.com/clover0/issue-agent/core"
 	"github.com/clover0/issue-agent/logger"
+	"github.com/clover0/issue-agent/util"
 	"github.com/clover0/issue-agent/util/pointer"
 )
 
@@ -101,6 +104,73 @@ func (s *BedrockMessageService) Create(
 	messages []types.Message,
 	toolSpecs []*types.ToolMemberToolSpec,
 ) (response *bedrockruntime.ConverseOutput, _ error) {
+	result, err := s.client.client.Converse(ctx, converseInput(modelID, systemMessage, messages, toolSpecs))
+	if err != nil {
+		return response, invokeError(err)
+	}
+
+	return result, nil
+}
+
+// CreateStream converses receiving the response as a stream, and returns the assembled output.
+// onDelta is called with the text and the tool input deltas.
+// The request is retried when no event arrives within the idle timeout.
+func (s *BedrockMessageService) CreateStream(
+	ctx context.Context,
+	modelID string,
+	systemMessage string,
+	messages []types.Message,
+	toolSpecs []*types.ToolMemberToolSpec,
+	idleTimeout time.Duration,
+	onDelta func(delta string),
+) (*bedrockruntime.ConverseOutput, error) {
+	input := converseInput(modelID, systemMessage, messages, toolSpecs)
+
+	var output *bedrockruntime.ConverseOutput
+	err := util.Retry(3, func() error {
+		streamCtx, touch, isIdle, cancel := idleContext(ctx, idleTimeout)
+		defer cancel()
+
+		result, err := s.client.client.ConverseStream(streamCtx, &bedrockruntime.ConverseStreamInput{
+			ModelId:         input.ModelId,
+			InferenceConfig: input.InferenceConfig,
+			System:          input.System,
+			Messages:        input.Messages,
+			ToolConfig:      input.ToolConfig,
+		})
+		if err != nil {
+			if isIdle() {
+				return util.NewRetryableError(errStreamIdle, 1*time.Second)
+			}
+			return invokeError(err)
+		}
+
+		stream := result.GetStream()
+		defer stream.Close()
+
+		output, err = readBedrockStream(streamCtx, stream, touch, onDelta)
+		if err != nil {
+			if isIdle() {
+				return util.NewRetryableError(errStreamIdle, 1*time.Second)
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// converseInput builds the input with the cache points of the system message, the tools and the last message.
+func converseInput(
+	modelID string,
+	systemMessage string,
+	messages []types.Message,
+	toolSpecs []*types.ToolMemberToolSpec,
+) *bedrockruntime.ConverseInput {
 	// add cache point to the last message
 	messages[len(messages)-1].Content = append(messages[len(messages)-1].Content,
 		&types.ContentBlockMemberCachePoint{
@@ -130,14 +200,106 @@ func (s *BedrockMessageService) Create(
 		Value: types.CachePointBlock{Type: types.CachePointTypeDefault},
 	})
 
-	result, err := s.client.client.Converse(ctx, input)
-	if err != nil {
-		errMsg := err.Error()
-		if strings.Contains(errMsg, "provided model identifier is invalid") {
-			return response, fmt.Errorf("failed to invoke model: %w: hint - check whether enabled the model and in the AWS region", err)
+	return input
+}
+
+func invokeError(err error) error {
+	if strings.Contains(err.Error(), "provided model identifier is invalid") {
+		return fmt.Errorf("failed to invoke model: %w: hint - check whether enabled the model and in the AWS region", err)
+	}
+	return fmt.Errorf("failed to invoke model: %w", err)
+}
+
+// readBedrockStream assembles the output from the events of the stream.
+func readBedrockStream(
+	ctx context.Context,
+	stream *bedrockruntime.ConverseStreamEventStream,
+	touch func(),
+	onDelta func(delta string),
+) (*bedrockruntime.ConverseOutput, error) {
+	type block struct {
+		text    strings.Builder
+		toolUse *types.ToolUseBlockStart
+		input   strings.Builder
+	}
+	var blocks []*block
+	blockAt := func(index *int32) *block {
+		i := int(aws.ToInt32(index))
+		for len(blocks) <= i {
+			blocks = append(blocks, &block{})
 		}
-		return response, fmt.Errorf("failed to invoke model: %w", err)
+		return blocks[i]
 	}
 
-	return result, nil
+	output := &bedrockruntime.ConverseOutput{}
+	for done := false; !done; {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case event, ok := <-stream.Events():
+			if !ok {
+				done = true
+				break
+			}
+			touch()
+
+			switch e := event.(type) {
+			case *types.ConverseStreamOutputMemberContentBlockStart:
+				if t, ok := e.Value.Start.(*types.ContentBlockStartMemberToolUse); ok {
+					blockAt(e.Value.ContentBlockIndex).toolUse = &t.Value
+				}
+			case *types.ConverseStreamOutputMemberContentBlockDelta:
+				b := blockAt(e.Value.ContentBlockIndex)
+				switch d := e.Value.Delta.(type) {
+				case *types.ContentBlockDeltaMemberText:
+					b.text.WriteString(d.Value)
+					onDelta(d.Value)
+				case *types.ContentBlockDeltaMemberToolUse:
+					b.input.WriteString(aws.ToString(d.Value.Input))
+					onDelta(aws.ToString(d.Value.Input))
+				}
+			case *types.ConverseStreamOutputMemberMessageStop:
+				output.StopReason = e.Value.StopReason
+			case *types.ConverseStreamOutputMemberMetadata:
+				if e.Value.Usage != nil {
+					output.Usage = e.Value.Usage
+				}
+			}
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stream: %w", err)
+	}
+	if output.Usage == nil {
+		return nil, fmt.Errorf("stream ended without usage")
+	}
+
+	message := types.Message{Role: types.ConversationRoleAssistant}
+	for _, b := range blocks {
+		if b.toolUse == nil {
+			message.Content = append(message.Content, &types.ContentBlockMemberText{Value: b.text.String()})
+			continue
+		}
+
+		inputMap := map[string]any{}
+		if b.input.Len() > 0 {
+			if err := json.Unmarshal([]byte(b.input.String()), &inputMap); err != nil {
+				// the input cut off by the max tokens is handled as the length over
+				if output.StopReason != types.StopReasonMaxTokens {
+					return nil, fmt.Errorf("failed to unmarshal tool input: %w", err)
+				}
+				inputMap = map[string]any{}
+			}
+		}
+		message.Content = append(message.Content, &types.ContentBlockMemberToolUse{
+			Value: types.ToolUseBlock{
+				ToolUseId: b.toolUse.ToolUseId,
+				Name:      b.toolUse.Name,
+				Input:     document.NewLazyDocument(inputMap),
+			},
+		})
+	}
+	output.Output = &types.ConverseOutputMemberMessage{Value: message}
+
+	return output, nil
 }
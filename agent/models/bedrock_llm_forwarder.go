@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/document"
@@ -11,13 +12,15 @@ import (
 
 	"github.com/clover0/issue-agent/core"
 	"github.com/clover0/issue-agent/logger"
-	"github.com/clover0/issue-agent/util/pointer"
 )
 
 type BedrockLLMForwarder struct {
 	Bedrock       BedrockClient
 	forwardLogger logger.Logger
 	returnLogger  logger.Logger
+
+	// streamIdleTimeout is the idle timeout of the stream. Zero means no streaming.
+	streamIdleTimeout time.Duration
 }
 
 func NewBedrockLLMForwarder(l logger.Logger) (core.LLMForwarder, error) {
@@ -45,7 +48,7 @@ func (a BedrockLLMForwarder) StartForward(input core.StartCompletionInput) ([]co
 	a.forwardLogger.Info(fmt.Sprintf("model: %s, sending message\n", input.Model))
 	a.forwardLogger.Debug("system prompt:\n%s\n", input.SystemPrompt)
 	a.forwardLogger.Debug("user prompt:\n%s\n", input.StartUserPrompt)
-	resp, err := a.Bedrock.Messages.Create(context.TODO(),
+	resp, err := a.create(context.TODO(),
 		input.Model,
 		input.SystemPrompt,
 		initMsg,
@@ -69,7 +72,7 @@ func (a BedrockLLMForwarder) StartForward(input core.StartCompletionInput) ([]co
 }
 
 func (a BedrockLLMForwarder) ForwardLLM(
-	_ context.Context,
+	ctx context.Context,
 	input core.StartCompletionInput,
 	llmContexts []core.ReturnToLLMContext,
 	history []core.LLMMessage,
@@ -120,6 +123,10 @@ func (a BedrockLLMForwarder) ForwardLLM(
 			toolResult.Content = append(toolResult.Content, &types.ToolResultContentBlockMemberText{Value: h.RawContent})
 			msg.Content = append(msg.Content, &types.ContentBlockMemberToolResult{Value: toolResult})
 
+		case core.LLMSystem:
+			// the system prompt is sent separately. The history returned by OpenAI has the system message.
+			continue
+
 		default:
 			return nil, fmt.Errorf("unknown role: %s", h.Role)
 		}
@@ -172,8 +179,8 @@ func (a BedrockLLMForwarder) ForwardLLM(
 	a.forwardLogger.Info(fmt.Sprintf("model: %s, sending message\n", input.Model))
 	a.forwardLogger.Debug("%s\n", newMsg.TruncatedRawContent("... truncated in debug output ..."))
 
-	resp, err := a.Bedrock.Messages.Create(
-		context.TODO(),
+	resp, err := a.create(
+		ctx,
 		input.Model,
 		input.SystemPrompt,
 		messages,
@@ -194,6 +201,28 @@ func (a BedrockLLMForwarder) ForwardLLM(
 	return history, nil
 }
 
+func (a BedrockLLMForwarder) withStreaming(idleTimeout time.Duration) core.LLMForwarder {
+	a.streamIdleTimeout = idleTimeout
+	return a
+}
+
+// create converses, receiving the response as a stream when streaming is enabled.
+func (a BedrockLLMForwarder) create(
+	ctx context.Context,
+	modelID string,
+	systemMessage string,
+	messages []types.Message,
+	toolSpecs []*types.ToolMemberToolSpec,
+) (*bedrockruntime.ConverseOutput, error) {
+	if a.streamIdleTimeout == 0 {
+		return a.Bedrock.Messages.Create(ctx, modelID, systemMessage, messages, toolSpecs)
+	}
+
+	deltas := newDeltaLogger(a.returnLogger)
+	defer deltas.Flush()
+	return a.Bedrock.Messages.CreateStream(ctx, modelID, systemMessage, messages, toolSpecs, a.streamIdleTimeout, deltas.Write)
+}
+
 // TODO: refactor with openai forwarder
 func (a BedrockLLMForwarder) ForwardStep(_ context.Context, history []core.LLMMessage) core.Step {
 	lastMsg := history[len(history)-1]
@@ -212,10 +241,10 @@ func (a BedrockLLMForwarder) ForwardStep(_ context.Context, history []core.LLMMe
 		}
 		return core.NewExecStep(input)
 	case core.FinishLengthOver:
-		return core.NewUnrecoverableStep(fmt.Errorf("chat completion length error"))
+		return core.NewLengthOverStep(history)
 	}
 
-	return core.NewUnknownStep()
+	return core.NewUnrecoverableStep(fmt.Errorf("generation stopped by %s", lastMsg.FinishReason))
 }
 
 func (a BedrockLLMForwarder) buildAssistantHistory(bedrockResp bedrockruntime.ConverseOutput) (core.LLMMessage, error) {
@@ -261,19 +290,7 @@ func (a BedrockLLMForwarder) buildAssistantHistory(bedrockResp bedrockruntime.Co
 // TODO: refactor rename
 func (a BedrockLLMForwarder) buildStartParams(input core.StartCompletionInput) ([]types.Message, []*types.ToolMemberToolSpec, []core.LLMMessage) {
 	var messages []types.Message
-	tools := make([]*types.ToolMemberToolSpec, len(input.Functions))
-
-	for i, f := range input.Functions {
-		tools[i] = &types.ToolMemberToolSpec{
-			Value: types.ToolSpecification{
-				Name:        pointer.String(f.Name.String()),
-				Description: &f.Description,
-				InputSchema: &types.ToolInputSchemaMemberJson{
-					Value: document.NewLazyDocument(f.Parameters),
-				},
-			},
-		}
-	}
+	tools := toBedrockTools(input.Tools)
 
 	messages = append(messages, types.Message{
 		Role: types.ConversationRoleUser,
@@ -306,6 +323,7 @@ func convertBedrockStopReasonToReason(reason types.StopReason) core.MessageFinis
 	case types.StopReasonMaxTokens:
 		return core.FinishLengthOver
 	default:
-		panic(fmt.Sprintf("unknown finish reason: %s", reason))
+		// such as guardrail_intervened and content_filtered, which stop the agent
+		return core.MessageFinishReason(reason)
 	}
 }
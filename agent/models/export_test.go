@@ -0,0 +1,24 @@
+package models
+
+import (
+	"golang.org/x/oauth2"
+
+	"github.com/clover0/issue-agent/core"
+	"github.com/clover0/issue-agent/logger"
+)
+
+var (
+	ToOpenAITools    = toOpenAITools
+	ToAnthropicTools = toAnthropicTools
+	ToBedrockTools   = toBedrockTools
+	ToGeminiTools    = toGeminiTools
+)
+
+func NewGeminiLLMForwarderWithBaseURL(l logger.Logger, apiKey string, baseURL string) core.LLMForwarder {
+	return newGeminiLLMForwarder(l, newGemini(l, apiKey, baseURL))
+}
+
+func NewAnthropicVertexLLMForwarderWithBaseURL(l logger.Logger, baseURL string, projectID string, region string, token string) core.LLMForwarder {
+	tokenSource := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	return newAnthropicLLMForwarder(l, newAnthropicVertex(l, baseURL, projectID, region, tokenSource))
+}
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/clover0/issue-agent/logger"
+	"github.com/clover0/issue-agent/util"
+)
+
+const geminiBaseURL = "https://generativelanguage.googleapis.com"
+
+type GeminiClient struct {
+	client  *http.Client
+	logger  logger.Logger
+	baseURL *url.URL
+}
+
+func NewGemini(logger logger.Logger, apiKey string) GeminiClient {
+	return newGemini(logger, apiKey, geminiBaseURL)
+}
+
+func newGemini(logger logger.Logger, apiKey string, baseURL string) GeminiClient {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		logger.Error("failed to parse base URL: %s", err)
+	}
+
+	client := &http.Client{
+		Transport: roundTripper(func(req *http.Request) (*http.Response, error) {
+			req.Header.Set("content-type", "application/json")
+			req.Header.Set("x-goog-api-key", apiKey)
+			return http.DefaultTransport.RoundTrip(req)
+		}),
+	}
+
+	return GeminiClient{
+		logger:  logger,
+		client:  client,
+		baseURL: u,
+	}
+}
+
+// GenerateContent calls generateContent API of the model.
+func (c GeminiClient) GenerateContent(ctx context.Context, model string, body J) (*GeminiResponse, error) {
+	u := c.baseURL.JoinPath("v1beta", "models", model+":generateContent")
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal body: %w", err)
+	}
+
+	var resp *GeminiResponse
+	err = util.Retry(3, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(b))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+
+		r, err := c.client.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to send request: %w", err)
+		}
+		defer r.Body.Close()
+
+		rb, err := io.ReadAll(r.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+
+		if r.StatusCode >= 400 {
+			if r.StatusCode == http.StatusServiceUnavailable {
+				return util.NewRetryableError(fmt.Errorf("service unavailable"), 1*time.Second)
+			}
+			if r.StatusCode == http.StatusTooManyRequests {
+				c.logger.Info(fmt.Sprintf("%s\nRate limited, retrying after 60 seconds...\n", rb))
+				return util.NewRetryableError(fmt.Errorf("too many requests, retrying after 60 seconds"), 60*time.Second)
+			}
+			return &util.StatusError{StatusCode: r.StatusCode, Err: fmt.Errorf("invalid request or server error %s", rb)}
+		}
+
+		if err := json.Unmarshal(rb, &resp); err != nil {
+			return fmt.Errorf("failed to unmarshal response body: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+type GeminiResponse struct {
+	Candidates []struct {
+		Content      GeminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	UsageMetadata struct {
+		PromptTokenCount        int64 `json:"promptTokenCount"`
+		CandidatesTokenCount    int64 `json:"candidatesTokenCount"`
+		CachedContentTokenCount int64 `json:"cachedContentTokenCount"`
+		ThoughtsTokenCount      int64 `json:"thoughtsTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+type GeminiContent struct {
+	Role  string       `json:"role"`
+	Parts []GeminiPart `json:"parts"`
+}
+
+type GeminiPart struct {
+	Text         string              `json:"text,omitempty"`
+	FunctionCall *GeminiFunctionCall `json:"functionCall,omitempty"`
+
+	// ThoughtSignature must be returned to the model as it is to keep the thinking of the model
+	ThoughtSignature string `json:"thoughtSignature,omitempty"`
+	Thought          bool   `json:"thought,omitempty"`
+}
+
+type GeminiFunctionCall struct {
+	ID   string         `json:"id,omitempty"`
+	Name string         `json:"name"`
+	Args map[string]any `json:"args"`
+}
@@ -0,0 +1,282 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/clover0/issue-agent/core"
+	"github.com/clover0/issue-agent/logger"
+)
+
+// geminiCallIDPrefix is the prefix of the tool caller IDs generated for the function calls without ID.
+// The generated IDs are not sent to Gemini.
+const geminiCallIDPrefix = "gemini_call_"
+
+type GeminiLLMForwarder struct {
+	gemini        GeminiClient
+	forwardLogger logger.Logger
+	receiveLogger logger.Logger
+}
+
+func NewGeminiLLMForwarder(l logger.Logger) (core.LLMForwarder, error) {
+	apiKey, ok := os.LookupEnv("GEMINI_API_KEY")
+	if !ok {
+		return nil, fmt.Errorf("GEMINI_API_KEY is not set")
+	}
+
+	return newGeminiLLMForwarder(l, NewGemini(l, apiKey)), nil
+}
+
+func newGeminiLLMForwarder(l logger.Logger, client GeminiClient) GeminiLLMForwarder {
+	return GeminiLLMForwarder{
+		gemini:        client,
+		forwardLogger: l.AddPrefix("[GeminiForwarder] ").SetColor(logger.Green),
+		receiveLogger: l.AddPrefix("[GeminiReceive] ").SetColor(logger.Yellow),
+	}
+}
+
+func (g GeminiLLMForwarder) StartForward(input core.StartCompletionInput) ([]core.LLMMessage, error) {
+	history := []core.LLMMessage{
+		{
+			Role:       core.LLMUser,
+			RawContent: input.StartUserPrompt,
+		},
+	}
+
+	g.forwardLogger.Info(fmt.Sprintf("model: %s, sending message\n", input.Model))
+	g.forwardLogger.Debug("system prompt:\n%s\n", input.SystemPrompt)
+	g.forwardLogger.Debug("user prompt:\n%s\n", input.StartUserPrompt)
+
+	return g.generate(context.TODO(), input, history)
+}
+
+func (g GeminiLLMForwarder) ForwardLLM(
+	ctx context.Context,
+	input core.StartCompletionInput,
+	llmContexts []core.ReturnToLLMContext,
+	history []core.LLMMessage,
+) ([]core.LLMMessage, error) {
+	var newMsg core.LLMMessage
+	for _, v := range llmContexts {
+		if v.ToolCallerID != "" {
+			newMsg = core.LLMMessage{
+				Role:       core.LLMTool,
+				RawContent: v.Content,
+				RespondToolCall: core.ToolCall{
+					ToolCallerID: v.ToolCallerID,
+					ToolName:     v.ToolName,
+				},
+			}
+		} else {
+			newMsg = core.LLMMessage{
+				Role:       core.LLMUser,
+				RawContent: v.Content,
+			}
+		}
+		history = append(history, newMsg)
+	}
+
+	g.forwardLogger.Info(fmt.Sprintf("model: %s, sending message\n", input.Model))
+	g.forwardLogger.Debug("%s\n", newMsg.TruncatedRawContent("... truncated in debug output ..."))
+
+	return g.generate(ctx, input, history)
+}
+
+// generate sends the whole history to Gemini and returns the history with the response.
+func (g GeminiLLMForwarder) generate(ctx context.Context, input core.StartCompletionInput, history []core.LLMMessage) ([]core.LLMMessage, error) {
+	contents, err := toGeminiContents(history)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := g.gemini.GenerateContent(ctx, input.Model, J{
+		"systemInstruction": J{
+			"parts": []J{{"text": input.SystemPrompt}},
+		},
+		"contents":         contents,
+		"tools":            toGeminiTools(input.Tools),
+		"generationConfig": J{"temperature": 0.0},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	lastMsg, err := toGeminiLLMMessage(resp, len(history))
+	if err != nil {
+		return nil, err
+	}
+	history = append(history, lastMsg)
+
+	g.receiveLogger.Info("returned messages:\n")
+	lastMsg.ShowAssistantMessage(g.receiveLogger)
+
+	return history, nil
+}
+
+func (g GeminiLLMForwarder) ForwardStep(_ context.Context, history []core.LLMMessage) core.Step {
+	lastMsg := history[len(history)-1]
+
+	switch lastMsg.FinishReason {
+	case core.FinishStop:
+		return core.NewWaitingInstructionStep(lastMsg.RawContent)
+	case core.FinishToolCalls:
+		var input []core.FunctionsInput
+		for _, v := range lastMsg.ReturnedToolCalls {
+			input = append(input, core.FunctionsInput{
+				FuncName:     v.ToolName,
+				FunctionArgs: v.Argument,
+				ToolCallerID: v.ToolCallerID,
+			})
+		}
+		return core.NewExecStep(input)
+	case core.FinishLengthOver:
+		return core.NewLengthOverStep(history)
+	}
+
+	return core.NewUnrecoverableStep(fmt.Errorf("generation stopped by %s", lastMsg.FinishReason))
+}
+
+// toGeminiContents converts the history to the contents of Gemini.
+// The consecutive messages of the same role are merged into a content,
+// because Gemini requires the responses of the function calls in a turn.
+func toGeminiContents(history []core.LLMMessage) ([]J, error) {
+	var contents []J
+	add := func(role string, parts ...any) {
+		if len(contents) > 0 && contents[len(contents)-1]["role"] == role {
+			contents[len(contents)-1]["parts"] = append(contents[len(contents)-1]["parts"].([]any), parts...)
+			return
+		}
+		contents = append(contents, J{"role": role, "parts": parts})
+	}
+
+	// function names by the tool caller ID, for the responses without the name
+	names := map[string]string{}
+	for _, h := range history {
+		switch h.Role {
+		case core.LLMAssistant:
+			for _, v := range h.ReturnedToolCalls {
+				names[v.ToolCallerID] = v.ToolName
+			}
+
+			// the parts returned by Gemini keep the thought signatures.
+			// RawMessageStruct is lost when the history is restored from a checkpoint.
+			if parts, ok := h.RawMessageStruct.([]GeminiPart); ok {
+				for _, p := range parts {
+					add("model", p)
+				}
+				continue
+			}
+
+			var parts []any
+			if h.RawContent != "" {
+				parts = append(parts, J{"text": h.RawContent})
+			}
+			for _, v := range h.ReturnedToolCalls {
+				var args map[string]any
+				if err := json.Unmarshal([]byte(v.Argument), &args); err != nil {
+					return nil, fmt.Errorf("failed to unmarshal tool argument: %w", err)
+				}
+				call := J{"name": v.ToolName, "args": args}
+				if !strings.HasPrefix(v.ToolCallerID, geminiCallIDPrefix) {
+					call["id"] = v.ToolCallerID
+				}
+				parts = append(parts, J{"functionCall": call})
+			}
+			add("model", parts...)
+
+		case core.LLMUser:
+			add("user", J{"text": h.RawContent})
+
+		case core.LLMTool:
+			id := h.RespondToolCall.ToolCallerID
+			name := h.RespondToolCall.ToolName
+			if name == "" {
+				name = names[id]
+			}
+			resp := J{"name": name, "response": J{"content": h.RawContent}}
+			if !strings.HasPrefix(id, geminiCallIDPrefix) {
+				resp["id"] = id
+			}
+			add("user", J{"functionResponse": resp})
+
+		case core.LLMSystem:
+			// the system prompt is sent as the system instruction. The history returned by OpenAI has the system message.
+			continue
+
+		default:
+			return nil, fmt.Errorf("unknown role: %s", h.Role)
+		}
+	}
+
+	return contents, nil
+}
+
+// toGeminiLLMMessage converts the response to the message.
+// The function calls without ID are given IDs unique in the history by the index of the message.
+func toGeminiLLMMessage(resp *GeminiResponse, index int) (core.LLMMessage, error) {
+	if len(resp.Candidates) == 0 {
+		return core.LLMMessage{}, fmt.Errorf("gemini returned no candidates")
+	}
+	candidate := resp.Candidates[0]
+
+	var texts []string
+	var toolCalls []core.ToolCall
+	for i, part := range candidate.Content.Parts {
+		if part.FunctionCall != nil {
+			args, err := json.Marshal(part.FunctionCall.Args)
+			if err != nil {
+				return core.LLMMessage{}, fmt.Errorf("failed to marshal function call args: %w", err)
+			}
+			if part.FunctionCall.Args == nil {
+				args = []byte("{}")
+			}
+
+			id := part.FunctionCall.ID
+			if id == "" {
+				id = fmt.Sprintf("%s%d_%d", geminiCallIDPrefix, index, i)
+			}
+			toolCalls = append(toolCalls, core.ToolCall{
+				ToolCallerID: id,
+				ToolName:     part.FunctionCall.Name,
+				Argument:     string(args),
+			})
+			continue
+		}
+		if part.Text != "" && !part.Thought {
+			texts = append(texts, part.Text)
+		}
+	}
+
+	usage := resp.UsageMetadata
+	return core.LLMMessage{
+		Role:              core.LLMAssistant,
+		RawContent:        strings.Join(texts, "\n"),
+		FinishReason:      convertGeminiFinishReason(candidate.FinishReason, len(toolCalls) > 0),
+		ReturnedToolCalls: toolCalls,
+		RawMessageStruct:  candidate.Content.Parts,
+		Usage: core.LLMUsage{
+			InputToken:     usage.PromptTokenCount - usage.CachedContentTokenCount,
+			OutputToken:    usage.CandidatesTokenCount + usage.ThoughtsTokenCount,
+			CacheReadToken: usage.CachedContentTokenCount,
+		},
+	}, nil
+}
+
+// convertGeminiFinishReason converts the finish reason.
+// Gemini finishes with STOP when it calls functions as well.
+// The other reasons such as SAFETY are kept to stop the agent.
+func convertGeminiFinishReason(reason string, calledFunctions bool) core.MessageFinishReason {
+	switch {
+	case reason == "MAX_TOKENS":
+		return core.FinishLengthOver
+	case calledFunctions:
+		return core.FinishToolCalls
+	case reason == "STOP":
+		return core.FinishStop
+	default:
+		return core.MessageFinishReason(strings.ToLower(reason))
+	}
+}
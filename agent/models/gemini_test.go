@@ -0,0 +1,83 @@
+package models_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clover0/issue-agent/core"
+	"github.com/clover0/issue-agent/models"
+	"github.com/clover0/issue-agent/test/assert"
+	"github.com/clover0/issue-agent/test/loggertest"
+)
+
+func TestGeminiLLMForwarder(t *testing.T) {
+	t.Parallel()
+
+	responses := []string{
+		`{
+  "candidates": [{
+    "content": {"role": "model", "parts": [
+      {"text": "I open the file."},
+      {"functionCall": {"name": "open_file", "args": {"path": "README.md"}}}
+    ]},
+    "finishReason": "STOP"
+  }],
+  "usageMetadata": {"promptTokenCount": 100, "candidatesTokenCount": 10, "cachedContentTokenCount": 40, "thoughtsTokenCount": 5}
+}`,
+		`{
+  "candidates": [{
+    "content": {"role": "model", "parts": [{"text": "done"}]},
+    "finishReason": "STOP"
+  }],
+  "usageMetadata": {"promptTokenCount": 120, "candidatesTokenCount": 2}
+}`,
+	}
+	var requests []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.URL.Path, "/v1beta/models/gemini-2.5-pro:generateContent")
+		assert.Equal(t, r.Header.Get("x-goog-api-key"), "test-key")
+
+		var body map[string]any
+		b, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(b, &body)
+		requests = append(requests, body)
+
+		_, _ = w.Write([]byte(responses[len(requests)-1]))
+	}))
+	defer server.Close()
+
+	forwarder := models.NewGeminiLLMForwarderWithBaseURL(loggertest.NewTestLogger(), "test-key", server.URL)
+	input := core.StartCompletionInput{
+		Model:           "gemini-2.5-pro",
+		SystemPrompt:    "system",
+		StartUserPrompt: "user",
+	}
+
+	history, err := forwarder.StartForward(input)
+	assert.NoError(t, err)
+
+	first := history[len(history)-1]
+	assert.Equal(t, first.RawContent, "I open the file.")
+	assert.Equal(t, first.FinishReason, core.FinishToolCalls)
+	assert.Equal(t, first.Usage, core.LLMUsage{InputToken: 60, OutputToken: 15, CacheReadToken: 40})
+	assert.Equal(t, len(first.ReturnedToolCalls), 1)
+	call := first.ReturnedToolCalls[0]
+	assert.Equal(t, call.ToolName, "open_file")
+	assert.Equal(t, call.Argument, `{"path":"README.md"}`)
+
+	history, err = forwarder.ForwardLLM(t.Context(), input, []core.ReturnToLLMContext{
+		{ToolCallerID: call.ToolCallerID, ToolName: "open_file", Content: "# README"},
+	}, history)
+	assert.NoError(t, err)
+	assert.Equal(t, history[len(history)-1].RawContent, "done")
+	assert.Equal(t, forwarder.ForwardStep(t.Context(), history).Do, core.WaitingInstruction)
+
+	// the generated tool caller ID is not sent to Gemini
+	assert.Equal(t, mustJSON(t, requests[1]["contents"]), `[`+
+		`{"parts":[{"text":"user"}],"role":"user"},`+
+		`{"parts":[{"text":"I open the file."},{"functionCall":{"args":{"path":"README.md"},"name":"open_file"}}],"role":"model"},`+
+		`{"parts":[{"functionResponse":{"name":"open_file","response":{"content":"# README"}}}],"role":"user"}]`)
+}
@@ -9,10 +9,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
 
+	"github.com/clover0/issue-agent/config"
 	"github.com/clover0/issue-agent/core"
 	"github.com/clover0/issue-agent/logger"
 	"github.com/clover0/issue-agent/util"
@@ -22,6 +26,16 @@ type OpenAI struct {
 	client        openai.Client
 	forwardLogger logger.Logger
 	receiveLogger logger.Logger
+	options       openAIOptions
+
+	// streamIdleTimeout is the idle timeout of the stream. Zero means no streaming.
+	streamIdleTimeout time.Duration
+}
+
+// openAIOptions turn off the parameters that the models of OpenAI-compatible endpoints do not support.
+type openAIOptions struct {
+	noParallelToolCalls bool
+	noTemperature       bool
 }
 
 func NewOpenAI(lo logger.Logger, apiKey string) OpenAI {
@@ -36,14 +50,54 @@ func NewOpenAI(lo logger.Logger, apiKey string) OpenAI {
 	}
 }
 
-func (o OpenAI) createCompletionParams(input core.StartCompletionInput) (openai.ChatCompletionNewParams, []core.LLMMessage) {
-	toolFuncs := make([]openai.ChatCompletionToolParam, len(input.Functions))
-	for i, f := range input.Functions {
-		toolFuncs[i] = openai.ChatCompletionToolParam{
-			Function: f.ToFunctionCalling(),
+// NewOpenAICompatible creates the client of the OpenAI-compatible endpoint.
+func NewOpenAICompatible(lo logger.Logger, endpoint config.OpenAICompatible) (OpenAI, error) {
+	opts := []option.RequestOption{
+		option.WithBaseURL(endpoint.BaseURL),
+		// the credentials of OpenAI in the environment variables must not be sent to the other endpoints
+		option.WithHeaderDel("authorization"),
+		option.WithHeaderDel("OpenAI-Organization"),
+		option.WithHeaderDel("OpenAI-Project"),
+	}
+	if endpoint.APIKeyEnv != "" {
+		apiKey, ok := os.LookupEnv(endpoint.APIKeyEnv)
+		if !ok {
+			return OpenAI{}, fmt.Errorf("%s is not set", endpoint.APIKeyEnv)
+		}
+		if endpoint.APIKeyHeader == "" {
+			opts = append(opts, option.WithHeader("authorization", "Bearer "+apiKey))
+		} else {
+			opts = append(opts, option.WithHeader(endpoint.APIKeyHeader, apiKey))
+		}
+	}
+	for k, v := range endpoint.QueryParams {
+		opts = append(opts, option.WithQuery(k, v))
+	}
+
+	prefix := fmt.Sprintf("[OpenAICompatible:%s]", endpoint.Name)
+	return OpenAI{
+		forwardLogger: lo.AddPrefix(prefix + "[Forwarder] ").SetColor(logger.Green),
+		receiveLogger: lo.AddPrefix(prefix + "[Receive] ").SetColor(logger.Yellow),
+		client:        openai.NewClient(opts...),
+		options: openAIOptions{
+			noParallelToolCalls: endpoint.Capabilities.ParallelToolCalls != nil && !*endpoint.Capabilities.ParallelToolCalls,
+			noTemperature:       endpoint.Capabilities.Temperature != nil && !*endpoint.Capabilities.Temperature,
+		},
+	}, nil
+}
+
+// isOpenAIReasoningModel reports whether the model is a reasoning model of OpenAI, such as o3.
+// The reasoning models do not accept the temperature parameter.
+func isOpenAIReasoningModel(model string) bool {
+	for _, prefix := range []string{"o1", "o3", "o4"} {
+		if model == prefix || strings.HasPrefix(model, prefix+"-") {
+			return true
 		}
 	}
+	return false
+}
 
+func (o OpenAI) createCompletionParams(input core.StartCompletionInput) (openai.ChatCompletionNewParams, []core.LLMMessage) {
 	historyInitial := []core.LLMMessage{
 		{
 			Role:       core.LLMSystem,
@@ -55,15 +109,22 @@ func (o OpenAI) createCompletionParams(input core.StartCompletionInput) (openai.
 		},
 	}
 
-	return openai.ChatCompletionNewParams{
+	params := openai.ChatCompletionNewParams{
 		Model: input.Model,
 		Messages: []openai.ChatCompletionMessageParamUnion{
 			openai.SystemMessage(input.SystemPrompt),
 			openai.UserMessage(input.StartUserPrompt),
 		},
-		Temperature: openai.Float(0.0),
-		Tools:       toolFuncs,
-	}, historyInitial
+		Tools: toOpenAITools(input.Tools),
+	}
+	if !o.options.noTemperature && !isOpenAIReasoningModel(input.Model) {
+		params.Temperature = openai.Float(0.0)
+	}
+	if o.options.noParallelToolCalls {
+		params.ParallelToolCalls = openai.Bool(false)
+	}
+
+	return params, historyInitial
 }
 
 func (o OpenAI) StartCompletion(ctx context.Context, input core.StartCompletionInput) ([]core.LLMMessage, error) {
@@ -74,7 +135,7 @@ func (o OpenAI) StartCompletion(ctx context.Context, input core.StartCompletionI
 	o.forwardLogger.Info(fmt.Sprintf("model: %s, sending message\n", input.Model))
 	o.forwardLogger.Debug("system prompt:\n%s\n", input.SystemPrompt)
 	o.forwardLogger.Debug("user prompt:\n%s\n", input.StartUserPrompt)
-	chat, err := o.client.Chat.Completions.New(ctx, params)
+	chat, err := o.complete(ctx, params)
 	if err != nil {
 		return nil, err
 	}
@@ -113,16 +174,19 @@ func (o OpenAI) ContinueCompletion(
 
 	// build from history
 	params.Messages = []openai.ChatCompletionMessageParamUnion{}
+	// the history returned by the other providers has no system message
+	if len(history) > 0 && history[0].Role != core.LLMSystem {
+		params.Messages = append(params.Messages, openai.SystemMessage(input.SystemPrompt))
+	}
 	for _, h := range history {
 		switch h.Role {
 		case core.LLMAssistant:
-			if h.RawMessageStruct == nil {
-				return nil, errors.New("rawMessageStruct should not be nil. But it is nil")
-			}
-
+			// RawMessageStruct is lost when the history is restored from a checkpoint,
+			// and it is the struct of the other provider when the history is returned by a fallback model.
 			m, ok := h.RawMessageStruct.(openai.ChatCompletionMessage)
 			if !ok {
-				return nil, errors.New("RawMessageStruct can't convert ChatCompletionMessage")
+				params.Messages = append(params.Messages, toAssistantMessageParam(h))
+				continue
 			}
 
 			params.Messages = append(params.Messages, m.ToParam())
@@ -163,7 +227,7 @@ func (o OpenAI) ContinueCompletion(
 	}
 
 	o.debugShowSendingMsg(params)
-	chat, err := o.client.Chat.Completions.New(ctx, params)
+	chat, err := o.complete(ctx, params)
 	if err != nil {
 		return nil, fmt.Errorf("continue completion error: %w", err)
 	}
@@ -188,6 +252,99 @@ func (o OpenAI) ContinueCompletion(
 	return history, nil
 }
 
+// complete creates the chat completion, receiving the response as a stream when streaming is enabled.
+// The stream is retried when no chunk arrives within the idle timeout.
+func (o OpenAI) complete(ctx context.Context, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	if o.streamIdleTimeout == 0 {
+		chat, err := o.client.Chat.Completions.New(ctx, params)
+		if err != nil {
+			return nil, statusError(err)
+		}
+		return chat, nil
+	}
+
+	params.StreamOptions = openai.ChatCompletionStreamOptionsParam{IncludeUsage: openai.Bool(true)}
+
+	var chat *openai.ChatCompletion
+	err := util.Retry(3, func() error {
+		var err error
+		chat, err = o.completeStream(ctx, params)
+		if errors.Is(err, errStreamIdle) {
+			return util.NewRetryableError(err, 1*time.Second)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return chat, nil
+}
+
+// statusError keeps the HTTP status code of the API error.
+func statusError(err error) error {
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		return &util.StatusError{StatusCode: apiErr.StatusCode, Err: err}
+	}
+	return err
+}
+
+// completeStream assembles the chat completion from the chunks of the stream.
+func (o OpenAI) completeStream(ctx context.Context, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	streamCtx, touch, isIdle, cancel := idleContext(ctx, o.streamIdleTimeout)
+	defer cancel()
+
+	deltas := newDeltaLogger(o.receiveLogger)
+	defer deltas.Flush()
+
+	stream := o.client.Chat.Completions.NewStreaming(streamCtx, params)
+	defer stream.Close()
+
+	acc := openai.ChatCompletionAccumulator{}
+	for stream.Next() {
+		touch()
+		chunk := stream.Current()
+		acc.AddChunk(chunk)
+		for _, c := range chunk.Choices {
+			deltas.Write(c.Delta.Content)
+			for _, t := range c.Delta.ToolCalls {
+				deltas.Write(t.Function.Arguments)
+			}
+		}
+	}
+	if err := stream.Err(); err != nil {
+		if isIdle() {
+			return nil, errStreamIdle
+		}
+		return nil, err
+	}
+	if len(acc.Choices) == 0 {
+		return nil, fmt.Errorf("stream ended without choices")
+	}
+
+	return &acc.ChatCompletion, nil
+}
+
+// toAssistantMessageParam builds the assistant message from the provider-neutral message.
+func toAssistantMessageParam(msg core.LLMMessage) openai.ChatCompletionMessageParamUnion {
+	assistant := openai.ChatCompletionAssistantMessageParam{}
+	if msg.RawContent != "" {
+		assistant.Content.OfString = openai.String(msg.RawContent)
+	}
+	for _, v := range msg.ReturnedToolCalls {
+		assistant.ToolCalls = append(assistant.ToolCalls, openai.ChatCompletionMessageToolCallParam{
+			ID: v.ToolCallerID,
+			Function: openai.ChatCompletionMessageToolCallFunctionParam{
+				Name:      v.ToolName,
+				Arguments: v.Argument,
+			},
+		})
+	}
+
+	return openai.ChatCompletionMessageParamUnion{OfAssistant: &assistant}
+}
+
 func convertToFinishReason(finishReason string) core.MessageFinishReason {
 	switch finishReason {
 	case "length":
@@ -197,7 +354,8 @@ func convertToFinishReason(finishReason string) core.MessageFinishReason {
 	case "tool_calls":
 		return core.FinishToolCalls
 	default:
-		panic(fmt.Sprintf("convertToFinishReason: unknown finish reason: %s", finishReason))
+		// such as content_filter, which stops the agent
+		return core.MessageFinishReason(finishReason)
 	}
 }
 
@@ -231,10 +389,10 @@ func (o OpenAI) CompletionNextStep(_ context.Context, history []core.LLMMessage)
 		}
 		return core.NewExecStep(input)
 	case core.FinishLengthOver:
-		return core.NewUnrecoverableStep(fmt.Errorf("chat completion length error"))
+		return core.NewLengthOverStep(history)
 	}
 
-	return core.NewUnknownStep()
+	return core.NewUnrecoverableStep(fmt.Errorf("generation stopped by %s", lastMsg.FinishReason))
 }
 
 func (o OpenAI) debugShowSendingMsg(param openai.ChatCompletionNewParams) {
@@ -4,9 +4,10 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"time"
 
+	"github.com/clover0/issue-agent/config"
 	"github.com/clover0/issue-agent/core"
-	"github.com/clover0/issue-agent/core/functions"
 	"github.com/clover0/issue-agent/logger"
 )
 
@@ -25,16 +26,25 @@ func NewOpenAILLMForwarder(l logger.Logger) (core.LLMForwarder, error) {
 	}, nil
 }
 
+// NewOpenAICompatibleLLMForwarder creates the forwarder to the OpenAI-compatible endpoint.
+func NewOpenAICompatibleLLMForwarder(l logger.Logger, endpoint config.OpenAICompatible) (core.LLMForwarder, error) {
+	o, err := NewOpenAICompatible(l, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("endpoint %s: %w", endpoint.Name, err)
+	}
+
+	return OpenAILLMForwarder{
+		openai: o,
+	}, nil
+}
+
+func (o OpenAILLMForwarder) withStreaming(idleTimeout time.Duration) core.LLMForwarder {
+	o.openai.streamIdleTimeout = idleTimeout
+	return o
+}
+
 func (o OpenAILLMForwarder) StartForward(input core.StartCompletionInput) ([]core.LLMMessage, error) {
-	return o.openai.StartCompletion(
-		context.TODO(),
-		core.StartCompletionInput{
-			Model:           input.Model,
-			SystemPrompt:    input.SystemPrompt,
-			StartUserPrompt: input.StartUserPrompt,
-			Functions:       functions.AllFunctions(),
-		},
-	)
+	return o.openai.StartCompletion(context.TODO(), input)
 }
 
 func (o OpenAILLMForwarder) ForwardLLM(
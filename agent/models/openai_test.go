@@ -0,0 +1,81 @@
+package models_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clover0/issue-agent/config"
+	"github.com/clover0/issue-agent/core"
+	"github.com/clover0/issue-agent/models"
+	"github.com/clover0/issue-agent/test/assert"
+	"github.com/clover0/issue-agent/test/loggertest"
+	"github.com/clover0/issue-agent/util/pointer"
+)
+
+const stubChatCompletion = `{
+  "id": "chatcmpl-1",
+  "object": "chat.completion",
+  "created": 1700000000,
+  "model": "local-model",
+  "choices": [{
+    "index": 0,
+    "finish_reason": "stop",
+    "message": {"role": "assistant", "content": "done"}
+  }],
+  "usage": {"prompt_tokens": 12, "completion_tokens": 3, "total_tokens": 15}
+}`
+
+func TestNewOpenAICompatibleLLMForwarder(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "openai-key")
+	t.Setenv("STUB_API_KEY", "stub-key")
+
+	var gotHeader http.Header
+	var gotQuery string
+	var gotBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Clone()
+		gotQuery = r.URL.Query().Get("api-version")
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &gotBody)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(stubChatCompletion))
+	}))
+	defer server.Close()
+
+	forwarder, err := models.NewOpenAICompatibleLLMForwarder(loggertest.NewTestLogger(), config.OpenAICompatible{
+		Name:         "stub",
+		BaseURL:      server.URL,
+		APIKeyEnv:    "STUB_API_KEY",
+		APIKeyHeader: "api-key",
+		QueryParams:  map[string]string{"api-version": "2024-10-21"},
+		Models:       []string{"local-model"},
+		Capabilities: config.ModelCapabilities{
+			ParallelToolCalls: pointer.Ptr(false),
+			Temperature:       pointer.Ptr(false),
+		},
+	})
+	assert.NoError(t, err)
+
+	history, err := forwarder.StartForward(core.StartCompletionInput{
+		Model:           "local-model",
+		SystemPrompt:    "system",
+		StartUserPrompt: "user",
+	})
+	assert.NoError(t, err)
+
+	last := history[len(history)-1]
+	assert.Equal(t, last.RawContent, "done")
+	assert.Equal(t, last.FinishReason, core.FinishStop)
+	assert.Equal(t, last.Usage.InputToken, int64(12))
+
+	assert.Equal(t, gotHeader.Get("api-key"), "stub-key")
+	assert.Equal(t, gotHeader.Get("Authorization"), "")
+	assert.Equal(t, gotQuery, "2024-10-21")
+	assert.Equal(t, gotBody["model"], any("local-model"))
+	assert.Equal(t, gotBody["parallel_tool_calls"], any(false))
+	_, hasTemperature := gotBody["temperature"]
+	assert.Equal(t, hasTemperature, false)
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"strings"
+
+	"github.com/clover0/issue-agent/core"
+)
+
+// modelPrices is the price table of the models in USD per million tokens.
+// Models are matched by containing the name, so the more specific name must come first.
+// Bedrock model IDs such as us.anthropic.claude-sonnet-4-20250514-v1:0 are matched as well.
+var modelPrices = []struct {
+	name  string
+	price core.ModelPrice
+}{
+	// https://docs.anthropic.com/en/docs/about-claude/pricing
+	{"claude-opus-4", core.ModelPrice{Input: 15, Output: 75, CacheRead: 1.5, CacheWrite: 18.75}},
+	{"claude-sonnet-4", core.ModelPrice{Input: 3, Output: 15, CacheRead: 0.3, CacheWrite: 3.75}},
+	{"claude-3-7-sonnet", core.ModelPrice{Input: 3, Output: 15, CacheRead: 0.3, CacheWrite: 3.75}},
+	{"claude-3-5-sonnet", core.ModelPrice{Input: 3, Output: 15, CacheRead: 0.3, CacheWrite: 3.75}},
+	{"claude-3-5-haiku", core.ModelPrice{Input: 0.8, Output: 4, CacheRead: 0.08, CacheWrite: 1}},
+
+	// https://openai.com/api/pricing/
+	{"gpt-4o-mini", core.ModelPrice{Input: 0.15, Output: 0.6, CacheRead: 0.075}},
+	{"gpt-4o", core.ModelPrice{Input: 2.5, Output: 10, CacheRead: 1.25}},
+	{"gpt-4.1-nano", core.ModelPrice{Input: 0.1, Output: 0.4, CacheRead: 0.025}},
+	{"gpt-4.1-mini", core.ModelPrice{Input: 0.4, Output: 1.6, CacheRead: 0.1}},
+	{"gpt-4.1", core.ModelPrice{Input: 2, Output: 8, CacheRead: 0.5}},
+
+	// https://ai.google.dev/gemini-api/docs/pricing
+	// the prices of prompts up to 200k tokens
+	{"gemini-2.5-pro", core.ModelPrice{Input: 1.25, Output: 10, CacheRead: 0.31}},
+	{"gemini-2.5-flash-lite", core.ModelPrice{Input: 0.1, Output: 0.4, CacheRead: 0.025}},
+	{"gemini-2.5-flash", core.ModelPrice{Input: 0.3, Output: 2.5, CacheRead: 0.075}},
+}
+
+// PriceOf returns the price of the model. It returns false for the unknown models.
+func PriceOf(model string) (core.ModelPrice, bool) {
+	for _, p := range modelPrices {
+		if strings.Contains(model, p.name) {
+			return p.price, true
+		}
+	}
+	return core.ModelPrice{}, false
+}
@@ -0,0 +1,49 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/clover0/issue-agent/core"
+	"github.com/clover0/issue-agent/models"
+	"github.com/clover0/issue-agent/test/assert"
+)
+
+func TestPriceOf(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		model  string
+		wantOK bool
+		want   core.ModelPrice
+	}{
+		"Anthropic model": {
+			model:  "claude-sonnet-4-20250514",
+			wantOK: true,
+			want:   core.ModelPrice{Input: 3, Output: 15, CacheRead: 0.3, CacheWrite: 3.75},
+		},
+		"AWS Bedrock model ID": {
+			model:  "us.anthropic.claude-opus-4-20250514-v1:0",
+			wantOK: true,
+			want:   core.ModelPrice{Input: 15, Output: 75, CacheRead: 1.5, CacheWrite: 18.75},
+		},
+		"more specific name is matched first": {
+			model:  "gpt-4o-mini",
+			wantOK: true,
+			want:   core.ModelPrice{Input: 0.15, Output: 0.6, CacheRead: 0.075},
+		},
+		"unknown model": {
+			model:  "unknown-model",
+			wantOK: false,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := models.PriceOf(tt.model)
+			assert.Equal(t, ok, tt.wantOK)
+			assert.Equal(t, got, tt.want)
+		})
+	}
+}
@@ -2,18 +2,22 @@ package models
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
+	"github.com/clover0/issue-agent/config"
 	"github.com/clover0/issue-agent/core"
 	"github.com/clover0/issue-agent/logger"
 	"github.com/clover0/issue-agent/util"
 )
 
+// SelectForwarder selects the forwarder by the prefix of the model name.
+// It is the fallback when the provider is not specified.
 func SelectForwarder(lo logger.Logger, model string) (core.LLMForwarder, error) {
 	if util.IsAWSBedrockModel(model) {
 		return NewBedrockLLMForwarder(lo)
 	}
-	if strings.HasPrefix(model, "gpt") {
+	if strings.HasPrefix(model, "gpt") || isOpenAIReasoningModel(model) {
 		return NewOpenAILLMForwarder(lo)
 	}
 
@@ -21,9 +25,68 @@ func SelectForwarder(lo logger.Logger, model string) (core.LLMForwarder, error)
 		return NewAnthropicLLMForwarder(lo)
 	}
 
+	if strings.HasPrefix(model, "gemini") {
+		return NewGeminiLLMForwarder(lo)
+	}
+
 	if model == "" {
 		return nil, fmt.Errorf("model is not specified")
 	}
 
-	return nil, fmt.Errorf("SelectForwarder: model %s is not supported", model)
+	return nil, fmt.Errorf("SelectForwarder: provider of model %s is unknown, set agent.provider to one of %s",
+		model, strings.Join(config.Providers(), ", "))
+}
+
+// SelectForwarderByProvider selects the forwarder of the provider.
+func SelectForwarderByProvider(lo logger.Logger, provider string) (core.LLMForwarder, error) {
+	switch provider {
+	case config.ProviderAnthropic:
+		return NewAnthropicLLMForwarder(lo)
+	case config.ProviderOpenAI:
+		return NewOpenAILLMForwarder(lo)
+	case config.ProviderBedrock:
+		return NewBedrockLLMForwarder(lo)
+	case config.ProviderVertex:
+		return NewAnthropicVertexLLMForwarder(lo)
+	case config.ProviderGemini:
+		return NewGeminiLLMForwarder(lo)
+	}
+
+	return nil, fmt.Errorf("SelectForwarderByProvider: provider %s is not supported, supported providers: %s",
+		provider, strings.Join(config.Providers(), ", "))
+}
+
+// SelectForwarderByConfig returns the selector of the forwarder by the configuration.
+// The forwarder is selected in the following order:
+//  1. the OpenAI-compatible endpoint serving the model
+//  2. the provider
+//  3. the prefix of the model name by SelectForwarder
+//
+// The forwarder receives the responses as streams when streaming is enabled.
+func SelectForwarderByConfig(conf config.Agent) core.SelectForwarder {
+	return func(lo logger.Logger, provider string, model string) (core.LLMForwarder, error) {
+		forwarder, err := selectForwarderByConfig(conf, lo, provider, model)
+		if err != nil {
+			return nil, err
+		}
+
+		if conf.Streaming.Enabled {
+			return WithStreaming(forwarder, conf.Streaming.IdleTimeout), nil
+		}
+		return forwarder, nil
+	}
+}
+
+func selectForwarderByConfig(conf config.Agent, lo logger.Logger, provider string, model string) (core.LLMForwarder, error) {
+	for _, e := range conf.OpenAICompatible {
+		if slices.Contains(e.Models, model) {
+			return NewOpenAICompatibleLLMForwarder(lo, e)
+		}
+	}
+
+	if provider != "" {
+		return SelectForwarderByProvider(lo, provider)
+	}
+
+	return SelectForwarder(lo, model)
 }
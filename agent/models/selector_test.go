@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/clover0/issue-agent/config"
 	"github.com/clover0/issue-agent/core"
 	"github.com/clover0/issue-agent/models"
 	"github.com/clover0/issue-agent/test/assert"
@@ -17,6 +18,7 @@ func TestSelectForwarder(t *testing.T) {
 	// TODO: Make it clear which environment variables need to be set.
 	_ = os.Setenv("ANTHROPIC_API_KEY", "test")
 	_ = os.Setenv("OPENAI_API_KEY", "test")
+	_ = os.Setenv("GEMINI_API_KEY", "test")
 
 	mockLogger := loggertest.NewTestLogger()
 
@@ -40,6 +42,11 @@ func TestSelectForwarder(t *testing.T) {
 			wantErr:  false,
 			wantType: models.AnthropicLLMForwarder{},
 		},
+		"Gemini model": {
+			model:    "gemini-2.5-pro",
+			wantErr:  false,
+			wantType: models.GeminiLLMForwarder{},
+		},
 		"Empty model": {
 			model:    "",
 			wantErr:  true,
@@ -73,3 +80,69 @@ func TestSelectForwarder(t *testing.T) {
 		})
 	}
 }
+
+func TestSelectForwarderByConfig(t *testing.T) {
+	t.Parallel()
+
+	_ = os.Setenv("ANTHROPIC_API_KEY", "test")
+	_ = os.Setenv("OPENAI_API_KEY", "test")
+
+	endpoints := []config.OpenAICompatible{
+		{Name: "ollama", BaseURL: "http://localhost:11434/v1", Models: []string{"qwen2.5-coder"}},
+	}
+
+	tests := map[string]struct {
+		conf     config.Agent
+		provider string
+		model    string
+		wantErr  bool
+		wantType core.LLMForwarder
+	}{
+		"model of OpenAI-compatible endpoint": {
+			conf:     config.Agent{OpenAICompatible: endpoints},
+			model:    "qwen2.5-coder",
+			wantType: models.OpenAILLMForwarder{},
+		},
+		"other model": {
+			conf:     config.Agent{OpenAICompatible: endpoints},
+			model:    "claude-3",
+			wantType: models.AnthropicLLMForwarder{},
+		},
+		"unknown model": {
+			conf:    config.Agent{OpenAICompatible: endpoints},
+			model:   "llama3",
+			wantErr: true,
+		},
+		"provider over model name": {
+			provider: config.ProviderAnthropic,
+			model:    "my-claude-deployment",
+			wantType: models.AnthropicLLMForwarder{},
+		},
+		"OpenAI reasoning model without provider": {
+			conf:     config.Agent{},
+			model:    "o3-mini",
+			wantType: models.OpenAILLMForwarder{},
+		},
+		"unsupported provider": {
+			provider: "azure",
+			model:    "gpt-4o",
+			wantErr:  true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			forwarder, err := models.SelectForwarderByConfig(tt.conf)(loggertest.NewTestLogger(), tt.provider, tt.model)
+
+			if tt.wantErr {
+				assert.HasError(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, reflect.TypeOf(forwarder), reflect.TypeOf(tt.wantType))
+		})
+	}
+}
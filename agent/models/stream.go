@@ -0,0 +1,123 @@
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/clover0/issue-agent/core"
+	"github.com/clover0/issue-agent/logger"
+)
+
+const defaultStreamIdleTimeout = 2 * time.Minute
+
+var errStreamIdle = errors.New("no event from the stream within the idle timeout")
+
+// streamable is the forwarder which can receive the responses as streams.
+type streamable interface {
+	withStreaming(idleTimeout time.Duration) core.LLMForwarder
+}
+
+// WithStreaming returns the forwarder receiving the responses as streams, if the forwarder supports streaming.
+// The request is aborted when no event arrives within the idle timeout.
+func WithStreaming(forwarder core.LLMForwarder, idleTimeout time.Duration) core.LLMForwarder {
+	s, ok := forwarder.(streamable)
+	if !ok {
+		return forwarder
+	}
+	if idleTimeout == 0 {
+		idleTimeout = defaultStreamIdleTimeout
+	}
+	return s.withStreaming(idleTimeout)
+}
+
+// idleContext returns the context canceled with errStreamIdle unless touch is called within the timeout.
+// isIdle reports whether the context was canceled by the idle timeout.
+func idleContext(ctx context.Context, timeout time.Duration) (_ context.Context, touch func(), isIdle func() bool, cancel func()) {
+	ctx, cancelCause := context.WithCancelCause(ctx)
+	timer := time.AfterFunc(timeout, func() { cancelCause(errStreamIdle) })
+
+	return ctx,
+		func() { timer.Reset(timeout) },
+		func() bool { return errors.Is(context.Cause(ctx), errStreamIdle) },
+		func() {
+			timer.Stop()
+			cancelCause(nil)
+		}
+}
+
+// readSSE reads the server-sent events and calls handle with the event name and the data of each event.
+// touch is called for each line to extend the idle timeout.
+func readSSE(r io.Reader, touch func(), handle func(event string, data []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+
+	var event string
+	var data bytes.Buffer
+	for scanner.Scan() {
+		touch()
+		line := scanner.Text()
+
+		switch {
+		case line == "":
+			if data.Len() > 0 {
+				if err := handle(event, data.Bytes()); err != nil {
+					return err
+				}
+			}
+			event = ""
+			data.Reset()
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if data.Len() > 0 {
+		return handle(event, data.Bytes())
+	}
+	return nil
+}
+
+// deltaLogger writes the deltas of the stream to the debug log line by line.
+type deltaLogger struct {
+	lo   logger.Logger
+	line strings.Builder
+}
+
+func newDeltaLogger(lo logger.Logger) *deltaLogger {
+	return &deltaLogger{lo: lo}
+}
+
+func (d *deltaLogger) Write(delta string) {
+	for {
+		i := strings.IndexByte(delta, '\n')
+		if i < 0 {
+			d.line.WriteString(delta)
+			return
+		}
+		d.line.WriteString(delta[:i])
+		d.lo.Debug("%s\n", d.line.String())
+		d.line.Reset()
+		delta = delta[i+1:]
+	}
+}
+
+// Flush writes the rest of the line.
+func (d *deltaLogger) Flush() {
+	if d.line.Len() > 0 {
+		d.lo.Debug("%s\n", d.line.String())
+		d.line.Reset()
+	}
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/document"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/openai/openai-go"
+
+	"github.com/clover0/issue-agent/core/functions"
+	"github.com/clover0/issue-agent/util/pointer"
+)
+
+// Adapters converting the provider-neutral tool definitions to each provider's tool format.
+
+func toOpenAITools(defs []functions.ToolDefinition) []openai.ChatCompletionToolParam {
+	tools := make([]openai.ChatCompletionToolParam, len(defs))
+	for i, d := range defs {
+		tools[i] = openai.ChatCompletionToolParam{
+			Function: openai.FunctionDefinitionParam{
+				Name:        d.Name,
+				Description: openai.String(d.Description),
+				Parameters:  openai.FunctionParameters(d.Parameters),
+			},
+		}
+	}
+	return tools
+}
+
+func toAnthropicTools(defs []functions.ToolDefinition) []J {
+	tools := make([]J, len(defs))
+	for i, d := range defs {
+		tools[i] = J{
+			"name":         d.Name,
+			"description":  d.Description,
+			"input_schema": d.Parameters,
+		}
+	}
+	return tools
+}
+
+// toGeminiTools converts the definitions to the function declarations of Gemini.
+// Gemini accepts a subset of OpenAPI schema, so the unsupported keywords are removed.
+func toGeminiTools(defs []functions.ToolDefinition) []J {
+	declarations := make([]J, len(defs))
+	for i, d := range defs {
+		declarations[i] = J{
+			"name":        d.Name,
+			"description": d.Description,
+		}
+		// Gemini rejects objects without properties
+		if props, ok := d.Parameters["properties"].(map[string]any); ok && len(props) > 0 {
+			declarations[i]["parameters"] = toGeminiSchema(d.Parameters)
+		}
+	}
+	return []J{{"functionDeclarations": declarations}}
+}
+
+func toGeminiSchema(schema map[string]any) map[string]any {
+	converted := make(map[string]any, len(schema))
+	for k, v := range schema {
+		if k == "additionalProperties" {
+			continue
+		}
+		switch v := v.(type) {
+		case functions.JSONSchema:
+			converted[k] = toGeminiSchema(v)
+		case map[string]any:
+			converted[k] = toGeminiSchema(v)
+		default:
+			converted[k] = v
+		}
+	}
+	return converted
+}
+
+func toBedrockTools(defs []functions.ToolDefinition) []*types.ToolMemberToolSpec {
+	tools := make([]*types.ToolMemberToolSpec, len(defs))
+	for i, d := range defs {
+		tools[i] = &types.ToolMemberToolSpec{
+			Value: types.ToolSpecification{
+				Name:        pointer.String(d.Name),
+				Description: pointer.String(d.Description),
+				InputSchema: &types.ToolInputSchemaMemberJson{
+					Value: document.NewLazyDocument(map[string]any(d.Parameters)),
+				},
+			},
+		}
+	}
+	return tools
+}
@@ -0,0 +1,82 @@
+package models_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/clover0/issue-agent/core/functions"
+	"github.com/clover0/issue-agent/models"
+	"github.com/clover0/issue-agent/test/assert"
+)
+
+func TestToolAdapters(t *testing.T) {
+	t.Parallel()
+
+	defs := []functions.ToolDefinition{
+		{
+			Name:        "open_file",
+			Description: "Open the file",
+			Parameters: functions.JSONSchema{
+				"type": "object",
+				"properties": map[string]any{
+					"path": map[string]any{"type": "string"},
+				},
+				"required": []string{"path"},
+			},
+		},
+	}
+	wantSchema := `{"properties":{"path":{"type":"string"}},"required":["path"],"type":"object"}`
+
+	t.Run("OpenAI", func(t *testing.T) {
+		t.Parallel()
+
+		got := models.ToOpenAITools(defs)
+		assert.Equal(t, len(got), 1)
+		assert.Equal(t, got[0].Function.Name, "open_file")
+		assert.Equal(t, got[0].Function.Description.Value, "Open the file")
+		assert.Equal(t, mustJSON(t, got[0].Function.Parameters), wantSchema)
+	})
+
+	t.Run("Anthropic", func(t *testing.T) {
+		t.Parallel()
+
+		got := models.ToAnthropicTools(defs)
+		assert.Equal(t, mustJSON(t, got),
+			`[{"description":"Open the file","input_schema":`+wantSchema+`,"name":"open_file"}]`)
+	})
+
+	t.Run("Gemini", func(t *testing.T) {
+		t.Parallel()
+
+		strict := []functions.ToolDefinition{{
+			Name:        "open_file",
+			Description: "Open the file",
+			Parameters: functions.JSONSchema{
+				"type":                 "object",
+				"properties":           map[string]any{"path": functions.JSONSchema{"type": "string"}},
+				"required":             []string{"path"},
+				"additionalProperties": false,
+			},
+		}}
+
+		got := models.ToGeminiTools(strict)
+		assert.Equal(t, mustJSON(t, got),
+			`[{"functionDeclarations":[{"description":"Open the file","name":"open_file","parameters":`+wantSchema+`}]}]`)
+	})
+
+	t.Run("Bedrock", func(t *testing.T) {
+		t.Parallel()
+
+		got := models.ToBedrockTools(defs)
+		assert.Equal(t, len(got), 1)
+		assert.Equal(t, *got[0].Value.Name, "open_file")
+		assert.Equal(t, *got[0].Value.Description, "Open the file")
+	})
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	assert.NoError(t, err)
+	return string(b)
+}
@@ -1,9 +1,8 @@
-package agithub
+package platform
 
 import (
 	"errors"
 	"fmt"
-	"os"
 	"path"
 
 	"github.com/go-git/go-git/v5"
@@ -12,22 +11,22 @@ import (
 	"github.com/clover0/issue-agent/logger"
 )
 
-func CloneRepository(lo logger.Logger, owner string, workRepository string, refBranch string) error {
-	token, ok := os.LookupEnv("GITHUB_TOKEN")
-	if !ok {
-		lo.Error("GITHUB_TOKEN is not set")
-		return fmt.Errorf("GITHUB_TOKEN is not set")
+// CloneRepository clones the repository of the service to the directory named the repository.
+func CloneRepository(lo logger.Logger, service Service, workRepository string, refBranch string) error {
+	url, err := service.CloneURL()
+	if err != nil {
+		lo.Error("%s\n", err)
+		return err
 	}
 
 	lo.Info("cloning repository...\n")
 	if _, err := git.PlainClone(path.Join(".", workRepository), false, &git.CloneOptions{
-		URL: fmt.Sprintf("https://oauth2:%s@github.com/%s/%s.git",
-			token, owner, workRepository),
+		URL:           url,
 		Depth:         1,
 		ReferenceName: plumbing.ReferenceName(refBranch),
 	}); err != nil {
 		if errors.Is(err, plumbing.ErrReferenceNotFound) {
-			return fmt.Errorf("branch %s not found in repository %s/%s", refBranch, owner, workRepository)
+			return fmt.Errorf("branch %s not found in repository %s", refBranch, workRepository)
 		}
 		return err
 	}
@@ -0,0 +1,47 @@
+package platform
+
+import (
+	"context"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+
+	"github.com/clover0/issue-agent/core/functions"
+)
+
+// Service is the service of the platform hosting the repository, such as GitHub and GitLab.
+// The pull requests are the merge requests on GitLab.
+type Service interface {
+	// the operations called by functions
+	functions.GitHubService
+
+	GetBranch(ctx context.Context, branchName string) (string, error)
+
+	// GetComment gets the comment on the issue or the pull request.
+	// issueNumber is empty when the platform identifies the comment only by the comment ID, such as GitHub.
+	GetComment(ctx context.Context, issueNumber string, commentID string) (functions.GetCommentOutput, error)
+
+	// GetReviewComment gets the review comment on the lines of the pull request.
+	// prNumber is empty when the platform identifies the comment only by the comment ID, such as GitHub.
+	GetReviewComment(ctx context.Context, prNumber string, reviewID string) (functions.GetReviewOutput, error)
+
+	CreatePullRequest(ctx context.Context, input NewPullRequest) (PullRequest, error)
+
+	// CloneURL returns the URL to clone and push the repository with the credentials.
+	CloneURL() (string, error)
+
+	// PushAuth returns the auth to push to the repository.
+	// It is nil when the credentials of the remote URL are used.
+	PushAuth() (transport.AuthMethod, error)
+}
+
+type NewPullRequest struct {
+	Title  string
+	Head   string
+	Base   string
+	Body   string
+	Labels []string
+}
+
+type PullRequest struct {
+	Number int
+}
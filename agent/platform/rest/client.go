@@ -0,0 +1,102 @@
+// Package rest is the client of the JSON REST APIs of the platforms, such as GitLab and Gitea.
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Client sends the requests to the API with the headers of the platform, such as the auth header.
+type Client struct {
+	platform   string
+	apiURL     string
+	header     http.Header
+	httpClient *http.Client
+}
+
+// NewClient creates the client of the API at apiURL, such as https://gitlab.com/api/v4.
+// platform is the name of the platform in the errors.
+func NewClient(platform string, apiURL string, header http.Header, httpClient *http.Client) *Client {
+	return &Client{
+		platform:   platform,
+		apiURL:     apiURL,
+		header:     header,
+		httpClient: httpClient,
+	}
+}
+
+// ErrorResponse is the error response of the API.
+type ErrorResponse struct {
+	Platform   string
+	StatusCode int
+	Body       string
+}
+
+func (e *ErrorResponse) Error() string {
+	return fmt.Sprintf("%s API responded %d: %s", e.Platform, e.StatusCode, e.Body)
+}
+
+// IsNotFound reports whether the API responded 404.
+func IsNotFound(err error) bool {
+	var errResp *ErrorResponse
+	return errors.As(err, &errResp) && errResp.StatusCode == http.StatusNotFound
+}
+
+// Do sends the request to the path under the API URL and decodes the JSON response into out.
+// The path must be escaped. out can be nil to discard the response, or *[]byte to get the raw response.
+func (c *Client) Do(ctx context.Context, method string, path string, body any, out any) (*http.Response, error) {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		reqBody = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.apiURL+path, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for k, v := range c.header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		return resp, &ErrorResponse{Platform: c.platform, StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+
+	if out == nil {
+		return resp, nil
+	}
+
+	if raw, ok := out.(*[]byte); ok {
+		*raw = respBody
+		return resp, nil
+	}
+
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return resp, fmt.Errorf("failed to decode response of %s %s: %w", method, path, err)
+	}
+
+	return resp, nil
+}
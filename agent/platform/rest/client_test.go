@@ -0,0 +1,62 @@
+package rest_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clover0/issue-agent/platform/rest"
+	"github.com/clover0/issue-agent/test/assert"
+)
+
+func TestClient_Do(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/items":
+			_, _ = w.Write([]byte(`{"name":"item"}`))
+		case "/api/raw":
+			_, _ = w.Write([]byte("raw content"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"not found"}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := rest.NewClient("GitLab", server.URL+"/api", http.Header{"Private-Token": {"token"}}, server.Client())
+
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+
+		var out struct {
+			Name string `json:"name"`
+		}
+		_, err := client.Do(context.Background(), http.MethodGet, "/items", nil, &out)
+		assert.NoError(t, err)
+		assert.Equal(t, out.Name, "item")
+	})
+
+	t.Run("raw", func(t *testing.T) {
+		t.Parallel()
+
+		var raw []byte
+		_, err := client.Do(context.Background(), http.MethodGet, "/raw", nil, &raw)
+		assert.NoError(t, err)
+		assert.Equal(t, string(raw), "raw content")
+	})
+
+	t.Run("error response", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := client.Do(context.Background(), http.MethodGet, "/missing", nil, nil)
+		assert.Equal(t, rest.IsNotFound(err), true)
+		assert.Equal(t, err.Error(), `GitLab API responded 404: {"message":"not found"}`)
+	})
+}
@@ -1,4 +1,4 @@
-package agithub
+package platform
 
 import (
 	"context"
@@ -10,42 +10,45 @@ import (
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
-	"github.com/google/go-github/v73/github"
 
 	"github.com/clover0/issue-agent/core/functions"
 	"github.com/clover0/issue-agent/logger"
+	"github.com/clover0/issue-agent/telemetry"
 )
 
-type SubmitFileGitHubService struct {
+// SubmitFileService commits and pushes the files, and creates the pull request on the platform.
+type SubmitFileService struct {
 	logger      logger.Logger
-	client      *github.Client
+	service     Service
 	callerInput functions.SubmitFilesServiceInput
 }
 
-func NewSubmitFileGitHubService(
+func NewSubmitFileService(
 	logger logger.Logger,
-	client *github.Client,
+	service Service,
 	callerInput functions.SubmitFilesServiceInput,
 ) (functions.SubmitFilesService, error) {
 	if callerInput.GitEmail == "" {
-		return SubmitFileGitHubService{}, fmt.Errorf("git email is not set")
+		return SubmitFileService{}, fmt.Errorf("git email is not set")
 	}
 	if callerInput.GitName == "" {
-		return SubmitFileGitHubService{}, fmt.Errorf("git  name is not set")
+		return SubmitFileService{}, fmt.Errorf("git  name is not set")
 	}
 
-	return SubmitFileGitHubService{
+	return SubmitFileService{
 		logger:      logger,
-		client:      client,
+		service:     service,
 		callerInput: callerInput,
 	}, nil
 }
 
-func (s SubmitFileGitHubService) SubmitFiles(input functions.SubmitFilesInput) (submitFileOut functions.SubmitFilesOutput, _ error) {
+func (s SubmitFileService) SubmitFiles(ctx context.Context, input functions.SubmitFilesInput) (submitFileOut functions.SubmitFilesOutput, err error) {
 	errorf := func(format string, a ...any) error {
 		return fmt.Errorf("submit file service: "+format, a...)
 	}
-	ctx := context.Background()
+	ctx, span := startSpan(ctx, "SubmitFiles", s.callerInput.GitHubOwner, s.callerInput.Repository,
+		telemetry.AttrGitBranch.String(s.callerInput.BaseBranch))
+	defer func() { telemetry.End(span, err) }()
 
 	repo, err := git.PlainOpen(".")
 	if err != nil {
@@ -69,7 +72,7 @@ func (s SubmitFileGitHubService) SubmitFiles(input functions.SubmitFilesInput) (
 		return submitFileOut, errorf("failed to add files: %w", err)
 	}
 
-	if err := s.resetSymlink(wt); err != nil {
+	if err := ResetSymlink(s.logger, wt); err != nil {
 		return submitFileOut, errorf("failed to reset symlink: %w", err)
 	}
 
@@ -90,8 +93,14 @@ func (s SubmitFileGitHubService) SubmitFiles(input functions.SubmitFilesInput) (
 		return submitFileOut, errorf("failed to get HEAD: %w", err)
 	}
 
+	auth, err := s.service.PushAuth()
+	if err != nil {
+		return submitFileOut, errorf("failed to get push auth: %w", err)
+	}
+
 	if err := repo.Push(&git.PushOptions{
 		RemoteName: "origin",
+		Auth:       auth,
 		Progress:   os.Stdout,
 		RefSpecs:   []config.RefSpec{config.RefSpec(fmt.Sprintf("%s:%s", ref.Name(), ref.Name()))},
 	}); err != nil {
@@ -101,27 +110,17 @@ func (s SubmitFileGitHubService) SubmitFiles(input functions.SubmitFilesInput) (
 	currentBranch := ref.Name().Short()
 	s.logger.Debug(fmt.Sprintf("created PR parameter: name=%s, email=%s, base-branch=%s branch=%s\n",
 		s.callerInput.GitName, s.callerInput.GitEmail, s.callerInput.BaseBranch, currentBranch))
-	pr, _, err := s.client.PullRequests.Create(ctx, s.callerInput.GitHubOwner, s.callerInput.Repository, &github.NewPullRequest{
-		Title: &input.CommitMessageShort,
-		Head:  &currentBranch,
-		Base:  &s.callerInput.BaseBranch,
-		Body:  &input.PullRequestContent,
+	pr, err := s.service.CreatePullRequest(ctx, NewPullRequest{
+		Title:  input.CommitMessageShort,
+		Head:   currentBranch,
+		Base:   s.callerInput.BaseBranch,
+		Body:   input.PullRequestContent,
+		Labels: s.callerInput.PRLabels,
 	})
 	if err != nil {
 		return submitFileOut, errorf("failed to create PR: %w", err)
 	}
 
-	if len(s.callerInput.PRLabels) > 0 {
-		if _, _, err = s.client.Issues.AddLabelsToIssue(
-			ctx,
-			s.callerInput.GitHubOwner,
-			s.callerInput.Repository,
-			*pr.Number,
-			s.callerInput.PRLabels); err != nil {
-			return submitFileOut, errorf("failed to add labels(%s) to PR: %w", s.callerInput.PRLabels, err)
-		}
-	}
-
 	// checkout to the base branch
 	if err := wt.Checkout(&git.CheckoutOptions{
 		Branch: plumbing.NewBranchReferenceName(s.callerInput.BaseBranch),
@@ -134,15 +133,15 @@ func (s SubmitFileGitHubService) SubmitFiles(input functions.SubmitFilesInput) (
 
 	return functions.SubmitFilesOutput{
 		Message: fmt.Sprintf("success creating pull request.\ncreated pull request number: %d\nbranch: %s.\n switched %s branch.",
-			*pr.Number, currentBranch, s.callerInput.BaseBranch),
+			pr.Number, currentBranch, s.callerInput.BaseBranch),
 		PushedBranch:      currentBranch,
-		PullRequestNumber: *pr.Number,
+		PullRequestNumber: pr.Number,
 	}, nil
 }
 
 // guardPushToBaseBranch guard pushing to base branch.
 // If the current branch is the base branch, return an error.
-func (s SubmitFileGitHubService) guardPushToBaseBranch(repo *git.Repository) error {
+func (s SubmitFileService) guardPushToBaseBranch(repo *git.Repository) error {
 	head, err := repo.Head()
 	if err != nil {
 		return fmt.Errorf("failed to get HEAD: %w", err)
@@ -155,7 +154,7 @@ func (s SubmitFileGitHubService) guardPushToBaseBranch(repo *git.Repository) err
 }
 
 // setGitConfig set git config.
-func (s SubmitFileGitHubService) setGitConfig(repo *git.Repository) error {
+func (s SubmitFileService) setGitConfig(repo *git.Repository) error {
 	cfg, err := repo.Config()
 	if err != nil {
 		return fmt.Errorf("failed to get config: %w", err)
@@ -171,9 +170,9 @@ func (s SubmitFileGitHubService) setGitConfig(repo *git.Repository) error {
 	return nil
 }
 
-// resetSymlink reset symlink from git staging.
+// ResetSymlink reset symlink from git staging.
 // Because go-git's file system behavior causes symlinks to be relative paths, resulting in extra diffs.
-func (s SubmitFileGitHubService) resetSymlink(wt *git.Worktree) error {
+func ResetSymlink(lo logger.Logger, wt *git.Worktree) error {
 	statuses, err := wt.Status()
 	if err != nil {
 		return fmt.Errorf("failed to get worktree status: %w", err)
@@ -188,13 +187,13 @@ func (s SubmitFileGitHubService) resetSymlink(wt *git.Worktree) error {
 			return fmt.Errorf("failed to open file %s: %w", path, err)
 		}
 		if f.Mode()&os.ModeSymlink != 0 {
-			s.logger.Debug(fmt.Sprintf("reset symlink: %s\n", path))
+			lo.Debug(fmt.Sprintf("reset symlink: %s\n", path))
 			if err := wt.Reset(&git.ResetOptions{Files: []string{path}}); err != nil {
 				return fmt.Errorf("failed to reset symlink: %w", err)
 			}
 		}
 	}
-	s.logger.Info(statuses.String())
+	lo.Info(statuses.String())
 
 	return nil
 }
@@ -203,7 +202,7 @@ func (s SubmitFileGitHubService) resetSymlink(wt *git.Worktree) error {
 type NopSubmitFileService struct{}
 
 // SubmitFiles is a no-op implementation of the SubmitFilesService interface.
-func (s NopSubmitFileService) SubmitFiles(input functions.SubmitFilesInput) (functions.SubmitFilesOutput, error) {
+func (s NopSubmitFileService) SubmitFiles(_ context.Context, input functions.SubmitFilesInput) (functions.SubmitFilesOutput, error) {
 	return functions.SubmitFilesOutput{
 		Message:           "NopSubmitFileService: operation skipped",
 		PushedBranch:      "",
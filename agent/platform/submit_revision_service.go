@@ -1,48 +1,51 @@
-package agithub
+package platform
 
 import (
+	"context"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing/object"
-	"github.com/google/go-github/v73/github"
 
 	"github.com/clover0/issue-agent/core/functions"
 	"github.com/clover0/issue-agent/logger"
+	"github.com/clover0/issue-agent/telemetry"
 )
 
-type SubmitRevisionGitHubService struct {
+// SubmitRevisionService commits and pushes the files to the branch of the pull request.
+type SubmitRevisionService struct {
 	logger      logger.Logger
-	client      *github.Client
+	service     Service
 	callerInput functions.SubmitRevisionServiceInput
 }
 
-func NewSubmitRevisionGitHubService(
+func NewSubmitRevisionService(
 	logger logger.Logger,
-	client *github.Client,
+	service Service,
 	callerInput functions.SubmitRevisionServiceInput,
 ) (functions.SubmitRevisionService, error) {
 	if callerInput.GitEmail == "" {
-		return SubmitRevisionGitHubService{}, fmt.Errorf("git email is not set")
+		return SubmitRevisionService{}, fmt.Errorf("git email is not set")
 	}
 	if callerInput.GitName == "" {
-		return SubmitRevisionGitHubService{}, fmt.Errorf("git  name is not set")
+		return SubmitRevisionService{}, fmt.Errorf("git  name is not set")
 	}
 
-	return SubmitRevisionGitHubService{
+	return SubmitRevisionService{
 		logger:      logger,
-		client:      client,
+		service:     service,
 		callerInput: callerInput,
 	}, nil
 }
 
-func (s SubmitRevisionGitHubService) SubmitRevision(input functions.SubmitRevisionInput) (submitFileOut functions.SubmitRevisionOutput, _ error) {
+func (s SubmitRevisionService) SubmitRevision(ctx context.Context, input functions.SubmitRevisionInput) (submitFileOut functions.SubmitRevisionOutput, err error) {
 	errorf := func(format string, a ...any) error {
 		return fmt.Errorf("submit revision service: "+format, a...)
 	}
-	var err error
+	_, span := startSpan(ctx, "SubmitRevision", s.callerInput.GitHubOwner, s.callerInput.Repository,
+		telemetry.AttrGitBranch.String(s.callerInput.WorkBranch))
+	defer func() { telemetry.End(span, err) }()
 
 	repo, err := git.PlainOpen(".")
 	if err != nil {
@@ -78,28 +81,9 @@ func (s SubmitRevisionGitHubService) SubmitRevision(input functions.SubmitRevisi
 		return submitFileOut, errorf("failed to add files: %w", err)
 	}
 
-	statuses, err := wt.Status()
-	if err != nil {
-		return submitFileOut, errorf("failed to get worktree status: %w", err)
-	}
-
-	// reset symlink because go-git's file system behavior causes symlinks to be relative paths, resulting in extra diffs.
-	for path, status := range statuses {
-		if status.Staging != git.Modified {
-			continue
-		}
-		f, err := os.Lstat(path)
-		if err != nil {
-			return submitFileOut, fmt.Errorf("failed to open file %s: %w", path, err)
-		}
-		if f.Mode()&os.ModeSymlink != 0 {
-			s.logger.Debug(fmt.Sprintf("reset symlink: %s\n", path))
-			if err := wt.Reset(&git.ResetOptions{Files: []string{path}}); err != nil {
-				return submitFileOut, errorf("failed to reset symlink: %w", err)
-			}
-		}
+	if err := ResetSymlink(s.logger, wt); err != nil {
+		return submitFileOut, errorf("failed to reset symlink: %w", err)
 	}
-	s.logger.Info(statuses.String())
 
 	if _, err := wt.Commit(
 		fmt.Sprintf("%s\n\n%s", input.CommitMessageShort, input.CommitMessageDetail),
@@ -113,7 +97,12 @@ func (s SubmitRevisionGitHubService) SubmitRevision(input functions.SubmitRevisi
 		return submitFileOut, errorf("failed to commit: %w", err)
 	}
 
-	if err := repo.Push(&git.PushOptions{RemoteName: "origin"}); err != nil {
+	auth, err := s.service.PushAuth()
+	if err != nil {
+		return submitFileOut, errorf("failed to get push auth: %w", err)
+	}
+
+	if err := repo.Push(&git.PushOptions{RemoteName: "origin", Auth: auth}); err != nil {
 		return submitFileOut, errorf("failed to push: %w", err)
 	}
 
@@ -124,6 +113,6 @@ func (s SubmitRevisionGitHubService) SubmitRevision(input functions.SubmitRevisi
 type NopSubmitRevisionService struct{}
 
 // SubmitRevision is a no-op implementation of the SubmitRevisionsService interface.
-func (s NopSubmitRevisionService) SubmitRevision(_ functions.SubmitRevisionInput) (functions.SubmitRevisionOutput, error) {
+func (s NopSubmitRevisionService) SubmitRevision(_ context.Context, _ functions.SubmitRevisionInput) (functions.SubmitRevisionOutput, error) {
 	return functions.SubmitRevisionOutput{}, nil
 }
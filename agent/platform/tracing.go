@@ -0,0 +1,28 @@
+package platform
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/clover0/issue-agent/telemetry"
+)
+
+// startSpan starts the span of the git operation named like git.SubmitFiles.
+func startSpan(ctx context.Context, operation string, owner string, repository string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return telemetry.Tracer().Start(ctx, "git."+operation,
+		trace.WithAttributes(telemetry.AttrGitRepository.String(owner+"/"+repository)),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// StartAPISpan starts the client span of the platform API operation named like gitlab.GetIssue,
+// with the attribute of the repository.
+func StartAPISpan(ctx context.Context, name string, repository attribute.KeyValue, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return telemetry.Tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(repository),
+		trace.WithAttributes(attrs...),
+	)
+}
@@ -0,0 +1,133 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/clover0/issue-agent/config"
+)
+
+const tracerName = "github.com/clover0/issue-agent"
+
+// Attribute keys of spans.
+// The keys of LLM follow the semantic conventions of generative AI.
+const (
+	AttrAgentName         = attribute.Key("agent.name")
+	AttrModel             = attribute.Key("gen_ai.request.model")
+	AttrInputTokens       = attribute.Key("gen_ai.usage.input_tokens")
+	AttrOutputTokens      = attribute.Key("gen_ai.usage.output_tokens")
+	AttrCacheReadTokens   = attribute.Key("gen_ai.usage.cache_read_input_tokens")
+	AttrCacheCreateTokens = attribute.Key("gen_ai.usage.cache_creation_input_tokens")
+	AttrFinishReason      = attribute.Key("gen_ai.response.finish_reason")
+	AttrToolName          = attribute.Key("gen_ai.tool.name")
+	AttrToolResultBytes   = attribute.Key("gen_ai.tool.result_bytes")
+	AttrGitHubRepository  = attribute.Key("github.repository")
+	AttrGitHubIssueNumber = attribute.Key("github.issue_number")
+	AttrGitHubPullRequest = attribute.Key("github.pull_request_number")
+	AttrGitHubBranch      = attribute.Key("github.branch")
+	AttrGitHubCommentID   = attribute.Key("github.comment_id")
+	AttrGitHubContentPath = attribute.Key("github.content_path")
+	AttrGitRepository     = attribute.Key("git.repository")
+	AttrGitBranch         = attribute.Key("git.branch")
+	AttrGitLabProject     = attribute.Key("gitlab.project")
+	AttrGitLabIID         = attribute.Key("gitlab.iid")
+	AttrGitLabNoteID      = attribute.Key("gitlab.note_id")
+	AttrGitLabBranch      = attribute.Key("gitlab.branch")
+	AttrGitLabContentPath = attribute.Key("gitlab.content_path")
+	AttrGiteaRepository   = attribute.Key("gitea.repository")
+	AttrGiteaIndex        = attribute.Key("gitea.index")
+	AttrGiteaCommentID    = attribute.Key("gitea.comment_id")
+	AttrGiteaBranch       = attribute.Key("gitea.branch")
+	AttrGiteaContentPath  = attribute.Key("gitea.content_path")
+)
+
+// Tracer returns the tracer of the global TracerProvider.
+// Spans are not recorded until Setup is called with an exporter.
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// End ends the span recording the error.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Setup sets the global TracerProvider exporting spans by the configuration.
+// The returned shutdown flushes the remaining spans and should be called before the command exits.
+func Setup(ctx context.Context, conf config.Tracing) (shutdown func(context.Context) error, err error) {
+	if conf.Exporter == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, closeExporter, err := newExporter(ctx, conf)
+	if err != nil {
+		return nil, fmt.Errorf("create %s exporter: %w", conf.Exporter, err)
+	}
+
+	res, err := resource.Merge(
+		resource.Default(),
+		resource.NewSchemaless(attribute.String("service.name", conf.ServiceName)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		return errors.Join(provider.Shutdown(ctx), closeExporter())
+	}, nil
+}
+
+func newExporter(ctx context.Context, conf config.Tracing) (sdktrace.SpanExporter, func() error, error) {
+	nopClose := func() error { return nil }
+
+	switch conf.Exporter {
+	case config.TracingOTLP:
+		var opts []otlptracehttp.Option
+		if conf.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(conf.Endpoint))
+		}
+		if len(conf.Headers) > 0 {
+			opts = append(opts, otlptracehttp.WithHeaders(conf.Headers))
+		}
+		exporter, err := otlptracehttp.New(ctx, opts...)
+		return exporter, nopClose, err
+
+	case config.TracingStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		return exporter, nopClose, err
+
+	case config.TracingFile:
+		file, err := os.OpenFile(conf.FilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("open %s: %w", conf.FilePath, err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			return nil, nil, errors.Join(err, file.Close())
+		}
+		return exporter, file.Close, nil
+	}
+
+	return nil, nil, fmt.Errorf("unknown exporter %s", conf.Exporter)
+}
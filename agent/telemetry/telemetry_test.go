@@ -0,0 +1,42 @@
+package telemetry_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/clover0/issue-agent/config"
+	"github.com/clover0/issue-agent/telemetry"
+	"github.com/clover0/issue-agent/test/assert"
+)
+
+func TestSetup(t *testing.T) {
+	t.Run("disabled without exporter", func(t *testing.T) {
+		shutdown, err := telemetry.Setup(t.Context(), config.Tracing{})
+
+		assert.NoError(t, err)
+		assert.NoError(t, shutdown(t.Context()))
+	})
+
+	t.Run("file exporter writes spans", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "spans.jsonl")
+		shutdown, err := telemetry.Setup(t.Context(), config.Tracing{
+			Exporter:    config.TracingFile,
+			FilePath:    path,
+			ServiceName: "issue-agent-test",
+		})
+		assert.NoError(t, err)
+
+		_, span := telemetry.Tracer().Start(t.Context(), "test-span")
+		span.SetAttributes(telemetry.AttrAgentName.String("testAgent"))
+		telemetry.End(span, nil)
+		assert.NoError(t, shutdown(t.Context()))
+
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, strings.Contains(string(data), `"Name":"test-span"`), true)
+		assert.Equal(t, strings.Contains(string(data), "testAgent"), true)
+		assert.Equal(t, strings.Contains(string(data), "issue-agent-test"), true)
+	})
+}
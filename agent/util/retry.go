@@ -51,3 +51,25 @@ func Retry(maxRetry int, f func() error) error {
 
 	return fmt.Errorf("reached maximum retry limit of %d: %w", maxRetry, lastErr)
 }
+
+// StatusError is the error response of an API with the HTTP status code.
+type StatusError struct {
+	StatusCode int
+	Err        error
+}
+
+func (s *StatusError) Error() string {
+	if s.Err != nil {
+		return s.Err.Error()
+	}
+	return fmt.Sprintf("status %d", s.StatusCode)
+}
+
+func (s *StatusError) Unwrap() error {
+	return s.Err
+}
+
+// HTTPStatusCode returns the status code, as the errors of AWS SDK do.
+func (s *StatusError) HTTPStatusCode() int {
+	return s.StatusCode
+}